@@ -9,7 +9,6 @@ import (
 	"os"
 	"reflect"
 	"strings"
-	"time"
 
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/core/runtime"
@@ -317,8 +316,8 @@ func inspect(injector *dingo.Injector) {
 
 type servemodule struct {
 	router            *web.Router
-	server            *http.Server
-	eventRouter       flamingo.EventRouter
+	server            *web.Server
+	addr              string
 	logger            flamingo.Logger
 	configuredSampler *opencensus.ConfiguredURLPrefixSampler
 }
@@ -326,16 +325,13 @@ type servemodule struct {
 // Inject basic application dependencies
 func (a *servemodule) Inject(
 	router *web.Router,
-	eventRouter flamingo.EventRouter,
+	server *web.Server,
 	logger flamingo.Logger,
 	configuredSampler *opencensus.ConfiguredURLPrefixSampler,
 ) {
 	a.router = router
-	a.eventRouter = eventRouter
+	a.server = server
 	a.logger = logger
-	a.server = &http.Server{
-		Addr: ":3322",
-	}
 	a.configuredSampler = configuredSampler
 }
 
@@ -353,10 +349,7 @@ func serveProvider(a *servemodule, logger flamingo.Logger) *cobra.Command {
 		Use:   "serve",
 		Short: "Default serve command - starts on Port 3322",
 		Run: func(cmd *cobra.Command, args []string) {
-			logger.Info(fmt.Sprintf("Starting HTTP Server at %s .....", a.server.Addr))
-			a.server.Handler = &ochttp.Handler{IsPublicEndpoint: true, Handler: a.router.Handler(), GetStartOptions: a.configuredSampler.GetStartOptions()}
-
-			err := a.listenAndServe()
+			err := a.server.Serve(a.addr, &ochttp.Handler{IsPublicEndpoint: true, Handler: a.router.Handler(), GetStartOptions: a.configuredSampler.GetStartOptions()})
 			if err != nil {
 				if err == http.ErrServerClosed {
 					logger.Error(err)
@@ -366,26 +359,15 @@ func serveProvider(a *servemodule, logger flamingo.Logger) *cobra.Command {
 			}
 		},
 	}
-	serveCmd.Flags().StringVarP(&a.server.Addr, "addr", "a", ":3322", "addr on which flamingo runs")
+	serveCmd.Flags().StringVarP(&a.addr, "addr", "a", ":3322", "addr on which flamingo runs")
 
 	return serveCmd
 }
 
-func (a *servemodule) listenAndServe() error {
-	a.eventRouter.Dispatch(context.Background(), &flamingo.ServerStartEvent{Port: a.server.Addr})
-	defer a.eventRouter.Dispatch(context.Background(), &flamingo.ServerShutdownEvent{})
-
-	err := a.server.ListenAndServe()
-
-	return err
-}
-
 // Notify upon flamingo Shutdown event
 func (a *servemodule) Notify(ctx context.Context, event flamingo.Event) {
 	if _, ok := event.(*flamingo.ShutdownEvent); ok {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-		a.logger.Info("Shutdown server on ", a.server.Addr)
+		a.logger.Info("Shutdown server on ", a.server.Addr())
 
 		err := a.server.Shutdown(ctx)
 		if err != nil {
@@ -58,7 +58,28 @@ func (m *inMemoryCache) Purge(key string) error {
 
 // PurgeTags purges all entries with matching tags from the cache
 func (m *inMemoryCache) PurgeTags(tags []string) error {
-	panic("implement me")
+	for _, key := range m.pool.Keys() {
+		item, ok := m.pool.Peek(key)
+		if !ok {
+			continue
+		}
+		if hasTag(item.(inMemoryCacheEntry).data.(*Entry).Meta.Tags, tags) {
+			m.pool.Remove(key)
+		}
+	}
+
+	return nil
+}
+
+func hasTag(entryTags []string, tags []string) bool {
+	for _, entryTag := range entryTags {
+		for _, tag := range tags {
+			if entryTag == tag {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Flush purges all entries in the cache
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"flamingo.me/dingo"
+
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// PageCacheModule caches rendered pages of anonymous users via the PageCacheFilter
+type PageCacheModule struct{}
+
+// Configure DI
+func (*PageCacheModule) Configure(injector *dingo.Injector) {
+	injector.Bind(new(PageCacheFilter)).In(dingo.Singleton)
+	injector.BindMulti(new(web.Filter)).ToProvider(func(filter *PageCacheFilter) web.Filter {
+		return filter
+	})
+}
+
+// CueConfig schema
+func (*PageCacheModule) CueConfig() string {
+	return `
+core: cache: page: {
+	queryParams: [...string]
+	lifetime: number | *60
+	gracetime: number | *600
+	header: string | *"X-Cache"
+}
+`
+}
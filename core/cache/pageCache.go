@@ -0,0 +1,400 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/groupcache/singleflight"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// PageCacheFilter caches the responses of anonymous GET and HEAD requests.
+	// Pages are cached per method, host, path, the configured query parameters and the request headers listed in Vary.
+	PageCacheFilter struct {
+		singleflight.Group
+		backend     Backend
+		logger      flamingo.Logger
+		responder   *web.Responder
+		queryParams []string
+		lifetime    time.Duration
+		gracetime   time.Duration
+		header      string
+	}
+
+	// RecordableResult is implemented by results which can be recorded by the page cache.
+	// Filters wrapping a result implement it to forward the decision of the wrapped result, see Recordable.
+	RecordableResult interface {
+		web.Result
+		Recordable() bool
+	}
+
+	// cachedPage is a recorded response
+	cachedPage struct {
+		Status int
+		Header http.Header
+		Body   []byte
+	}
+
+	// pageVariants lists the request headers a page varies on, it is stored for each cache key
+	pageVariants []string
+
+	pageRecorder struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+
+	// detachedContext keeps the values of the request context, without its cancellation
+	detachedContext struct {
+		context.Context
+	}
+
+	pageTagsKeyType struct{}
+
+	skipPageKeyType struct{}
+)
+
+const (
+	// PageCacheHit is set in the page cache header if the page is served from cache
+	PageCacheHit = "HIT"
+	// PageCacheStale is set in the page cache header if an expired page is served within its gracetime and revalidated in the background
+	PageCacheStale = "STALE"
+	// PageCacheMiss is set in the page cache header if the page is not cached yet
+	PageCacheMiss = "MISS"
+	// PageCacheBypass is set in the page cache header if the request can not be served from cache
+	PageCacheBypass = "BYPASS"
+)
+
+var (
+	pageTagsKey pageTagsKeyType
+	skipPageKey skipPageKeyType
+)
+
+// AddPageTags tags the cached page of the current request, tagged pages can be purged via PageCacheFilter.PurgeTags
+func AddPageTags(r *web.Request, tags ...string) {
+	existing, _ := r.Values.Load(pageTagsKey)
+	existingTags, _ := existing.([]string)
+	r.Values.Store(pageTagsKey, append(append([]string(nil), existingTags...), tags...))
+}
+
+func pageTags(r *web.Request) []string {
+	tags, _ := r.Values.Load(pageTagsKey)
+	result, _ := tags.([]string)
+	return result
+}
+
+// SkipPage excludes the current request from the page cache, e.g. because the page contains a value of this request only.
+// It works before and after the PageCacheFilter runs: skipped requests are neither served from cache, nor stored.
+func SkipPage(r *web.Request) {
+	r.Values.Store(skipPageKey, true)
+}
+
+func skippedPage(r *web.Request) bool {
+	_, ok := r.Values.Load(skipPageKey)
+	return ok
+}
+
+// Recordable checks if the page cache can record the result.
+// RenderResponse and DataResponse results are recorded, other results only if they implement RecordableResult,
+// since streamed results like file responses and event streams can not be recorded.
+func Recordable(result web.Result) bool {
+	switch result := result.(type) {
+	case *web.RenderResponse, *web.DataResponse:
+		return true
+	case RecordableResult:
+		return result.Recordable()
+	}
+	return false
+}
+
+// Inject PageCacheFilter dependencies
+func (f *PageCacheFilter) Inject(logger flamingo.Logger, responder *web.Responder, cfg *struct {
+	Backend     Backend      `inject:"core.cache.page,optional"`
+	QueryParams config.Slice `inject:"config:core.cache.page.queryParams,optional"`
+	Lifetime    float64      `inject:"config:core.cache.page.lifetime,optional"`
+	Gracetime   float64      `inject:"config:core.cache.page.gracetime,optional"`
+	Header      string       `inject:"config:core.cache.page.header,optional"`
+}) *PageCacheFilter {
+	f.logger = logger.WithField(flamingo.LogKeyModule, "core.cache").WithField(flamingo.LogKeyCategory, "pageCache")
+	f.responder = responder
+	if cfg != nil {
+		f.backend = cfg.Backend
+		_ = cfg.QueryParams.MapInto(&f.queryParams)
+		f.lifetime = time.Duration(cfg.Lifetime * float64(time.Second))
+		f.gracetime = time.Duration(cfg.Gracetime * float64(time.Second))
+		f.header = cfg.Header
+	}
+	if f.backend == nil {
+		f.backend = NewInMemoryCache()
+	}
+	sort.Strings(f.queryParams)
+	return f
+}
+
+// PurgeTags removes all pages with one of the tags from the cache
+func (f *PageCacheFilter) PurgeTags(tags ...string) error {
+	return f.backend.PurgeTags(tags)
+}
+
+// Filter serves cached pages, and caches successful responses.
+// Expired pages are served within their gracetime, while they are revalidated in the background.
+func (f *PageCacheFilter) Filter(ctx context.Context, r *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	if !f.cacheableRequest(r) {
+		f.setHeader(w.Header(), PageCacheBypass)
+		return chain.Next(ctx, r, w)
+	}
+
+	key := f.key(r)
+
+	if entry, ok := f.lookup(key, r); ok {
+		page := entry.Data.(cachedPage)
+
+		if entry.Meta.lifetime.After(time.Now()) {
+			return f.response(page, PageCacheHit)
+		}
+
+		if entry.Meta.gracetime.After(time.Now()) {
+			go f.revalidate(detachedContext{ctx}, r, chain.Fork(), key)
+			return f.response(page, PageCacheStale)
+		}
+	}
+
+	result := chain.Next(ctx, r, w)
+
+	page, ok, err := f.record(ctx, r, key, result)
+	if err != nil {
+		// the result has been applied to the recorder already
+		return f.responder.ServerError(fmt.Errorf("page cache: %w", err))
+	}
+	if !ok {
+		return result
+	}
+
+	return f.response(page, PageCacheMiss)
+}
+
+// cacheableRequest checks if the request is a GET or HEAD request of an anonymous user
+func (f *PageCacheFilter) cacheableRequest(r *web.Request) bool {
+	req := r.Request()
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	// partials and authenticated requests are never cached
+	if req.Header.Get("X-Partial") != "" || req.Header.Get("Authorization") != "" {
+		return false
+	}
+
+	// event streams and websockets do not end, so they can not be recorded
+	if strings.Contains(req.Header.Get("Accept"), "text/event-stream") || req.Header.Get("Upgrade") != "" {
+		return false
+	}
+
+	if skippedPage(r) {
+		return false
+	}
+
+	// identities, flash messages and every other session value make the page personal
+	return r.Session().IsEmpty()
+}
+
+// key for the request, without the varying request headers
+func (f *PageCacheFilter) key(r *web.Request) string {
+	req := r.Request()
+
+	query := make(url.Values)
+	for _, param := range f.queryParams {
+		if values, ok := req.URL.Query()[param]; ok {
+			query[param] = values
+		}
+	}
+
+	return "page:" + req.Method + ":" + req.Host + req.URL.Path + "?" + query.Encode()
+}
+
+// variantKey adds the values of the varying request headers to the key
+func variantKey(key string, variants pageVariants, r *web.Request) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range variants {
+		b.WriteString("\n" + name + ":" + strings.Join(r.Request().Header[name], ","))
+	}
+	return b.String()
+}
+
+// lookup the page variant for the request
+func (f *PageCacheFilter) lookup(key string, r *web.Request) (*Entry, bool) {
+	entry, ok := f.backend.Get(key)
+	if !ok {
+		return nil, false
+	}
+	variants, ok := entry.Data.(pageVariants)
+	if !ok {
+		return nil, false
+	}
+
+	entry, ok = f.backend.Get(variantKey(key, variants, r))
+	if !ok {
+		return nil, false
+	}
+	if _, ok := entry.Data.(cachedPage); !ok {
+		return nil, false
+	}
+	return entry, true
+}
+
+// record applies recordable results and stores them if they are cacheable.
+// The returned page is valid even if it is not cacheable, since the result has been applied already.
+func (f *PageCacheFilter) record(ctx context.Context, r *web.Request, key string, result web.Result) (cachedPage, bool, error) {
+	if !Recordable(result) {
+		return cachedPage{}, false, nil
+	}
+
+	// the page is recorded without conditional headers, the response to the client handles them again
+	req := r.Request().Clone(ctx)
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	unconditional := web.CreateRequest(req, r.Session())
+	unconditional.Params = r.Params
+	r.Values.Range(func(key, value interface{}) bool {
+		unconditional.Values.Store(key, value)
+		return true
+	})
+
+	recorder := &pageRecorder{header: make(http.Header), status: http.StatusOK}
+	if err := result.Apply(web.ContextWithRequest(ctx, unconditional), recorder); err != nil {
+		return cachedPage{}, false, err
+	}
+
+	page := cachedPage{Status: recorder.status, Header: recorder.header, Body: recorder.body.Bytes()}
+
+	variants, ok := cacheableResponse(page)
+	if !ok || !r.Session().IsEmpty() || skippedPage(r) {
+		return page, true, nil
+	}
+
+	meta := Meta{
+		Tags:      pageTags(r),
+		lifetime:  time.Now().Add(f.lifetime),
+		gracetime: time.Now().Add(f.lifetime + f.gracetime),
+	}
+
+	if err := f.backend.Set(key, &Entry{Meta: meta, Data: variants}); err != nil {
+		f.logger.WithContext(ctx).Warn("page cache: ", err)
+		return page, true, nil
+	}
+	if err := f.backend.Set(variantKey(key, variants, r), &Entry{Meta: meta, Data: page}); err != nil {
+		f.logger.WithContext(ctx).Warn("page cache: ", err)
+	}
+
+	return page, true, nil
+}
+
+// cacheableResponse checks if the page can be shared between users, and returns the headers it varies on
+func cacheableResponse(page cachedPage) (pageVariants, bool) {
+	if page.Status != http.StatusOK || page.Header.Get("Set-Cookie") != "" {
+		return nil, false
+	}
+
+	for _, directive := range strings.Split(page.Header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-store", "private":
+			return nil, false
+		}
+	}
+
+	variants := pageVariants{}
+	for _, vary := range page.Header["Vary"] {
+		for _, name := range strings.Split(vary, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				variants = append(variants, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(variants)
+
+	return variants, true
+}
+
+// revalidate processes the request again and stores the new page, only one revalidation per key runs at a time
+func (f *PageCacheFilter) revalidate(ctx context.Context, r *web.Request, chain *web.FilterChain, key string) {
+	_, _ = f.Do(key, func() (interface{}, error) {
+		req := web.CreateRequest(r.Request().Clone(ctx), nil)
+		for name, value := range r.Params {
+			req.Params[name] = value
+		}
+		ctx := web.ContextWithSession(web.ContextWithRequest(ctx, req), req.Session())
+
+		result := chain.Next(ctx, req, &pageRecorder{header: make(http.Header)})
+		if _, _, err := f.record(ctx, req, key, result); err != nil {
+			f.logger.WithContext(ctx).Warn("page cache revalidation: ", err)
+		}
+		return nil, nil
+	})
+}
+
+// response for a recorded page
+func (f *PageCacheFilter) response(page cachedPage, status string) web.Result {
+	header := make(http.Header, len(page.Header)+1)
+	for name, values := range page.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	f.setHeader(header, status)
+
+	return &web.Response{
+		Status: uint(page.Status),
+		Header: header,
+		Body:   bytes.NewReader(page.Body),
+	}
+}
+
+func (f *PageCacheFilter) setHeader(header http.Header, status string) {
+	if f.header != "" {
+		header.Set(f.header, status)
+	}
+}
+
+// Header of the recorded page
+func (pr *pageRecorder) Header() http.Header {
+	return pr.header
+}
+
+// Write to the recorded body
+func (pr *pageRecorder) Write(b []byte) (int, error) {
+	return pr.body.Write(b)
+}
+
+// WriteHeader records the status
+func (pr *pageRecorder) WriteHeader(status int) {
+	pr.status = status
+}
+
+// Deadline is not set for detached contexts
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done is never closed for detached contexts
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err is always nil for detached contexts
+func (detachedContext) Err() error {
+	return nil
+}
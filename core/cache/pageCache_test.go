@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+func testPageCacheFilter() *PageCacheFilter {
+	return &PageCacheFilter{
+		backend:     NewInMemoryCache(),
+		logger:      flamingo.NullLogger{},
+		responder:   new(web.Responder),
+		queryParams: []string{"page"},
+		lifetime:    time.Minute,
+		gracetime:   time.Minute,
+		header:      "X-Cache",
+	}
+}
+
+func servePage(t *testing.T, filter *PageCacheFilter, request *http.Request, session *web.Session, controller func(ctx context.Context, r *web.Request) web.Result) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := web.CreateRequest(request, session)
+	ctx := web.ContextWithSession(web.ContextWithRequest(context.Background(), req), req.Session())
+	recorder := httptest.NewRecorder()
+
+	chain := web.NewFilterChain(func(ctx context.Context, r *web.Request, w http.ResponseWriter) web.Result {
+		return controller(ctx, r)
+	})
+	result := filter.Filter(ctx, req, recorder, chain)
+	require.NoError(t, result.Apply(ctx, recorder))
+	return recorder
+}
+
+// wrappedResult is a result wrapped by another filter, which forwards if the wrapped result is recordable
+type wrappedResult struct {
+	web.Result
+}
+
+func (r wrappedResult) Apply(ctx context.Context, w http.ResponseWriter) error {
+	w.Header().Set("X-Wrapped", "wrapped")
+	return r.Result.Apply(ctx, w)
+}
+
+func (r wrappedResult) Recordable() bool {
+	return Recordable(r.Result)
+}
+
+func TestPageCacheFilter(t *testing.T) {
+	responder := new(web.Responder)
+
+	var calls int32
+	controller := func(ctx context.Context, r *web.Request) web.Result {
+		atomic.AddInt32(&calls, 1)
+		AddPageTags(r, "category-1")
+		return responder.Data(r.Request().URL.Query().Get("page"))
+	}
+
+	t.Run("miss and hit", func(t *testing.T) {
+		filter := testPageCacheFilter()
+		atomic.StoreInt32(&calls, 0)
+
+		recorder := servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category?page=2", nil), nil, controller)
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, `"2"`, recorder.Body.String())
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category?page=2&utm_source=mail", nil), nil, controller)
+		assert.Equal(t, PageCacheHit, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, `"2"`, recorder.Body.String())
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category?page=3", nil), nil, controller)
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, `"3"`, recorder.Body.String())
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "http://other.example/category?page=2", nil), nil, controller)
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("bypass", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		recorder := servePage(t, filter, httptest.NewRequest(http.MethodPost, "/category", nil), nil, controller)
+		assert.Equal(t, PageCacheBypass, recorder.Header().Get("X-Cache"))
+
+		request := httptest.NewRequest(http.MethodGet, "/category", nil)
+		request.Header.Set("Authorization", "Bearer token")
+		recorder = servePage(t, filter, request, nil, controller)
+		assert.Equal(t, PageCacheBypass, recorder.Header().Get("X-Cache"))
+
+		session := web.EmptySession()
+		session.Store("identity", "user")
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), session, controller)
+		assert.Equal(t, PageCacheBypass, recorder.Header().Get("X-Cache"))
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, func(ctx context.Context, r *web.Request) web.Result {
+			r.Session().Store("cart", "1")
+			return responder.Data("personal")
+		})
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+		_, ok := filter.backend.Get(filter.key(web.CreateRequest(httptest.NewRequest(http.MethodGet, "/category", nil), nil)))
+		assert.False(t, ok, "pages of requests which started a session are not stored")
+	})
+
+	t.Run("uncacheable responses", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		responses := map[string]func() web.Result{
+			"not found": func() web.Result { return responder.Data("missing").Status(http.StatusNotFound) },
+			"private": func() web.Result {
+				return responder.Data("private").SetNoCache()
+			},
+			"cookie": func() web.Result {
+				response := responder.Data("cookie")
+				response.Header.Set("Set-Cookie", "a=b")
+				return response
+			},
+			"vary all": func() web.Result {
+				response := responder.Data("vary")
+				response.Header.Set("Vary", "*")
+				return response
+			},
+			"plain response": func() web.Result { return &web.Response{Status: http.StatusNoContent} },
+		}
+
+		for name, response := range responses {
+			response := response
+			path := "/" + url.PathEscape(name)
+			for i := 0; i < 2; i++ {
+				recorder := servePage(t, filter, httptest.NewRequest(http.MethodGet, path, nil), nil, func(ctx context.Context, r *web.Request) web.Result {
+					return response()
+				})
+				assert.NotEqual(t, PageCacheHit, recorder.Header().Get("X-Cache"), name)
+			}
+		}
+	})
+
+	t.Run("vary", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		localized := func(ctx context.Context, r *web.Request) web.Result {
+			response := responder.Data(r.Request().Header.Get("Accept-Language"))
+			response.Header.Add("Vary", "accept-language")
+			return response
+		}
+		request := func(language string) *http.Request {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Accept-Language", language)
+			return request
+		}
+
+		assert.Equal(t, PageCacheMiss, servePage(t, filter, request("de"), nil, localized).Header().Get("X-Cache"))
+		assert.Equal(t, PageCacheMiss, servePage(t, filter, request("en"), nil, localized).Header().Get("X-Cache"))
+
+		recorder := servePage(t, filter, request("de"), nil, localized)
+		assert.Equal(t, PageCacheHit, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, `"de"`, recorder.Body.String())
+	})
+
+	t.Run("conditional requests", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		tagged := func(ctx context.Context, r *web.Request) web.Result {
+			response := responder.Data("content")
+			response.CacheDirective = &web.CacheDirective{ETag: `"v1"`}
+			return response
+		}
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("If-None-Match", `"v1"`)
+		recorder := servePage(t, filter, request, nil, tagged)
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/", nil), nil, tagged)
+		assert.Equal(t, PageCacheHit, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, `"content"`, recorder.Body.String())
+
+		recorder = servePage(t, filter, request, nil, tagged)
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Equal(t, PageCacheHit, recorder.Header().Get("X-Cache"))
+	})
+
+	t.Run("purge tags", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, controller)
+		assert.Equal(t, PageCacheHit, servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, controller).Header().Get("X-Cache"))
+
+		require.NoError(t, filter.PurgeTags("category-2"))
+		assert.Equal(t, PageCacheHit, servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, controller).Header().Get("X-Cache"))
+
+		require.NoError(t, filter.PurgeTags("category-1"))
+		assert.Equal(t, PageCacheMiss, servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, controller).Header().Get("X-Cache"))
+	})
+
+	t.Run("stale while revalidate", func(t *testing.T) {
+		filter := testPageCacheFilter()
+		filter.lifetime = 0
+
+		var version int32
+		versioned := func(ctx context.Context, r *web.Request) web.Result {
+			return responder.Data(atomic.AddInt32(&version, 1))
+		}
+
+		recorder := servePage(t, filter, httptest.NewRequest(http.MethodGet, "/", nil), nil, versioned)
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, "1", recorder.Body.String())
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/", nil), nil, versioned)
+		assert.Equal(t, PageCacheStale, recorder.Header().Get("X-Cache"))
+		assert.JSONEq(t, "1", recorder.Body.String())
+
+		revalidated := false
+		for deadline := time.Now().Add(time.Second); !revalidated && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			revalidated = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/", nil), nil, versioned).Body.String() != "1\n"
+		}
+		assert.True(t, revalidated)
+	})
+
+	t.Run("wrapped results", func(t *testing.T) {
+		filter := testPageCacheFilter()
+		atomic.StoreInt32(&calls, 0)
+
+		wrapped := func(ctx context.Context, r *web.Request) web.Result {
+			return wrappedResult{controller(ctx, r)}
+		}
+
+		recorder := servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category?page=2", nil), nil, wrapped)
+		assert.Equal(t, PageCacheMiss, recorder.Header().Get("X-Cache"))
+		assert.Equal(t, "wrapped", recorder.Header().Get("X-Wrapped"))
+
+		recorder = servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category?page=2", nil), nil, wrapped)
+		assert.Equal(t, PageCacheHit, recorder.Header().Get("X-Cache"))
+		assert.Equal(t, "wrapped", recorder.Header().Get("X-Wrapped"))
+		assert.JSONEq(t, `"2"`, recorder.Body.String())
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("results which are not recordable", func(t *testing.T) {
+		filter := testPageCacheFilter()
+		atomic.StoreInt32(&calls, 0)
+
+		streamed := func(ctx context.Context, r *web.Request) web.Result {
+			atomic.AddInt32(&calls, 1)
+			return &web.Response{Status: http.StatusOK, Body: strings.NewReader("streamed")}
+		}
+
+		for i := 0; i < 2; i++ {
+			recorder := servePage(t, filter, httptest.NewRequest(http.MethodGet, "/download", nil), nil, streamed)
+			assert.Empty(t, recorder.Header().Get("X-Cache"))
+			assert.Equal(t, "streamed", recorder.Body.String())
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("results which fail to apply", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		recorder := servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, func(ctx context.Context, r *web.Request) web.Result {
+			return responder.Data(make(chan int))
+		})
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+
+	t.Run("skipped pages", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		// a filter running before the page cache skips the request
+		req := web.CreateRequest(httptest.NewRequest(http.MethodGet, "/category", nil), nil)
+		SkipPage(req)
+		recorder := httptest.NewRecorder()
+		filter.Filter(context.Background(), req, recorder, web.NewFilterChain(func(ctx context.Context, r *web.Request, w http.ResponseWriter) web.Result {
+			return controller(ctx, r)
+		}))
+		assert.Equal(t, PageCacheBypass, recorder.Header().Get("X-Cache"))
+
+		servePage(t, filter, httptest.NewRequest(http.MethodGet, "/category", nil), nil, func(ctx context.Context, r *web.Request) web.Result {
+			SkipPage(r)
+			return controller(ctx, r)
+		})
+		_, ok := filter.backend.Get(filter.key(web.CreateRequest(httptest.NewRequest(http.MethodGet, "/category", nil), nil)))
+		assert.False(t, ok, "pages skipped after the filter are not stored")
+	})
+
+	t.Run("event streams and websockets are bypassed", func(t *testing.T) {
+		filter := testPageCacheFilter()
+
+		request := httptest.NewRequest(http.MethodGet, "/category", nil)
+		request.Header.Set("Accept", "text/event-stream")
+		assert.Equal(t, PageCacheBypass, servePage(t, filter, request, nil, controller).Header().Get("X-Cache"))
+
+		request = httptest.NewRequest(http.MethodGet, "/category", nil)
+		request.Header.Set("Connection", "Upgrade")
+		request.Header.Set("Upgrade", "websocket")
+		assert.Equal(t, PageCacheBypass, servePage(t, filter, request, nil, controller).Header().Get("X-Cache"))
+	})
+}
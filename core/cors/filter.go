@@ -0,0 +1,318 @@
+package cors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// corsFilter applies the default policy, or the policy configured for the route of the request
+	corsFilter struct {
+		logger        flamingo.Logger
+		defaultPolicy *policy
+		routes        map[string]*policy
+	}
+
+	// policy of cross-origin requests
+	policy struct {
+		allowAll         bool
+		origins          map[string]bool
+		patterns         []*regexp.Regexp
+		methods          []string
+		headers          []string
+		anyHeader        bool
+		exposedHeaders   []string
+		allowCredentials bool
+		maxAge           int
+	}
+
+	// policyConfig is the policy of core.cors.routes, unset fields are taken from the default policy
+	policyConfig struct {
+		Handlers         []string  `json:"handlers"`
+		AllowedOrigins   *[]string `json:"allowedOrigins"`
+		AllowedMethods   *[]string `json:"allowedMethods"`
+		AllowedHeaders   *[]string `json:"allowedHeaders"`
+		ExposedHeaders   *[]string `json:"exposedHeaders"`
+		AllowCredentials *bool     `json:"allowCredentials"`
+		MaxAge           *float64  `json:"maxAge"`
+	}
+)
+
+// Inject dependencies
+func (f *corsFilter) Inject(logger flamingo.Logger, cfg *struct {
+	AllowedOrigins   config.Slice `inject:"config:core.cors.allowedOrigins,optional"`
+	AllowedMethods   config.Slice `inject:"config:core.cors.allowedMethods,optional"`
+	AllowedHeaders   config.Slice `inject:"config:core.cors.allowedHeaders,optional"`
+	ExposedHeaders   config.Slice `inject:"config:core.cors.exposedHeaders,optional"`
+	AllowCredentials bool         `inject:"config:core.cors.allowCredentials,optional"`
+	MaxAge           float64      `inject:"config:core.cors.maxAge,optional"`
+	Routes           config.Slice `inject:"config:core.cors.routes,optional"`
+}) *corsFilter {
+	f.logger = logger.WithField(flamingo.LogKeyModule, "cors")
+	f.defaultPolicy = new(policy)
+	f.routes = make(map[string]*policy)
+
+	if cfg == nil {
+		return f
+	}
+
+	var defaults policyConfig
+	var origins, methods, headers, exposedHeaders []string
+	_ = cfg.AllowedOrigins.MapInto(&origins)
+	_ = cfg.AllowedMethods.MapInto(&methods)
+	_ = cfg.AllowedHeaders.MapInto(&headers)
+	_ = cfg.ExposedHeaders.MapInto(&exposedHeaders)
+	defaults.AllowedOrigins = &origins
+	defaults.AllowedMethods = &methods
+	defaults.AllowedHeaders = &headers
+	defaults.ExposedHeaders = &exposedHeaders
+	defaults.AllowCredentials = &cfg.AllowCredentials
+	defaults.MaxAge = &cfg.MaxAge
+	defaultPolicy, err := newPolicy(defaults)
+	if err != nil {
+		panic(fmt.Errorf("core.cors: %w", err))
+	}
+	f.defaultPolicy = defaultPolicy
+
+	var routes []policyConfig
+	if err := cfg.Routes.MapInto(&routes); err != nil {
+		f.logger.Warn("CORS route policies error: ", err)
+	}
+	for _, route := range routes {
+		override := route
+		if override.AllowedOrigins == nil {
+			override.AllowedOrigins = defaults.AllowedOrigins
+		}
+		if override.AllowedMethods == nil {
+			override.AllowedMethods = defaults.AllowedMethods
+		}
+		if override.AllowedHeaders == nil {
+			override.AllowedHeaders = defaults.AllowedHeaders
+		}
+		if override.ExposedHeaders == nil {
+			override.ExposedHeaders = defaults.ExposedHeaders
+		}
+		if override.AllowCredentials == nil {
+			override.AllowCredentials = defaults.AllowCredentials
+		}
+		if override.MaxAge == nil {
+			override.MaxAge = defaults.MaxAge
+		}
+
+		p, err := newPolicy(override)
+		if err != nil {
+			panic(fmt.Errorf("core.cors.routes: handlers %v: %w", route.Handlers, err))
+		}
+		for _, handler := range route.Handlers {
+			f.routes[handler] = p
+		}
+	}
+
+	return f
+}
+
+// errWildcardCredentials rejects policies allowing credentials for every origin, which would let any site act on behalf of the user
+var errWildcardCredentials = errors.New(`the origin "*" cannot be combined with allowCredentials, list the allowed origins instead`)
+
+// newPolicy compiles the origins of the policy.
+// Origins are either `*`, exact origins like `https://example.com`, patterns like `https://*.example.com`
+// or regular expressions starting with `^`, which match case-insensitive.
+func newPolicy(cfg policyConfig) (*policy, error) {
+	p := &policy{
+		origins:          make(map[string]bool),
+		methods:          *cfg.AllowedMethods,
+		exposedHeaders:   *cfg.ExposedHeaders,
+		allowCredentials: *cfg.AllowCredentials,
+		maxAge:           int(*cfg.MaxAge),
+	}
+
+	for _, origin := range *cfg.AllowedOrigins {
+		var pattern string
+		switch {
+		case origin == "*":
+			p.allowAll = true
+			continue
+		case strings.HasPrefix(origin, "^"):
+			pattern = "(?i)" + origin
+		case strings.Contains(origin, "*"):
+			pattern = "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `.*`) + "$"
+		default:
+			p.origins[strings.ToLower(origin)] = true
+			continue
+		}
+
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("origin %q: %w", origin, err)
+		}
+		p.patterns = append(p.patterns, compiled)
+	}
+
+	for _, header := range *cfg.AllowedHeaders {
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers = append(p.headers, http.CanonicalHeaderKey(header))
+	}
+
+	if p.allowAll && p.allowCredentials {
+		return nil, errWildcardCredentials
+	}
+
+	return p, nil
+}
+
+func (p *policy) allowsOrigin(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *policy) allowsMethod(method string) bool {
+	for _, allowed := range p.methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsHeaders checks the comma separated headers of a Access-Control-Request-Headers header
+func (p *policy) allowsHeaders(requested string) (string, bool) {
+	var headers []string
+	for _, header := range strings.Split(requested, ",") {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		if !p.anyHeader && !contains(p.headers, header) {
+			return header, false
+		}
+		headers = append(headers, header)
+	}
+
+	return strings.Join(headers, ", "), true
+}
+
+// allowOrigin is either the origin, or `*` for any origin, which is never combined with credentials
+func (p *policy) allowOrigin(origin string) string {
+	if p.allowAll {
+		return "*"
+	}
+
+	return origin
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// policyFor returns the policy configured for the route, or the default policy
+func (f *corsFilter) policyFor(handler *web.Handler) *policy {
+	if handler != nil {
+		if p, ok := f.routes[handler.GetHandlerName()]; ok {
+			return p
+		}
+	}
+
+	return f.defaultPolicy
+}
+
+// Filter answers preflight requests and adds the CORS headers to allowed cross-origin requests
+func (f *corsFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	origin := req.Request().Header.Get("Origin")
+	requestedMethod := req.Request().Header.Get("Access-Control-Request-Method")
+	if origin != "" && req.Request().Method == http.MethodOptions && requestedMethod != "" {
+		return f.preflight(ctx, req, origin, requestedMethod)
+	}
+
+	p := f.policyFor(web.HandlerFromContext(ctx))
+	if !p.allowAll {
+		// the response depends on the origin, so caches must not serve it to requests of other or without origins
+		w.Header().Add("Vary", "Origin")
+	}
+	if origin == "" {
+		return chain.Next(ctx, req, w)
+	}
+
+	if !p.allowsOrigin(origin) {
+		f.logger.WithContext(ctx).Debugf("origin %q not allowed for %s %s", origin, req.Request().Method, req.Request().URL.Path)
+		return chain.Next(ctx, req, w)
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", p.allowOrigin(origin))
+	if p.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.exposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.exposedHeaders, ", "))
+	}
+
+	return chain.Next(ctx, req, w)
+}
+
+// preflight requests are answered directly, they never reach the action
+func (f *corsFilter) preflight(ctx context.Context, req *web.Request, origin, requestedMethod string) web.Result {
+	p := f.policyFor(web.PreflightHandlerFromContext(ctx))
+	header := http.Header{"Vary": []string{"Origin, Access-Control-Request-Method, Access-Control-Request-Headers"}}
+
+	if !p.allowsOrigin(origin) {
+		f.logger.WithContext(ctx).Debugf("origin %q not allowed for preflight of %s %s", origin, requestedMethod, req.Request().URL.Path)
+		return &web.Response{Status: http.StatusForbidden, Header: header}
+	}
+
+	if !p.allowsMethod(requestedMethod) {
+		f.logger.WithContext(ctx).Debugf("method %s not allowed for preflight of origin %q to %s", requestedMethod, origin, req.Request().URL.Path)
+		return &web.Response{Status: http.StatusForbidden, Header: header}
+	}
+
+	headers, ok := p.allowsHeaders(req.Request().Header.Get("Access-Control-Request-Headers"))
+	if !ok {
+		f.logger.WithContext(ctx).Debugf("header %s not allowed for preflight of origin %q to %s %s", headers, origin, requestedMethod, req.Request().URL.Path)
+		return &web.Response{Status: http.StatusForbidden, Header: header}
+	}
+
+	header.Set("Access-Control-Allow-Origin", p.allowOrigin(origin))
+	header.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	if headers != "" {
+		header.Set("Access-Control-Allow-Headers", headers)
+	}
+	if p.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if p.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(p.maxAge))
+	}
+
+	return &web.Response{Status: http.StatusNoContent, Header: header}
+}
@@ -0,0 +1,233 @@
+package cors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type filterConfig = struct {
+	AllowedOrigins   config.Slice `inject:"config:core.cors.allowedOrigins,optional"`
+	AllowedMethods   config.Slice `inject:"config:core.cors.allowedMethods,optional"`
+	AllowedHeaders   config.Slice `inject:"config:core.cors.allowedHeaders,optional"`
+	ExposedHeaders   config.Slice `inject:"config:core.cors.exposedHeaders,optional"`
+	AllowCredentials bool         `inject:"config:core.cors.allowCredentials,optional"`
+	MaxAge           float64      `inject:"config:core.cors.maxAge,optional"`
+	Routes           config.Slice `inject:"config:core.cors.routes,optional"`
+}
+
+func testFilter() *corsFilter {
+	return new(corsFilter).Inject(flamingo.NullLogger{}, &filterConfig{
+		AllowedOrigins: config.Slice{"https://example.com", "https://*.example.org", `^https://(shop|www)\.example\.net$`},
+		AllowedMethods: config.Slice{"GET", "POST"},
+		AllowedHeaders: config.Slice{"content-type", "Authorization"},
+		ExposedHeaders: config.Slice{"X-Total-Count"},
+		MaxAge:         600,
+		Routes: config.Slice{
+			config.Map{
+				"handlers":         config.Slice{"api.items", "api.item"},
+				"allowedOrigins":   config.Slice{"https://app.example.com"},
+				"allowedMethods":   config.Slice{"GET", "PUT", "DELETE"},
+				"allowCredentials": true,
+			},
+			config.Map{
+				"handlers":       config.Slice{"public"},
+				"allowedOrigins": config.Slice{"*"},
+				"allowedHeaders": config.Slice{"*"},
+			},
+		},
+	})
+}
+
+func route(name string) *web.Handler {
+	return web.NewRegistry().MustRoute("/items", name)
+}
+
+// handle passes the request to the filter like the router does, preflight requests carry the route of the requested method.
+// reached reports if the request got through to the action.
+func handle(f *corsFilter, handler *web.Handler, request *http.Request) (recorder *httptest.ResponseRecorder, reached bool) {
+	ctx := context.Background()
+	if handler != nil && request.Header.Get("Access-Control-Request-Method") != "" {
+		ctx = web.ContextWithPreflightHandler(ctx, handler)
+	} else if handler != nil {
+		ctx = web.ContextWithHandler(ctx, handler)
+	}
+
+	chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+		reached = true
+		return &web.Response{Status: http.StatusOK}
+	}, f)
+
+	recorder = httptest.NewRecorder()
+	_ = chain.Next(ctx, web.CreateRequest(request, nil), recorder).Apply(ctx, recorder)
+
+	return recorder, reached
+}
+
+func crossOrigin(method, origin string) *http.Request {
+	request := httptest.NewRequest(method, "/items", nil)
+	request.Header.Set("Origin", origin)
+	return request
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	request := crossOrigin(http.MethodOptions, origin)
+	request.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		request.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return request
+}
+
+func TestCorsFilter_Origins(t *testing.T) {
+	f := testFilter()
+
+	for _, tt := range []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://example.com", allowed: true},
+		{origin: "https://EXAMPLE.com", allowed: true},
+		{origin: "http://example.com", allowed: false},
+		{origin: "https://example.com.evil.com", allowed: false},
+		{origin: "https://shop.example.org", allowed: true},
+		{origin: "https://SHOP.Example.org", allowed: true},
+		{origin: "https://example.org", allowed: false},
+		{origin: "https://www.example.net", allowed: true},
+		{origin: "https://WWW.example.net", allowed: true},
+		{origin: "https://evil.example.net", allowed: false},
+		{origin: "null", allowed: false},
+	} {
+		t.Run(tt.origin, func(t *testing.T) {
+			recorder, reached := handle(f, nil, crossOrigin(http.MethodGet, tt.origin))
+			assert.True(t, reached, "actual requests reach the action")
+			assert.Equal(t, []string{"Origin"}, recorder.Header()["Vary"])
+
+			if tt.allowed {
+				assert.Equal(t, tt.origin, recorder.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "X-Total-Count", recorder.Header().Get("Access-Control-Expose-Headers"))
+			} else {
+				assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+				assert.Empty(t, recorder.Header().Get("Access-Control-Expose-Headers"))
+			}
+			assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+
+	t.Run("same origin", func(t *testing.T) {
+		recorder, reached := handle(f, nil, httptest.NewRequest(http.MethodGet, "/items", nil))
+		assert.True(t, reached)
+		assert.Equal(t, []string{"Origin"}, recorder.Header()["Vary"], "a cached response must not be served to cross-origin requests")
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("any origin", func(t *testing.T) {
+		recorder, _ := handle(f, route("public"), crossOrigin(http.MethodGet, "https://evil.com"))
+		assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, recorder.Header()["Vary"], "the response does not depend on the origin")
+	})
+}
+
+func TestCorsFilter_Preflight(t *testing.T) {
+	f := testFilter()
+
+	t.Run("allowed", func(t *testing.T) {
+		recorder, reached := handle(f, nil, preflight("https://example.com", http.MethodPost, "content-type, authorization"))
+		assert.False(t, reached, "preflight requests are answered by the filter")
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, "https://example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", recorder.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type, Authorization", recorder.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers", recorder.Header().Get("Vary"))
+	})
+
+	for name, request := range map[string]*http.Request{
+		"origin": preflight("https://evil.com", http.MethodPost, ""),
+		"method": preflight("https://example.com", http.MethodDelete, ""),
+		"header": preflight("https://example.com", http.MethodPost, "Content-Type, X-Custom"),
+	} {
+		t.Run("forbidden "+name, func(t *testing.T) {
+			recorder, reached := handle(f, nil, request)
+			assert.False(t, reached)
+			assert.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Methods"))
+		})
+	}
+
+	t.Run("options without preflight", func(t *testing.T) {
+		_, reached := handle(f, nil, crossOrigin(http.MethodOptions, "https://example.com"))
+		assert.True(t, reached)
+	})
+}
+
+func TestCorsFilter_Routes(t *testing.T) {
+	f := testFilter()
+
+	t.Run("credentials", func(t *testing.T) {
+		recorder, _ := handle(f, route("api.item"), preflight("https://app.example.com", http.MethodDelete, "Content-Type"))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"), "the origin is echoed for credentials")
+		assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "GET, PUT, DELETE", recorder.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"), "unset fields are inherited")
+
+		recorder, _ = handle(f, route("api.items"), crossOrigin(http.MethodGet, "https://app.example.com"))
+		assert.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+
+		recorder, _ = handle(f, route("api.item"), preflight("https://evil.com", http.MethodDelete, ""))
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		recorder, _ = handle(f, route("api.items"), crossOrigin(http.MethodGet, "https://evil.com"))
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("public", func(t *testing.T) {
+		recorder, _ := handle(f, route("public"), preflight("https://evil.com", http.MethodGet, "X-Anything"))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Anything", recorder.Header().Get("Access-Control-Allow-Headers"))
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("unconfigured route", func(t *testing.T) {
+		recorder, _ := handle(f, route("other"), preflight("https://evil.com", http.MethodGet, ""))
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
+
+func TestCorsFilter_InvalidPattern(t *testing.T) {
+	assert.Panics(t, func() {
+		new(corsFilter).Inject(flamingo.NullLogger{}, &filterConfig{AllowedOrigins: config.Slice{"^https://(shop"}})
+	})
+}
+
+func TestCorsFilter_WildcardCredentials(t *testing.T) {
+	t.Run("default policy", func(t *testing.T) {
+		assert.Panics(t, func() {
+			new(corsFilter).Inject(flamingo.NullLogger{}, &filterConfig{
+				AllowedOrigins:   config.Slice{"*"},
+				AllowCredentials: true,
+			})
+		})
+	})
+
+	t.Run("route policy", func(t *testing.T) {
+		assert.Panics(t, func() {
+			new(corsFilter).Inject(flamingo.NullLogger{}, &filterConfig{
+				AllowedOrigins:   config.Slice{"https://example.com"},
+				AllowCredentials: true,
+				Routes:           config.Slice{config.Map{"handlers": config.Slice{"public"}, "allowedOrigins": config.Slice{"*"}}},
+			})
+		}, "credentials are inherited from the default policy")
+	})
+}
@@ -0,0 +1,38 @@
+package cors
+
+import (
+	"flamingo.me/dingo"
+
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// Module answers CORS preflight requests and adds the CORS headers to cross-origin requests
+type Module struct{}
+
+// Configure DI
+func (*Module) Configure(injector *dingo.Injector) {
+	injector.BindMulti(new(web.Filter)).To(new(corsFilter))
+}
+
+// CueConfig schema
+func (*Module) CueConfig() string {
+	return `
+core: cors: {
+	allowedOrigins: [...string]
+	allowedMethods: [...string] | *["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"]
+	allowedHeaders: [...string] | *["Accept", "Accept-Language", "Content-Language", "Content-Type", "Authorization", "X-Requested-With"]
+	exposedHeaders: [...string]
+	allowCredentials: bool | *false
+	maxAge: number | *600
+	routes: [...{
+		handlers: [...string]
+		allowedOrigins?: [...string]
+		allowedMethods?: [...string]
+		allowedHeaders?: [...string]
+		exposedHeaders?: [...string]
+		allowCredentials?: bool
+		maxAge?: number
+	}]
+}
+`
+}
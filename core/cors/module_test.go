@@ -0,0 +1,14 @@
+package cors_test
+
+import (
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/cors"
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+func TestModule_Configure(t *testing.T) {
+	if err := config.TryModules(nil, new(cors.Module)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,166 @@
+package csrf
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"flamingo.me/flamingo/v3/core/security/headers"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// csrfFilter checks the token of requests with unsafe methods
+	csrfFilter struct {
+		responder    *web.Responder
+		fieldName    string
+		headerName   string
+		exempt       map[string]bool
+		doubleSubmit map[string]bool
+		cookieName   string
+		cookieSecure bool
+	}
+)
+
+type contextKey string
+
+// contextCookieToken is the double-submit cookie value of the request, the template functions render it on double-submit routes
+const contextCookieToken contextKey = "cookieToken"
+
+// safeMethods do not need a token, they must not change state
+var safeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Inject dependencies
+func (f *csrfFilter) Inject(responder *web.Responder, cfg *struct {
+	FieldName    string       `inject:"config:core.csrf.fieldName,optional"`
+	HeaderName   string       `inject:"config:core.csrf.headerName,optional"`
+	Exempt       config.Slice `inject:"config:core.csrf.exempt,optional"`
+	DoubleSubmit config.Slice `inject:"config:core.csrf.doubleSubmit.handlers,optional"`
+	CookieName   string       `inject:"config:core.csrf.doubleSubmit.cookieName,optional"`
+	CookieSecure bool         `inject:"config:core.csrf.doubleSubmit.secure,optional"`
+}) *csrfFilter {
+	f.responder = responder
+	f.fieldName = defaultFieldName
+	f.headerName = "X-CSRF-Token"
+	f.cookieName = "csrf_token"
+	f.exempt = make(map[string]bool)
+	f.doubleSubmit = make(map[string]bool)
+
+	if cfg == nil {
+		return f
+	}
+
+	if cfg.FieldName != "" {
+		f.fieldName = cfg.FieldName
+	}
+	if cfg.HeaderName != "" {
+		f.headerName = cfg.HeaderName
+	}
+	if cfg.CookieName != "" {
+		f.cookieName = cfg.CookieName
+	}
+	f.cookieSecure = cfg.CookieSecure
+
+	var exempt, doubleSubmit []string
+	_ = cfg.Exempt.MapInto(&exempt)
+	_ = cfg.DoubleSubmit.MapInto(&doubleSubmit)
+	for _, handler := range exempt {
+		f.exempt[handler] = true
+	}
+	for _, handler := range doubleSubmit {
+		f.doubleSubmit[handler] = true
+	}
+
+	return f
+}
+
+// Filter rejects requests with unsafe methods without a valid token
+func (f *csrfFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	var handler string
+	if route := web.HandlerFromContext(ctx); route != nil {
+		handler = route.GetHandlerName()
+	}
+
+	doubleSubmit := f.doubleSubmit[handler]
+	if doubleSubmit {
+		ctx = context.WithValue(ctx, contextCookieToken, f.ensureCookie(req, w))
+	}
+
+	if safeMethods[req.Request().Method] || f.exempt[handler] {
+		return f.next(ctx, req, w, chain, doubleSubmit)
+	}
+
+	token, err := f.submittedToken(ctx, req)
+	if err != nil {
+		return f.responder.Forbidden(fmt.Errorf("csrf token unreadable for %s %s: %w", req.Request().Method, req.Request().URL.Path, err))
+	}
+	if token == "" {
+		return f.responder.Forbidden(fmt.Errorf("csrf token missing for %s %s", req.Request().Method, req.Request().URL.Path))
+	}
+
+	if doubleSubmit {
+		cookie, err := req.Request().Cookie(f.cookieName)
+		if err != nil || !equalTokens(cookie.Value, token) {
+			return f.responder.Forbidden(fmt.Errorf("csrf token does not match the cookie for %s %s", req.Request().Method, req.Request().URL.Path))
+		}
+		return f.next(ctx, req, w, chain, doubleSubmit)
+	}
+
+	if !validToken(req.Session(), token) {
+		return f.responder.Forbidden(fmt.Errorf("csrf token invalid for %s %s", req.Request().Method, req.Request().URL.Path))
+	}
+
+	return chain.Next(ctx, req, w)
+}
+
+// next continues the chain, responses of double-submit routes render the cookie value, so they are marked private
+func (f *csrfFilter) next(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain, doubleSubmit bool) web.Result {
+	result := chain.Next(ctx, req, w)
+	if result == nil || !doubleSubmit {
+		return result
+	}
+
+	return headers.PrivateResult(result)
+}
+
+// submittedToken is taken from the header, or from the form field.
+// Multipart bodies are read via Request.Uploads, so the files are stored with the configured limits and removed after the request.
+func (f *csrfFilter) submittedToken(ctx context.Context, req *web.Request) (string, error) {
+	if token := req.Request().Header.Get(f.headerName); token != "" {
+		return token, nil
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(req.Request().Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		if _, err := req.Uploads(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	token, _ := req.Form1(f.fieldName)
+	return token, nil
+}
+
+// ensureCookie sets the double-submit cookie, if the client does not have one yet, and returns its value
+func (f *csrfFilter) ensureCookie(req *web.Request, w http.ResponseWriter) string {
+	if cookie, err := req.Request().Cookie(f.cookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	value := randomCookieToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     f.cookieName,
+		Value:    value,
+		Path:     "/",
+		Secure:   f.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return value
+}
@@ -0,0 +1,294 @@
+package csrf
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type filterConfig = struct {
+	FieldName    string       `inject:"config:core.csrf.fieldName,optional"`
+	HeaderName   string       `inject:"config:core.csrf.headerName,optional"`
+	Exempt       config.Slice `inject:"config:core.csrf.exempt,optional"`
+	DoubleSubmit config.Slice `inject:"config:core.csrf.doubleSubmit.handlers,optional"`
+	CookieName   string       `inject:"config:core.csrf.doubleSubmit.cookieName,optional"`
+	CookieSecure bool         `inject:"config:core.csrf.doubleSubmit.secure,optional"`
+}
+
+func routeContext(name string) context.Context {
+	if name == "" {
+		return context.Background()
+	}
+
+	return web.ContextWithHandler(context.Background(), web.NewRegistry().MustRoute("/form", name))
+}
+
+// submit passes the request to the filter and reports if it got through to the action, the result is the one of the filter
+func submit(f *csrfFilter, route string, request *http.Request, session *web.Session) (result web.Result, reached bool) {
+	chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+		reached = true
+		return &web.Response{Status: http.StatusOK}
+	}, f)
+
+	result = chain.Next(routeContext(route), web.CreateRequest(request, session), httptest.NewRecorder())
+
+	return result, reached
+}
+
+// respond returns the response the browser receives for the request, including the cookies issued by the filter
+func respond(f *csrfFilter, route string, request *http.Request) *http.Response {
+	chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+		return &web.Response{Status: http.StatusOK}
+	}, f)
+
+	ctx := routeContext(route)
+	recorder := httptest.NewRecorder()
+	_ = chain.Next(ctx, web.CreateRequest(request, web.EmptySession()), recorder).Apply(ctx, recorder)
+
+	return recorder.Result()
+}
+
+func formRequest(method string, values url.Values) *http.Request {
+	request := httptest.NewRequest(method, "/form", strings.NewReader(values.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request
+}
+
+func assertForbidden(t *testing.T, result web.Result, reached bool) {
+	t.Helper()
+
+	assert.False(t, reached)
+	require.IsType(t, new(web.ServerErrorResponse), result)
+	assert.Equal(t, uint(http.StatusForbidden), result.(*web.ServerErrorResponse).Response.Status)
+}
+
+func TestCsrfFilter_Session(t *testing.T) {
+	f := new(csrfFilter).Inject(new(web.Responder), nil)
+	session := web.EmptySession()
+	token := Token(session)
+
+	t.Run("safe methods", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace} {
+			_, reached := submit(f, "", httptest.NewRequest(method, "/form", nil), web.EmptySession())
+			assert.True(t, reached, method)
+		}
+	})
+
+	t.Run("form field", func(t *testing.T) {
+		_, reached := submit(f, "", formRequest(http.MethodPost, url.Values{"csrf_token": {token}}), session)
+		assert.True(t, reached)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodDelete, "/form", nil)
+		request.Header.Set("X-CSRF-Token", Token(session))
+		_, reached := submit(f, "", request, session)
+		assert.True(t, reached)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		result, reached := submit(f, "", formRequest(http.MethodPost, url.Values{"name": {"value"}}), session)
+		assertForbidden(t, result, reached)
+	})
+
+	t.Run("token of another session", func(t *testing.T) {
+		result, reached := submit(f, "", formRequest(http.MethodPost, url.Values{"csrf_token": {Token(web.EmptySession())}}), session)
+		assertForbidden(t, result, reached)
+	})
+
+	t.Run("token without session secret", func(t *testing.T) {
+		result, reached := submit(f, "", formRequest(http.MethodPut, url.Values{"csrf_token": {token}}), web.EmptySession())
+		assertForbidden(t, result, reached)
+	})
+
+	t.Run("tampered token", func(t *testing.T) {
+		tampered := []byte(token)
+		tampered[len(tampered)-1] ^= 1
+		for _, invalid := range []string{string(tampered), token[:20], "not base64!"} {
+			result, reached := submit(f, "", formRequest(http.MethodPost, url.Values{"csrf_token": {invalid}}), session)
+			assertForbidden(t, result, reached)
+		}
+	})
+}
+
+func TestCsrfFilter_Multipart(t *testing.T) {
+	f := new(csrfFilter).Inject(new(web.Responder), nil)
+	session := web.EmptySession()
+
+	multipartRequest := func(token string) *http.Request {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		if token != "" {
+			require.NoError(t, writer.WriteField("csrf_token", token))
+		}
+		file, err := writer.CreateFormFile("attachment", "invoice.txt")
+		require.NoError(t, err)
+		_, err = file.Write([]byte("invoice"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		request := httptest.NewRequest(http.MethodPost, "/form", body)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		return request
+	}
+
+	t.Run("form field", func(t *testing.T) {
+		var upload *web.Upload
+		chain := web.NewFilterChain(func(ctx context.Context, req *web.Request, _ http.ResponseWriter) web.Result {
+			var err error
+			upload, err = req.Upload(ctx, "attachment")
+			require.NoError(t, err)
+			return &web.Response{Status: http.StatusOK}
+		}, f)
+
+		chain.Next(context.Background(), web.CreateRequest(multipartRequest(Token(session)), session), httptest.NewRecorder())
+		require.NotNil(t, upload, "the action is reached and the files are available")
+		content, err := ioutil.ReadFile(upload.Path)
+		require.NoError(t, err)
+		assert.Equal(t, "invoice", string(content))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		result, reached := submit(f, "", multipartRequest(""), session)
+		assertForbidden(t, result, reached)
+	})
+}
+
+func TestCsrfFilter_Routes(t *testing.T) {
+	f := new(csrfFilter).Inject(new(web.Responder), &filterConfig{
+		FieldName:    "_token",
+		Exempt:       config.Slice{"webhook"},
+		DoubleSubmit: config.Slice{"api.order"},
+		CookieName:   "XSRF-TOKEN",
+		CookieSecure: true,
+	})
+
+	t.Run("exempt", func(t *testing.T) {
+		_, reached := submit(f, "webhook", formRequest(http.MethodPost, nil), web.EmptySession())
+		assert.True(t, reached)
+
+		result, reached := submit(f, "other", formRequest(http.MethodPost, nil), web.EmptySession())
+		assertForbidden(t, result, reached)
+	})
+
+	t.Run("configured field name", func(t *testing.T) {
+		session := web.EmptySession()
+		_, reached := submit(f, "other", formRequest(http.MethodPost, url.Values{"_token": {Token(session)}}), session)
+		assert.True(t, reached)
+	})
+
+	t.Run("double-submit cookie is issued", func(t *testing.T) {
+		response := respond(f, "page", httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, response.Cookies(), "other routes do not issue the cookie")
+		assert.Empty(t, response.Header.Get("Cache-Control"))
+
+		cookies := respond(f, "api.order", httptest.NewRequest(http.MethodGet, "/", nil)).Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "XSRF-TOKEN", cookies[0].Name)
+		assert.NotEmpty(t, cookies[0].Value)
+		assert.True(t, cookies[0].Secure)
+		assert.False(t, cookies[0].HttpOnly, "the cookie must be readable by JavaScript")
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.AddCookie(cookies[0])
+		response = respond(f, "api.order", request)
+		assert.Empty(t, response.Cookies(), "an existing cookie is kept")
+		assert.Equal(t, "private", response.Header.Get("Cache-Control"), "shared caches must not store the cookie value")
+	})
+
+	t.Run("double-submit", func(t *testing.T) {
+		request := func(cookie, header string) *http.Request {
+			request := httptest.NewRequest(http.MethodPost, "/api/order", nil)
+			if cookie != "" {
+				request.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: cookie})
+			}
+			if header != "" {
+				request.Header.Set("X-CSRF-Token", header)
+			}
+			return request
+		}
+
+		_, reached := submit(f, "api.order", request("cookie-token", "cookie-token"), nil)
+		assert.True(t, reached)
+
+		result, reached := submit(f, "api.order", request("cookie-token", "other-token"), nil)
+		assertForbidden(t, result, reached)
+
+		result, reached = submit(f, "api.order", request("", "cookie-token"), nil)
+		assertForbidden(t, result, reached)
+
+		result, reached = submit(f, "api.order", request("cookie-token", ""), nil)
+		assertForbidden(t, result, reached)
+
+		session := web.EmptySession()
+		result, reached = submit(f, "api.order", request("cookie-token", Token(session)), session)
+		assertForbidden(t, result, reached)
+	})
+
+	t.Run("double-submit templates render the cookie value", func(t *testing.T) {
+		// render the token in the action, like a template of the route does
+		render := func(handlerName string, request *http.Request) (string, *httptest.ResponseRecorder) {
+			var token string
+			chain := web.NewFilterChain(func(ctx context.Context, _ *web.Request, _ http.ResponseWriter) web.Result {
+				token = new(tokenFunc).Inject(flamingo.NullLogger{}).Func(ctx).(func() string)()
+				return &web.Response{Status: http.StatusOK}
+			}, f)
+
+			session := web.EmptySession()
+			ctx := web.ContextWithSession(routeContext(handlerName), session)
+			recorder := httptest.NewRecorder()
+			chain.Next(ctx, web.CreateRequest(request, session), recorder)
+			return token, recorder
+		}
+
+		request := httptest.NewRequest(http.MethodGet, "/api/order", nil)
+		request.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: "cookie-token"})
+		token, _ := render("api.order", request)
+		assert.Equal(t, "cookie-token", token)
+
+		token, recorder := render("api.order", httptest.NewRequest(http.MethodGet, "/api/order", nil))
+		require.Len(t, recorder.Result().Cookies(), 1)
+		assert.Equal(t, recorder.Result().Cookies()[0].Value, token, "a newly issued cookie is rendered as well")
+
+		token, _ = render("page", request)
+		assert.NotEqual(t, "cookie-token", token, "other routes render session tokens")
+		assert.NotEmpty(t, token)
+	})
+}
+
+func TestTemplateFunctions(t *testing.T) {
+	session := web.EmptySession()
+	ctx := web.ContextWithSession(context.Background(), session)
+
+	token := new(tokenFunc).Inject(flamingo.NullLogger{}).Func(ctx).(func() string)()
+	assert.True(t, validToken(session, token))
+	assert.NotEqual(t, token, new(tokenFunc).Inject(flamingo.NullLogger{}).Func(ctx).(func() string)(), "every token is different")
+
+	field := new(fieldFunc).Inject(flamingo.NullLogger{}, &struct {
+		FieldName string `inject:"config:core.csrf.fieldName,optional"`
+	}{FieldName: "_token"}).Func(ctx).(func() template.HTML)()
+	assert.Regexp(t, `^<input type="hidden" name="_token" value="[A-Za-z0-9_-]+">$`, string(field))
+
+	meta := new(metaFunc)
+	meta.Inject(flamingo.NullLogger{})
+	assert.Regexp(t, `^<meta name="csrf-token" content="[A-Za-z0-9_-]+">$`, string(meta.Func(ctx).(func() template.HTML)()))
+
+	assert.Empty(t, new(tokenFunc).Inject(flamingo.NullLogger{}).Func(context.Background()).(func() string)(), "no token without session")
+
+	cookieCtx := context.WithValue(ctx, contextCookieToken, `"><script>`)
+	assert.Equal(t, `<meta name="csrf-token" content="&#34;&gt;&lt;script&gt;">`, string(meta.Func(cookieCtx).(func() template.HTML)()), "cookie values are escaped")
+}
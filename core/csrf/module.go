@@ -0,0 +1,43 @@
+package csrf
+
+import (
+	"flamingo.me/dingo"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// Module protects requests with unsafe methods against cross-site request forgery
+type Module struct{}
+
+// Configure DI
+func (*Module) Configure(injector *dingo.Injector) {
+	injector.BindMulti(new(web.Filter)).To(new(csrfFilter))
+
+	flamingo.BindTemplateFunc(injector, "csrfToken", new(tokenFunc))
+	flamingo.BindTemplateFunc(injector, "csrfField", new(fieldFunc))
+	flamingo.BindTemplateFunc(injector, "csrfMeta", new(metaFunc))
+}
+
+// CueConfig schema
+func (*Module) CueConfig() string {
+	return `
+core: csrf: {
+	fieldName: string | *"csrf_token"
+	headerName: string | *"X-CSRF-Token"
+	exempt: [...string]
+	doubleSubmit: {
+		handlers: [...string]
+		cookieName: string | *"csrf_token"
+		secure: bool | *true
+	}
+}
+`
+}
+
+// Depends on the session module
+func (*Module) Depends() []dingo.Module {
+	return []dingo.Module{
+		new(flamingo.SessionModule),
+	}
+}
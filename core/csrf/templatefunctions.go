@@ -0,0 +1,83 @@
+package csrf
+
+import (
+	"context"
+	"html/template"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+const defaultFieldName = "csrf_token"
+
+type (
+	// tokenFunc renders the plain token, e.g. for JavaScript clients
+	tokenFunc struct {
+		logger flamingo.Logger
+	}
+
+	// fieldFunc renders a hidden form field with the token
+	fieldFunc struct {
+		tokenFunc
+		fieldName string
+	}
+
+	// metaFunc renders a meta tag with the token
+	metaFunc struct {
+		tokenFunc
+	}
+)
+
+// Inject dependencies
+func (f *tokenFunc) Inject(logger flamingo.Logger) *tokenFunc {
+	f.logger = logger.WithField(flamingo.LogKeyModule, "csrf")
+	return f
+}
+
+// Inject dependencies
+func (f *fieldFunc) Inject(logger flamingo.Logger, cfg *struct {
+	FieldName string `inject:"config:core.csrf.fieldName,optional"`
+}) *fieldFunc {
+	f.tokenFunc.Inject(logger)
+	f.fieldName = defaultFieldName
+	if cfg != nil && cfg.FieldName != "" {
+		f.fieldName = cfg.FieldName
+	}
+	return f
+}
+
+// token for the session of the context, or the double-submit cookie value on double-submit routes
+func (f *tokenFunc) token(ctx context.Context) string {
+	if cookieToken, ok := ctx.Value(contextCookieToken).(string); ok {
+		return cookieToken
+	}
+
+	session := web.SessionFromContext(ctx)
+	if session == nil {
+		f.logger.WithContext(ctx).Warn("csrf token without session")
+		return ""
+	}
+
+	return Token(session)
+}
+
+// Func returns the csrfToken template function
+func (f *tokenFunc) Func(ctx context.Context) interface{} {
+	return func() string {
+		return f.token(ctx)
+	}
+}
+
+// Func returns the csrfField template function
+func (f *fieldFunc) Func(ctx context.Context) interface{} {
+	return func() template.HTML {
+		return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(f.fieldName) + `" value="` + template.HTMLEscapeString(f.token(ctx)) + `">`)
+	}
+}
+
+// Func returns the csrfMeta template function
+func (f *metaFunc) Func(ctx context.Context) interface{} {
+	return func() template.HTML {
+		return template.HTML(`<meta name="csrf-token" content="` + template.HTMLEscapeString(f.token(ctx)) + `">`)
+	}
+}
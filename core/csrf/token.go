@@ -0,0 +1,82 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+const (
+	// sessionSecretKey stores the per-session secret the tokens are derived from
+	sessionSecretKey = "core.csrf.secret"
+
+	nonceLength  = 16
+	secretLength = 32
+)
+
+// Token returns a new token for the session, the session secret is created if necessary.
+// Every call returns a different token, so the token in a response does not reveal the secret (BREACH).
+func Token(session *web.Session) string {
+	nonce := randomBytes(nonceLength)
+	return base64.RawURLEncoding.EncodeToString(append(nonce, sign(secret(session), nonce)...))
+}
+
+// validToken checks if the token has been created for the session
+func validToken(session *web.Session, token string) bool {
+	stored, ok := session.Load(sessionSecretKey)
+	if !ok {
+		return false
+	}
+	key, ok := stored.(string)
+	if !ok {
+		return false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != nonceLength+sha256.Size {
+		return false
+	}
+
+	return hmac.Equal(raw[nonceLength:], sign(key, raw[:nonceLength]))
+}
+
+// secret of the session, created on first use
+func secret(session *web.Session) string {
+	if stored, ok := session.Load(sessionSecretKey); ok {
+		if key, ok := stored.(string); ok && key != "" {
+			return key
+		}
+	}
+
+	key := base64.RawURLEncoding.EncodeToString(randomBytes(secretLength))
+	session.Store(sessionSecretKey, key)
+	return key
+}
+
+func sign(key string, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write(nonce)
+	return mac.Sum(nil)
+}
+
+// randomCookieToken for the double-submit cookie
+func randomCookieToken() string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(secretLength))
+}
+
+// equalTokens compares the tokens in constant time
+func equalTokens(a, b string) bool {
+	return a != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
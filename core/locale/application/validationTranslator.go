@@ -0,0 +1,31 @@
+package application
+
+import (
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// ValidationTranslator translates field errors of web.Request.Bind with the label service
+	ValidationTranslator struct {
+		labelService *LabelService
+	}
+)
+
+var _ web.ValidationTranslator = (*ValidationTranslator)(nil)
+
+// Inject dependencies
+func (t *ValidationTranslator) Inject(labelService *LabelService) {
+	t.labelService = labelService
+}
+
+// TranslateFieldError translates the message with the key `validation.<rule>`, e.g. `validation.required`.
+// The translation can use the arguments `field` and `param`, the untranslated message is used as default.
+func (t *ValidationTranslator) TranslateFieldError(fieldError web.FieldError) string {
+	return t.labelService.NewLabel("validation." + fieldError.Rule).
+		SetDefaultLabel(fieldError.Message).
+		SetTranslationArguments(map[string]interface{}{
+			"field": fieldError.Field,
+			"param": fieldError.Param,
+		}).
+		String()
+}
@@ -26,6 +26,7 @@ type (
 func (m *Module) Configure(injector *dingo.Injector) {
 	injector.Bind(new(domain.TranslationService)).In(dingo.ChildSingleton).To(infrastructure.TranslationService{})
 	injector.Bind(new(application.DateTimeServiceInterface)).To(application.DateTimeService{})
+	injector.Bind(new(web.ValidationTranslator)).To(application.ValidationTranslator{})
 
 	if m.EnableTranslationAPI {
 		web.BindRoutes(injector, new(routes))
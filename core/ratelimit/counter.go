@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// Counter stores the state of the rate limits, implementations have to apply the changes atomically
+	Counter interface {
+		// TakeToken takes a token from the bucket of the key, which holds up to limit tokens and is refilled within the window.
+		// It returns if a token was available, and the tokens left.
+		TakeToken(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (allowed bool, tokens float64, err error)
+		// CountRequest counts a request in the current window of the key, if the estimated number of requests
+		// within the sliding window stays within the limit. It returns the counts of the current and the previous window.
+		CountRequest(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (allowed bool, current, previous int, err error)
+	}
+
+	// MemoryCounter keeps the counters in memory, they are not shared between instances
+	MemoryCounter struct {
+		mu        sync.Mutex
+		buckets   map[string]*bucketState
+		windows   map[string]*windowState
+		lastSweep time.Time
+	}
+
+	bucketState struct {
+		tokens  float64
+		last    time.Time
+		expires time.Time
+	}
+
+	windowState struct {
+		index    int64
+		current  int
+		previous int
+		expires  time.Time
+	}
+)
+
+// sweepInterval for expired counters of the MemoryCounter
+const sweepInterval = time.Minute
+
+var _ Counter = new(MemoryCounter)
+
+// NewMemoryCounter creates a new MemoryCounter
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{
+		buckets: make(map[string]*bucketState),
+		windows: make(map[string]*windowState),
+	}
+}
+
+// TakeToken from the bucket of the key
+func (c *MemoryCounter) TakeToken(_ context.Context, key string, limit int, window time.Duration, now time.Time) (bool, float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+
+	rate := float64(limit) / float64(window)
+	state, ok := c.buckets[key]
+	if !ok {
+		state = &bucketState{tokens: float64(limit), last: now}
+		c.buckets[key] = state
+	}
+
+	if elapsed := now.Sub(state.last); elapsed > 0 {
+		state.tokens = math.Min(float64(limit), state.tokens+float64(elapsed)*rate)
+		state.last = now
+	}
+
+	allowed := state.tokens >= 1
+	if allowed {
+		state.tokens--
+	}
+	state.expires = now.Add(time.Duration((float64(limit) - state.tokens) / rate))
+
+	return allowed, state.tokens, nil
+}
+
+// CountRequest in the sliding window of the key
+func (c *MemoryCounter) CountRequest(_ context.Context, key string, limit int, window time.Duration, now time.Time) (bool, int, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+
+	index := now.UnixNano() / int64(window)
+	state, ok := c.windows[key]
+	if !ok {
+		state = &windowState{index: index}
+		c.windows[key] = state
+	}
+
+	switch state.index {
+	case index:
+	case index - 1:
+		state.previous, state.current = state.current, 0
+	default:
+		state.previous, state.current = 0, 0
+	}
+	state.index = index
+	state.expires = time.Unix(0, (index+2)*int64(window))
+
+	if estimate(state.current, state.previous, window, now)+1 > float64(limit) {
+		return false, state.current, state.previous, nil
+	}
+
+	state.current++
+	return true, state.current, state.previous, nil
+}
+
+// sweep removes expired counters, at most once per sweepInterval
+func (c *MemoryCounter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < sweepInterval {
+		return
+	}
+	c.lastSweep = now
+
+	for key, state := range c.buckets {
+		if now.After(state.expires) {
+			delete(c.buckets, key)
+		}
+	}
+	for key, state := range c.windows {
+		if now.After(state.expires) {
+			delete(c.windows, key)
+		}
+	}
+}
+
+// estimate the requests within the sliding window, the previous window is weighted by its overlap with the sliding window
+func estimate(current, previous int, window time.Duration, now time.Time) float64 {
+	elapsed := now.UnixNano() % int64(window)
+	weight := 1 - float64(elapsed)/float64(window)
+
+	return float64(previous)*weight + float64(current)
+}
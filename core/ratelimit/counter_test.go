@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCounter_TakeToken(t *testing.T) {
+	counter := NewMemoryCounter()
+	now := time.Unix(1000, 0)
+
+	for i := 2; i >= 0; i-- {
+		allowed, tokens, err := counter.TakeToken(context.Background(), "key", 3, 3*time.Second, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, float64(i), tokens)
+	}
+
+	allowed, tokens, err := counter.TakeToken(context.Background(), "key", 3, 3*time.Second, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, allowed, "the bucket is empty")
+	assert.InDelta(t, 0.5, tokens, 0.0001)
+
+	allowed, tokens, err = counter.TakeToken(context.Background(), "key", 3, 3*time.Second, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowed, "one token is refilled per second")
+	assert.InDelta(t, 0, tokens, 0.0001)
+
+	allowed, tokens, err = counter.TakeToken(context.Background(), "key", 3, 3*time.Second, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, float64(2), tokens, "the bucket holds at most limit tokens")
+
+	allowed, _, err = counter.TakeToken(context.Background(), "other", 3, 3*time.Second, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowed, "keys have their own buckets")
+}
+
+func TestMemoryCounter_CountRequest(t *testing.T) {
+	counter := NewMemoryCounter()
+	window := time.Minute
+	start := time.Unix(0, 0).Add(1000 * window)
+
+	for i := 1; i <= 4; i++ {
+		allowed, current, previous, err := counter.CountRequest(context.Background(), "key", 4, window, start)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, i, current)
+		assert.Equal(t, 0, previous)
+	}
+
+	allowed, current, _, err := counter.CountRequest(context.Background(), "key", 4, window, start.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 4, current)
+
+	// the previous window still weighs 3/4 after a quarter of the next window
+	allowed, current, previous, err := counter.CountRequest(context.Background(), "key", 4, window, start.Add(75*time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 1, current)
+	assert.Equal(t, 4, previous)
+
+	allowed, _, _, err = counter.CountRequest(context.Background(), "key", 4, window, start.Add(76*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, current, previous, err = counter.CountRequest(context.Background(), "key", 4, window, start.Add(3*window))
+	require.NoError(t, err)
+	assert.True(t, allowed, "older windows are not counted")
+	assert.Equal(t, 1, current)
+	assert.Equal(t, 0, previous)
+}
+
+func TestMemoryCounter_Sweep(t *testing.T) {
+	counter := NewMemoryCounter()
+	now := time.Unix(1000, 0)
+
+	_, _, _ = counter.TakeToken(context.Background(), "bucket", 10, time.Second, now)
+	_, _, _, _ = counter.CountRequest(context.Background(), "window", 10, time.Second, now)
+	assert.Len(t, counter.buckets, 1)
+	assert.Len(t, counter.windows, 1)
+
+	_, _, _ = counter.TakeToken(context.Background(), "other", 10, time.Second, now.Add(2*sweepInterval))
+	assert.Len(t, counter.buckets, 1)
+	assert.Contains(t, counter.buckets, "other")
+	assert.Empty(t, counter.windows)
+}
+
+func TestRedisCounter_windowKeys(t *testing.T) {
+	current, previous := NewRedisCounter(nil, "ratelimit:").windowKeys("ip:192.0.2.1", 42)
+	assert.Equal(t, "{ratelimit:ip:192.0.2.1}:42", current)
+	assert.Equal(t, "{ratelimit:ip:192.0.2.1}:41", previous, "both keys share the hash tag, so they are in the same cluster slot")
+}
@@ -0,0 +1,355 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flamingo.me/dingo"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// KeyFunc returns the key a request is counted for, requests with an empty key are not limited
+	KeyFunc func(ctx context.Context, req *web.Request) string
+
+	keyFuncProvider         func() map[string]KeyFunc
+	identityServiceProvider func() *auth.WebIdentityService
+
+	// rateLimitFilter applies the rules of the route to the request
+	rateLimitFilter struct {
+		responder *web.Responder
+		counter   Counter
+		logger    flamingo.Logger
+		rules     []rule
+		keyFuncs  map[string]KeyFunc
+		now       func() time.Time
+
+		// trustedProxies may set the X-Forwarded-For header
+		trustedProxies []*net.IPNet
+
+		identityService         identityServiceProvider
+		identityServiceOnce     sync.Once
+		identityServiceInstance *auth.WebIdentityService
+	}
+
+	rule struct {
+		Name      string   `json:"name"`
+		Handlers  []string `json:"handlers"`
+		Algorithm string   `json:"algorithm"`
+		Limit     int      `json:"limit"`
+		Window    float64  `json:"window"`
+		Key       string   `json:"key"`
+	}
+
+	// decision of a rule for a request
+	decision struct {
+		allowed    bool
+		limit      int
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}
+)
+
+// algorithms
+const (
+	algorithmTokenBucket   = "tokenBucket"
+	algorithmSlidingWindow = "slidingWindow"
+)
+
+// BindKeyFunc binds a custom key function, rules reference it via their key
+func BindKeyFunc(injector *dingo.Injector, name string) *dingo.Binding {
+	return injector.BindMap(new(KeyFunc), name)
+}
+
+// Inject dependencies
+func (f *rateLimitFilter) Inject(
+	responder *web.Responder,
+	counter Counter,
+	logger flamingo.Logger,
+	keyFuncs keyFuncProvider,
+	identityService identityServiceProvider,
+	cfg *struct {
+		Rules          config.Slice `inject:"config:core.ratelimit.rules,optional"`
+		TrustedProxies config.Slice `inject:"config:core.ratelimit.trustedProxies,optional"`
+	},
+) *rateLimitFilter {
+	f.responder = responder
+	f.counter = counter
+	f.logger = logger.WithField(flamingo.LogKeyModule, "ratelimit")
+	f.identityService = identityService
+	f.now = time.Now
+
+	f.keyFuncs = map[string]KeyFunc{
+		"ip":      f.clientIP,
+		"session": f.sessionKey,
+		"subject": f.subjectKey,
+	}
+	if keyFuncs != nil {
+		for name, keyFunc := range keyFuncs() {
+			f.keyFuncs[name] = keyFunc
+		}
+	}
+
+	if cfg != nil {
+		if err := cfg.Rules.MapInto(&f.rules); err != nil {
+			panic(fmt.Errorf("core.ratelimit.rules: %w", err))
+		}
+
+		var trustedProxies []string
+		if err := cfg.TrustedProxies.MapInto(&trustedProxies); err != nil {
+			panic(fmt.Errorf("core.ratelimit.trustedProxies: %w", err))
+		}
+		for _, proxy := range trustedProxies {
+			network, err := parseNetwork(proxy)
+			if err != nil {
+				panic(fmt.Errorf("core.ratelimit.trustedProxies: %w", err))
+			}
+			f.trustedProxies = append(f.trustedProxies, network)
+		}
+	}
+	for _, rule := range f.rules {
+		if _, ok := f.keyFuncs[rule.Key]; !ok {
+			panic(fmt.Errorf("core.ratelimit.rules: rule %q uses unknown key %q", rule.Name, rule.Key))
+		}
+		if rule.Algorithm != algorithmTokenBucket && rule.Algorithm != algorithmSlidingWindow {
+			panic(fmt.Errorf("core.ratelimit.rules: rule %q uses unknown algorithm %q", rule.Name, rule.Algorithm))
+		}
+		if rule.Limit < 1 || rule.Window <= 0 {
+			panic(fmt.Errorf("core.ratelimit.rules: rule %q needs a positive limit and window", rule.Name))
+		}
+	}
+
+	return f
+}
+
+// Filter rejects requests exceeding a limit with 429 Too Many Requests, the RateLimit headers report the tightest limit
+func (f *rateLimitFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	var handler string
+	if route := web.HandlerFromContext(ctx); route != nil {
+		handler = route.GetHandlerName()
+	}
+
+	var tightest *decision
+	var exceeded *rule
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.applies(handler) {
+			continue
+		}
+
+		key := f.keyFuncs[rule.Key](ctx, req)
+		if key == "" {
+			continue
+		}
+
+		d, err := rule.take(ctx, f.counter, rule.Name+":"+key, f.now())
+		if err != nil {
+			// the rate limit fails open, an unavailable counter must not take the site down
+			f.logger.WithContext(ctx).Warn("rule ", rule.Name, ": ", err)
+			continue
+		}
+
+		if tightest == nil || !d.allowed || d.remaining < tightest.remaining {
+			tightest = &d
+		}
+		if !d.allowed {
+			exceeded = rule
+			break
+		}
+	}
+
+	if tightest == nil {
+		return chain.Next(ctx, req, w)
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(tightest.reset)))
+
+	if exceeded != nil {
+		return f.responder.TooManyRequests(fmt.Errorf("rate limit %q exceeded for %s %s", exceeded.Name, req.Request().Method, req.Request().URL.Path), tightest.retryAfter)
+	}
+
+	return chain.Next(ctx, req, w)
+}
+
+// applies checks if the rule applies to the handler, rules without handlers apply to all requests
+func (r *rule) applies(handler string) bool {
+	if len(r.Handlers) == 0 {
+		return true
+	}
+
+	for _, h := range r.Handlers {
+		if h == handler {
+			return true
+		}
+	}
+
+	return false
+}
+
+// take counts the request for the key with the algorithm of the rule
+func (r *rule) take(ctx context.Context, counter Counter, key string, now time.Time) (decision, error) {
+	window := time.Duration(r.Window * float64(time.Second))
+
+	if r.Algorithm == algorithmSlidingWindow {
+		allowed, current, previous, err := counter.CountRequest(ctx, key, r.Limit, window, now)
+		if err != nil {
+			return decision{}, err
+		}
+		return slidingWindowDecision(allowed, current, previous, r.Limit, window, now), nil
+	}
+
+	allowed, tokens, err := counter.TakeToken(ctx, key, r.Limit, window, now)
+	if err != nil {
+		return decision{}, err
+	}
+	return tokenBucketDecision(allowed, tokens, r.Limit, window), nil
+}
+
+// tokenBucketDecision resets when the bucket is full again, and allows a retry as soon as one token is refilled
+func tokenBucketDecision(allowed bool, tokens float64, limit int, window time.Duration) decision {
+	perToken := float64(window) / float64(limit)
+
+	d := decision{
+		allowed:   allowed,
+		limit:     limit,
+		remaining: int(tokens),
+		reset:     time.Duration((float64(limit) - tokens) * perToken),
+	}
+	if !allowed {
+		d.retryAfter = time.Duration((1 - tokens) * perToken)
+	}
+
+	return d
+}
+
+// slidingWindowDecision resets with the current window, and allows a retry as soon as the estimate drops below the limit
+func slidingWindowDecision(allowed bool, current, previous, limit int, window time.Duration, now time.Time) decision {
+	elapsed := time.Duration(now.UnixNano() % int64(window))
+
+	d := decision{
+		allowed:   allowed,
+		limit:     limit,
+		remaining: int(math.Max(0, float64(limit)-estimate(current, previous, window, now))),
+		reset:     window - elapsed,
+	}
+	if allowed {
+		return d
+	}
+
+	// within the current window the weight of the previous window decreases
+	if free := float64(limit - 1 - current); free >= 0 && previous > 0 {
+		d.retryAfter = time.Duration((1-free/float64(previous))*float64(window)) - elapsed
+		return d
+	}
+
+	// in the next window the current window becomes the previous one
+	d.retryAfter = window - elapsed + time.Duration(math.Max(0, 1-float64(limit-1)/float64(current))*float64(window))
+	return d
+}
+
+// seconds rounds up to full seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// parseNetwork parses a CIDR range, or a single IP
+func parseNetwork(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP %q", value)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}, nil
+}
+
+// trusted checks if the address is a trusted proxy
+func (f *rateLimitFilter) trusted(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range f.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP of the request, without port.
+// X-Forwarded-For is only used for requests of trusted proxies: every proxy appends the address it got the request from,
+// so the right-most address which is not a trusted proxy is the client, the addresses left of it can be spoofed.
+func (f *rateLimitFilter) clientIP(_ context.Context, req *web.Request) string {
+	ip := withoutPort(req.Request().RemoteAddr)
+
+	if f.trusted(ip) {
+		forwarded := strings.Split(strings.Join(req.Request().Header["X-Forwarded-For"], ","), ",")
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			hop := withoutPort(strings.TrimSpace(forwarded[i]))
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !f.trusted(hop) {
+				break
+			}
+		}
+	}
+
+	return "ip:" + ip
+}
+
+func withoutPort(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+
+	return address
+}
+
+// sessionKey of the request, clients without a session are counted by IP
+func (f *rateLimitFilter) sessionKey(ctx context.Context, req *web.Request) string {
+	if req.Session().ID() == "" {
+		return f.clientIP(ctx, req)
+	}
+
+	return "session:" + req.Session().IDHash()
+}
+
+// subjectKey is the subject of the authenticated user, anonymous clients are counted by IP
+func (f *rateLimitFilter) subjectKey(ctx context.Context, req *web.Request) string {
+	f.identityServiceOnce.Do(func() {
+		if f.identityService != nil {
+			f.identityServiceInstance = f.identityService()
+		}
+	})
+
+	if identity := f.identityServiceInstance.Identify(ctx, req); identity != nil {
+		return "subject:" + identity.Broker() + ":" + identity.Subject()
+	}
+
+	return f.clientIP(ctx, req)
+}
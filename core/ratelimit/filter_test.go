@@ -0,0 +1,317 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/core/auth"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type filterConfig = struct {
+	Rules          config.Slice `inject:"config:core.ratelimit.rules,optional"`
+	TrustedProxies config.Slice `inject:"config:core.ratelimit.trustedProxies,optional"`
+}
+
+type (
+	testIdentity struct{ subject string }
+
+	testIdentifier struct{}
+
+	failingCounter struct{}
+)
+
+func (i testIdentity) Subject() string { return i.subject }
+func (testIdentity) Broker() string    { return "test" }
+
+func (testIdentifier) Broker() string { return "test" }
+
+func (testIdentifier) Identify(_ context.Context, req *web.Request) (auth.Identity, error) {
+	if user := req.Request().Header.Get("X-User"); user != "" {
+		return testIdentity{subject: user}, nil
+	}
+
+	return nil, errors.New("anonymous")
+}
+
+func (failingCounter) TakeToken(context.Context, string, int, time.Duration, time.Time) (bool, float64, error) {
+	return false, 0, errors.New("counter unavailable")
+}
+
+func (failingCounter) CountRequest(context.Context, string, int, time.Duration, time.Time) (bool, int, int, error) {
+	return false, 0, 0, errors.New("counter unavailable")
+}
+
+func newTestFilter(rules config.Slice, keyFuncs map[string]KeyFunc) *rateLimitFilter {
+	return newConfiguredTestFilter(&filterConfig{Rules: rules}, keyFuncs)
+}
+
+func newConfiguredTestFilter(cfg *filterConfig, keyFuncs map[string]KeyFunc) *rateLimitFilter {
+	identityService := new(auth.WebIdentityService).Inject([]auth.RequestIdentifier{testIdentifier{}}, nil, nil, nil)
+
+	f := new(rateLimitFilter).Inject(
+		new(web.Responder),
+		NewMemoryCounter(),
+		flamingo.NullLogger{},
+		func() map[string]KeyFunc { return keyFuncs },
+		func() *auth.WebIdentityService { return identityService },
+		cfg,
+	)
+	f.now = func() time.Time { return time.Unix(0, 0).Add(1000 * time.Minute) }
+
+	return f
+}
+
+func ruleConfig(name, algorithm string, limit int, window float64, key string, handlers ...string) map[string]interface{} {
+	h := make([]interface{}, len(handlers))
+	for i, handler := range handlers {
+		h[i] = handler
+	}
+
+	return map[string]interface{}{"name": name, "algorithm": algorithm, "limit": float64(limit), "window": window, "key": key, "handlers": h}
+}
+
+// limit counts the request against the rules of the filter and reports if it got through to the action,
+// the header carries the RateLimit fields the filter set on the response
+func limit(ctx context.Context, f *rateLimitFilter, request *http.Request) (result web.Result, header http.Header, reached bool) {
+	chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+		reached = true
+		return &web.Response{Status: http.StatusOK}
+	}, f)
+
+	recorder := httptest.NewRecorder()
+	result = chain.Next(ctx, web.CreateRequest(request, nil), recorder)
+
+	return result, recorder.Header(), reached
+}
+
+func clientRequest(remoteAddr string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = remoteAddr
+	return request
+}
+
+func assertTooManyRequests(t *testing.T, result web.Result, reached bool, retryAfter string) {
+	t.Helper()
+
+	assert.False(t, reached)
+	require.IsType(t, new(web.ServerErrorResponse), result)
+	assert.Equal(t, uint(http.StatusTooManyRequests), result.(*web.ServerErrorResponse).Response.Status)
+	assert.Equal(t, retryAfter, result.(*web.ServerErrorResponse).Response.Header.Get("Retry-After"))
+}
+
+func TestRateLimitFilter_TokenBucket(t *testing.T) {
+	f := newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 2, 10, "ip")}, nil)
+	ctx := context.Background()
+
+	_, header, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assert.True(t, reached)
+	assert.Equal(t, "2", header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "5", header.Get("RateLimit-Reset"))
+
+	_, header, reached = limit(ctx, f, clientRequest("192.0.2.1:4712"))
+	assert.True(t, reached, "the port is not part of the key")
+	assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", header.Get("RateLimit-Reset"))
+
+	result, header, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assertTooManyRequests(t, result, reached, "5")
+	assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+
+	_, _, reached = limit(ctx, f, clientRequest("192.0.2.2:4711"))
+	assert.True(t, reached, "other clients are not limited")
+
+	f.now = func() time.Time { return time.Unix(0, 0).Add(1000*time.Minute + 5*time.Second) }
+	_, _, reached = limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assert.True(t, reached, "a token is refilled after 5 seconds")
+}
+
+func TestRateLimitFilter_SlidingWindow(t *testing.T) {
+	f := newTestFilter(config.Slice{ruleConfig("api", algorithmSlidingWindow, 2, 60, "ip")}, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, header, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+		assert.True(t, reached)
+		assert.Equal(t, "60", header.Get("RateLimit-Reset"))
+	}
+
+	result, header, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assertTooManyRequests(t, result, reached, "90")
+	assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+
+	// three quarters into the next window, the previous window weighs 1/4
+	f.now = func() time.Time { return time.Unix(0, 0).Add(1001*time.Minute + 45*time.Second) }
+	_, header, reached = limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assert.True(t, reached)
+	assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "15", header.Get("RateLimit-Reset"))
+
+	result, _, reached = limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assertTooManyRequests(t, result, reached, "15")
+}
+
+func TestRateLimitFilter_Handlers(t *testing.T) {
+	f := newTestFilter(config.Slice{
+		ruleConfig("global", algorithmTokenBucket, 10, 60, "ip"),
+		ruleConfig("login", algorithmTokenBucket, 1, 60, "ip", "auth.login", "auth.callback"),
+	}, nil)
+
+	login := web.ContextWithHandler(context.Background(), web.NewRegistry().MustRoute("/login", "auth.login"))
+	home := web.ContextWithHandler(context.Background(), web.NewRegistry().MustRoute("/", "home"))
+
+	_, header, reached := limit(login, f, clientRequest("192.0.2.1:4711"))
+	assert.True(t, reached)
+	assert.Equal(t, "1", header.Get("RateLimit-Limit"), "the headers report the tightest limit")
+	assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+
+	result, _, reached := limit(login, f, clientRequest("192.0.2.1:4711"))
+	assertTooManyRequests(t, result, reached, "60")
+
+	_, header, reached = limit(home, f, clientRequest("192.0.2.1:4711"))
+	assert.True(t, reached, "the login rule does not apply to other handlers")
+	assert.Equal(t, "10", header.Get("RateLimit-Limit"))
+	assert.Equal(t, "7", header.Get("RateLimit-Remaining"))
+}
+
+func TestRateLimitFilter_Keys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("remote address", func(t *testing.T) {
+		f := newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "ip")}, nil)
+
+		_, _, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+		assert.True(t, reached)
+
+		request := clientRequest("192.0.2.1:4712")
+		request.Header.Set("X-Forwarded-For", "198.51.100.1")
+		result, _, reached := limit(ctx, f, request)
+		assertTooManyRequests(t, result, reached, "60")
+	})
+
+	t.Run("forwarded ip", func(t *testing.T) {
+		f := newConfiguredTestFilter(&filterConfig{
+			Rules:          config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "ip")},
+			TrustedProxies: config.Slice{"10.0.0.0/8", "2001:db8::1"},
+		}, nil)
+
+		request := clientRequest("10.0.0.1:4711")
+		request.Header.Set("X-Forwarded-For", "192.0.2.1, 10.0.0.2")
+		_, _, reached := limit(ctx, f, request)
+		assert.True(t, reached)
+
+		_, _, reached = limit(ctx, f, clientRequest("10.0.0.1:4711"))
+		assert.True(t, reached, "the proxy is not counted")
+
+		request = clientRequest("[2001:db8::1]:4711")
+		request.Header.Set("X-Forwarded-For", "192.0.2.1")
+		result, _, reached := limit(ctx, f, request)
+		assertTooManyRequests(t, result, reached, "60")
+	})
+
+	t.Run("spoofed forwarded ip", func(t *testing.T) {
+		f := newConfiguredTestFilter(&filterConfig{
+			Rules:          config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "ip")},
+			TrustedProxies: config.Slice{"10.0.0.1"},
+		}, nil)
+
+		request := clientRequest("10.0.0.1:4711")
+		request.Header.Set("X-Forwarded-For", "198.51.100.1, 192.0.2.1")
+		_, _, reached := limit(ctx, f, request)
+		assert.True(t, reached)
+
+		request = clientRequest("10.0.0.1:4711")
+		request.Header.Add("X-Forwarded-For", "198.51.100.2")
+		request.Header.Add("X-Forwarded-For", "192.0.2.1")
+		result, _, reached := limit(ctx, f, request)
+		assertTooManyRequests(t, result, reached, "60")
+
+		request = clientRequest("192.0.2.2:4711")
+		request.Header.Set("X-Forwarded-For", "198.51.100.3")
+		_, _, reached = limit(ctx, f, request)
+		assert.True(t, reached, "untrusted clients cannot set the forwarded ip")
+
+		request = clientRequest("192.0.2.2:4711")
+		request.Header.Set("X-Forwarded-For", "198.51.100.4")
+		result, _, reached = limit(ctx, f, request)
+		assertTooManyRequests(t, result, reached, "60")
+	})
+
+	t.Run("subject", func(t *testing.T) {
+		f := newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "subject")}, nil)
+
+		request := clientRequest("192.0.2.1:4711")
+		request.Header.Set("X-User", "alice")
+		_, _, reached := limit(ctx, f, request)
+		assert.True(t, reached)
+
+		request = clientRequest("192.0.2.2:4711")
+		request.Header.Set("X-User", "alice")
+		result, _, reached := limit(ctx, f, request)
+		assertTooManyRequests(t, result, reached, "60")
+
+		_, _, reached = limit(ctx, f, clientRequest("192.0.2.1:4711"))
+		assert.True(t, reached, "anonymous clients are counted by IP")
+	})
+
+	t.Run("session without ID", func(t *testing.T) {
+		f := newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "session")}, nil)
+
+		_, _, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+		assert.True(t, reached)
+
+		result, _, reached := limit(ctx, f, clientRequest("192.0.2.1:4712"))
+		assertTooManyRequests(t, result, reached, "60")
+
+		_, _, reached = limit(ctx, f, clientRequest("192.0.2.2:4711"))
+		assert.True(t, reached, "clients without session are counted by IP")
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		f := newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "apiKey")}, map[string]KeyFunc{
+			"apiKey": func(_ context.Context, req *web.Request) string {
+				return req.Request().Header.Get("X-Api-Key")
+			},
+		})
+
+		request := clientRequest("192.0.2.1:4711")
+		request.Header.Set("X-Api-Key", "key-1")
+		_, _, reached := limit(ctx, f, request)
+		assert.True(t, reached)
+
+		result, _, reached := limit(ctx, f, request)
+		assertTooManyRequests(t, result, reached, "60")
+
+		_, header, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+		assert.True(t, reached, "requests without key are not limited")
+		assert.Empty(t, header.Get("RateLimit-Limit"))
+	})
+}
+
+func TestRateLimitFilter_FailOpen(t *testing.T) {
+	f := newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "ip")}, nil)
+	f.counter = failingCounter{}
+	ctx := context.Background()
+
+	_, header, reached := limit(ctx, f, clientRequest("192.0.2.1:4711"))
+	assert.True(t, reached)
+	assert.Empty(t, header.Get("RateLimit-Limit"))
+}
+
+func TestRateLimitFilter_Inject(t *testing.T) {
+	assert.Panics(t, func() { newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 1, 60, "unknown")}, nil) })
+	assert.Panics(t, func() { newTestFilter(config.Slice{ruleConfig("api", "fixedWindow", 1, 60, "ip")}, nil) })
+	assert.Panics(t, func() { newTestFilter(config.Slice{ruleConfig("api", algorithmTokenBucket, 0, 60, "ip")}, nil) })
+	assert.Panics(t, func() { newConfiguredTestFilter(&filterConfig{TrustedProxies: config.Slice{"10.0.0.0/33"}}, nil) })
+	assert.Panics(t, func() { newConfiguredTestFilter(&filterConfig{TrustedProxies: config.Slice{"proxy"}}, nil) })
+}
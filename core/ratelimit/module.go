@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"errors"
+
+	"flamingo.me/dingo"
+	"github.com/gomodule/redigo/redis"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// Module limits the number of requests per client
+type Module struct {
+	backend        string
+	sessionBackend string
+}
+
+// Inject dependencies
+func (m *Module) Inject(cfg *struct {
+	Backend        string `inject:"config:core.ratelimit.backend,optional"`
+	SessionBackend string `inject:"config:flamingo.session.backend,optional"`
+}) *Module {
+	if cfg != nil {
+		m.backend = cfg.Backend
+		m.sessionBackend = cfg.SessionBackend
+	}
+
+	return m
+}
+
+// Configure DI
+func (m *Module) Configure(injector *dingo.Injector) {
+	if m.backend == "redis" {
+		if m.sessionBackend != "redis" {
+			panic(errors.New("core.ratelimit.backend redis shares the redis pool of the session, flamingo.session.backend must be redis"))
+		}
+		injector.Bind(new(Counter)).ToProvider(func(pool *redis.Pool) Counter {
+			return NewRedisCounter(pool, "ratelimit:")
+		}).In(dingo.Singleton)
+	} else {
+		injector.Bind(new(Counter)).ToProvider(func() Counter {
+			return NewMemoryCounter()
+		}).In(dingo.Singleton)
+	}
+
+	injector.BindMulti(new(web.Filter)).To(new(rateLimitFilter))
+}
+
+// CueConfig schema
+func (*Module) CueConfig() string {
+	return `
+core: ratelimit: {
+	backend: *"memory" | "redis"
+	rules: [...{
+		name: string
+		handlers: [...string]
+		algorithm: *"tokenBucket" | "slidingWindow"
+		limit: int
+		window: number | *60
+		key: string | *"ip"
+	}]
+	trustedProxies: [...string]
+}
+`
+}
+
+// Depends on the session module
+func (*Module) Depends() []dingo.Module {
+	return []dingo.Module{
+		new(flamingo.SessionModule),
+	}
+}
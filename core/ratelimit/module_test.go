@@ -0,0 +1,14 @@
+package ratelimit_test
+
+import (
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/ratelimit"
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+func TestModule_Configure(t *testing.T) {
+	if err := config.TryModules(nil, new(ratelimit.Module)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type (
+	// RedisCounter keeps the counters in redis, they are shared between instances
+	RedisCounter struct {
+		pool   *redis.Pool
+		prefix string
+	}
+)
+
+var _ Counter = new(RedisCounter)
+
+// tokenBucketScript mirrors MemoryCounter.TakeToken, times are in milliseconds
+var tokenBucketScript = redis.NewScript(1, `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = limit / window
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or limit
+local last = tonumber(state[2]) or now
+if now > last then
+	tokens = math.min(limit, tokens + (now - last) * rate)
+	last = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", last)
+redis.call("PEXPIRE", KEYS[1], math.ceil((limit - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript mirrors MemoryCounter.CountRequest, times are in milliseconds.
+// The keys of the current and the previous window are passed as KEYS[1] and KEYS[2], as redis cluster requires.
+var slidingWindowScript = redis.NewScript(2, `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+local weight = 1 - (now % window) / window
+
+if previous * weight + current + 1 > limit then
+	return {0, current, previous}
+end
+
+current = redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], window * 2)
+return {1, current, previous}
+`)
+
+// NewRedisCounter creates a new RedisCounter, the keys are prefixed with the given prefix
+func NewRedisCounter(pool *redis.Pool, prefix string) *RedisCounter {
+	return &RedisCounter{
+		pool:   pool,
+		prefix: prefix,
+	}
+}
+
+// TakeToken from the bucket of the key
+func (c *RedisCounter) TakeToken(_ context.Context, key string, limit int, window time.Duration, now time.Time) (bool, float64, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Values(tokenBucketScript.Do(conn, c.prefix+key, limit, milliseconds(window), now.UnixNano()/int64(time.Millisecond)))
+	if err != nil {
+		return false, 0, err
+	}
+
+	var allowed int
+	var tokens string
+	if _, err := redis.Scan(values, &allowed, &tokens); err != nil {
+		return false, 0, err
+	}
+
+	remaining, err := strconv.ParseFloat(tokens, 64)
+	return allowed == 1, remaining, err
+}
+
+// CountRequest in the sliding window of the key
+func (c *RedisCounter) CountRequest(_ context.Context, key string, limit int, window time.Duration, now time.Time) (bool, int, int, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	windowMs, nowMs := milliseconds(window), now.UnixNano()/int64(time.Millisecond)
+	currentKey, previousKey := c.windowKeys(key, nowMs/windowMs)
+
+	values, err := redis.Ints(slidingWindowScript.Do(conn, currentKey, previousKey, limit, windowMs, nowMs))
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	return values[0] == 1, values[1], values[2], nil
+}
+
+// windowKeys of the window with the index and its previous window, the hash tag keeps both in the same cluster slot
+func (c *RedisCounter) windowKeys(key string, index int64) (string, string) {
+	tagged := "{" + c.prefix + key + "}:"
+	return tagged + strconv.FormatInt(index, 10), tagged + strconv.FormatInt(index-1, 10)
+}
+
+func milliseconds(d time.Duration) int64 {
+	if ms := int64(d / time.Millisecond); ms > 0 {
+		return ms
+	}
+	return 1
+}
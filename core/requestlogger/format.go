@@ -0,0 +1,250 @@
+package requestlogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	// entry of the access log, collected after the response has been written
+	entry struct {
+		start           time.Time
+		duration        time.Duration
+		method          string
+		uri             string
+		path            string
+		proto           string
+		status          int
+		size            int
+		referer         string
+		userAgent       string
+		clientIP        []string
+		businessID      string
+		requestID       string
+		traceID         string
+		spanID          string
+		extra           string
+		slow            bool
+		requestHeaders  map[string]string
+		responseHeaders map[string]string
+	}
+)
+
+// access log formats
+const (
+	formatHuman    = "human"
+	formatCombined = "combined"
+	formatJSON     = "json"
+	formatLogfmt   = "logfmt"
+)
+
+// additional log keys of the access log
+const (
+	logKeyResponseSize    flamingo.LogKey = "response_size"
+	logKeyUserAgent       flamingo.LogKey = "user_agent"
+	logKeySlow            flamingo.LogKey = "slow"
+	logKeyRequestHeaders  flamingo.LogKey = "request_headers"
+	logKeyResponseHeaders flamingo.LogKey = "response_headers"
+)
+
+const redacted = "[REDACTED]"
+
+// availableFields can be selected via core.requestlogger.fields for the json and logfmt formats
+var availableFields = map[flamingo.LogKey]func(e *entry) interface{}{
+	flamingo.LogKeyAccesslog:     func(*entry) interface{} { return 1 },
+	flamingo.LogKeyTimestamp:     func(e *entry) interface{} { return e.start.Format(time.RFC3339Nano) },
+	flamingo.LogKeyMethod:        func(e *entry) interface{} { return e.method },
+	flamingo.LogKeyPath:          func(e *entry) interface{} { return e.path },
+	flamingo.LogKeyRequestedURL:  func(e *entry) interface{} { return e.uri },
+	flamingo.LogKeyResponseCode:  func(e *entry) interface{} { return e.status },
+	flamingo.LogKeyResponseTime:  func(e *entry) interface{} { return float64(e.duration) / float64(time.Millisecond) },
+	logKeyResponseSize:           func(e *entry) interface{} { return e.size },
+	flamingo.LogKeyReferer:       func(e *entry) interface{} { return e.referer },
+	logKeyUserAgent:              func(e *entry) interface{} { return e.userAgent },
+	flamingo.LogKeyClientIP:      func(e *entry) interface{} { return strings.Join(e.clientIP, ", ") },
+	flamingo.LogKeyBusinessID:    func(e *entry) interface{} { return e.businessID },
+	flamingo.LogKeyCorrelationID: func(e *entry) interface{} { return e.requestID },
+	flamingo.LogKeyTraceID:       func(e *entry) interface{} { return e.traceID },
+	flamingo.LogKeySpanID:        func(e *entry) interface{} { return e.spanID },
+}
+
+// defaultFields are logged if no fields are configured
+var defaultFields = []flamingo.LogKey{
+	flamingo.LogKeyAccesslog,
+	flamingo.LogKeyMethod,
+	flamingo.LogKeyPath,
+	flamingo.LogKeyRequestedURL,
+	flamingo.LogKeyResponseCode,
+	flamingo.LogKeyResponseTime,
+	logKeyResponseSize,
+	flamingo.LogKeyClientIP,
+	flamingo.LogKeyReferer,
+	logKeyUserAgent,
+	flamingo.LogKeyCorrelationID,
+	flamingo.LogKeyTraceID,
+}
+
+// fields returns the selected fields of the entry, empty strings are left out
+func (e *entry) fields(keys []flamingo.LogKey) ([]flamingo.LogKey, []interface{}) {
+	selected := make([]flamingo.LogKey, 0, len(keys)+1)
+	values := make([]interface{}, 0, len(keys)+1)
+
+	for _, key := range keys {
+		value := availableFields[key](e)
+		if s, ok := value.(string); ok && s == "" {
+			continue
+		}
+		selected = append(selected, key)
+		values = append(values, value)
+	}
+
+	if e.slow {
+		selected = append(selected, logKeySlow)
+		values = append(values, true)
+	}
+
+	return selected, values
+}
+
+// human formats the entry as short line, e.g. `GET /path 200: 12b in 1.2ms`
+func (e *entry) human() string {
+	var extra string
+	if e.extra != "" {
+		extra = " (" + e.extra + ")"
+	}
+
+	return fmt.Sprintf(
+		statusCodeColor(e.status)("%s %s %d: %s in %s%s"),
+		e.method,
+		e.uri,
+		e.status,
+		humanBytes(e.size),
+		e.duration,
+		extra,
+	)
+}
+
+// combined formats the entry in the Apache combined log format
+func (e *entry) combined() string {
+	size := "-"
+	if e.size > 0 {
+		size = strconv.Itoa(e.size)
+	}
+
+	return fmt.Sprintf(
+		`%s - - [%s] "%s %s %s" %d %s "%s" "%s"`,
+		e.remoteHost(),
+		e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.method,
+		escapeQuotes(e.uri),
+		e.proto,
+		e.status,
+		size,
+		dashIfEmpty(escapeQuotes(e.referer)),
+		dashIfEmpty(escapeQuotes(e.userAgent)),
+	)
+}
+
+// json formats the selected fields and the captured headers as JSON object
+func (e *entry) json(keys []flamingo.LogKey) string {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+
+	write := func(key string, value interface{}) {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(value)
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	selected, values := e.fields(keys)
+	for i, key := range selected {
+		write(string(key), values[i])
+	}
+	if len(e.requestHeaders) > 0 {
+		write(string(logKeyRequestHeaders), e.requestHeaders)
+	}
+	if len(e.responseHeaders) > 0 {
+		write(string(logKeyResponseHeaders), e.responseHeaders)
+	}
+
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// logfmt formats the selected fields and the captured headers as key=value pairs
+func (e *entry) logfmt(keys []flamingo.LogKey) string {
+	pairs := make([]string, 0, len(keys)+len(e.requestHeaders)+len(e.responseHeaders))
+
+	selected, values := e.fields(keys)
+	for i, key := range selected {
+		pairs = append(pairs, string(key)+"="+logfmtValue(fmt.Sprint(values[i])))
+	}
+	pairs = append(pairs, logfmtHeaders(logKeyRequestHeaders, e.requestHeaders)...)
+	pairs = append(pairs, logfmtHeaders(logKeyResponseHeaders, e.responseHeaders)...)
+
+	return strings.Join(pairs, " ")
+}
+
+// remoteHost is the first client IP without port
+func (e *entry) remoteHost() string {
+	if len(e.clientIP) == 0 || e.clientIP[0] == "" {
+		return "-"
+	}
+
+	if host, _, err := net.SplitHostPort(e.clientIP[0]); err == nil {
+		return host
+	}
+
+	return e.clientIP[0]
+}
+
+func logfmtHeaders(prefix flamingo.LogKey, headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = string(prefix) + "." + name + "=" + logfmtValue(headers[name])
+	}
+
+	return pairs
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strconv.Quote(value) != `"`+value+`"` {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+func escapeQuotes(s string) string {
+	return strings.Replace(s, `"`, `\"`, -1)
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
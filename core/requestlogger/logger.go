@@ -1,22 +1,43 @@
 package requestlogger
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
 	"flamingo.me/flamingo/v3/framework/web"
+	"flamingo.me/flamingo/v3/framework/web/filter"
 	"github.com/labstack/gommon/color"
+	"go.opencensus.io/trace"
 )
 
 type (
 	logger struct {
-		logger    flamingo.Logger
-		responder *web.Responder
+		logger          flamingo.Logger
+		responder       *web.Responder
+		format          string
+		fields          []flamingo.LogKey
+		exclude         []string
+		sampleRate      float64
+		slowThreshold   time.Duration
+		requestHeaders  []string
+		responseHeaders []string
+		redact          map[string]bool
+		requestIDHeader string
+		random          func() float64
+		out             io.Writer
+		mu              sync.Mutex
 	}
 
 	loggedResponse struct {
@@ -46,6 +67,26 @@ func (r *responseWriterLogger) WriteHeader(statusCode int) {
 	r.rw.WriteHeader(statusCode)
 }
 
+// Flush implements http.Flusher for streaming responses
+func (r *responseWriterLogger) Flush() {
+	if flusher, ok := r.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for websocket connections
+func (r *responseWriterLogger) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		r.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
 // Apply logger to request
 func (l *loggedResponse) Apply(ctx context.Context, rw http.ResponseWriter) error {
 	var err error
@@ -60,9 +101,74 @@ func (l *loggedResponse) Apply(ctx context.Context, rw http.ResponseWriter) erro
 	return err
 }
 
-func (r *logger) Inject(flogger flamingo.Logger, responder *web.Responder) {
+// Inject dependencies
+func (r *logger) Inject(flogger flamingo.Logger, responder *web.Responder, cfg *struct {
+	Format          string       `inject:"config:core.requestlogger.format,optional"`
+	Output          string       `inject:"config:core.requestlogger.output,optional"`
+	Fields          config.Slice `inject:"config:core.requestlogger.fields,optional"`
+	Exclude         config.Slice `inject:"config:core.requestlogger.exclude,optional"`
+	SampleRate      float64      `inject:"config:core.requestlogger.sampleRate,optional"`
+	SlowThreshold   float64      `inject:"config:core.requestlogger.slowThreshold,optional"`
+	RequestHeaders  config.Slice `inject:"config:core.requestlogger.headers.request,optional"`
+	ResponseHeaders config.Slice `inject:"config:core.requestlogger.headers.response,optional"`
+	Redact          config.Slice `inject:"config:core.requestlogger.headers.redact,optional"`
+	RequestIDHeader string       `inject:"config:flamingo.web.requestID.header,optional"`
+}) {
 	r.logger = flogger
 	r.responder = responder
+	r.format = formatHuman
+	r.sampleRate = 1
+	r.random = rand.Float64
+	r.requestIDHeader = filter.RequestIDHeader
+
+	if cfg == nil {
+		return
+	}
+
+	if cfg.Format != "" {
+		r.format = cfg.Format
+	}
+	switch cfg.Output {
+	case "stdout":
+		r.out = os.Stdout
+	case "stderr":
+		r.out = os.Stderr
+	}
+
+	var fields []string
+	_ = cfg.Fields.MapInto(&fields)
+	for _, field := range fields {
+		if _, ok := availableFields[flamingo.LogKey(field)]; !ok {
+			flogger.WithField(flamingo.LogKeyModule, "requestlogger").Warn("unknown access log field ", field)
+			continue
+		}
+		r.fields = append(r.fields, flamingo.LogKey(field))
+	}
+
+	_ = cfg.Exclude.MapInto(&r.exclude)
+	r.sampleRate = cfg.SampleRate
+	r.slowThreshold = time.Duration(cfg.SlowThreshold * float64(time.Millisecond))
+
+	r.requestHeaders = canonicalHeaders(cfg.RequestHeaders)
+	r.responseHeaders = canonicalHeaders(cfg.ResponseHeaders)
+	r.redact = make(map[string]bool)
+	for _, header := range canonicalHeaders(cfg.Redact) {
+		r.redact[header] = true
+	}
+
+	if cfg.RequestIDHeader != "" {
+		r.requestIDHeader = cfg.RequestIDHeader
+	}
+}
+
+func canonicalHeaders(headers config.Slice) []string {
+	var names []string
+	_ = headers.MapInto(&names)
+	for i := range names {
+		names[i] = http.CanonicalHeaderKey(names[i])
+	}
+
+	return names
 }
 
 func humanBytes(bc int) string {
@@ -94,56 +200,160 @@ func (r *logger) Filter(ctx context.Context, req *web.Request, w http.ResponseWr
 	webResponse := chain.Next(ctx, req, w)
 
 	logCallbackFunc := func(rwl *responseWriterLogger) {
-		cp := statusCodeColor(rwl.statusCode)
-		extra := new(strings.Builder)
+		e := &entry{
+			start:      start,
+			duration:   time.Since(start),
+			method:     req.Request().Method,
+			uri:        req.Request().RequestURI,
+			path:       req.Request().URL.Path,
+			proto:      req.Request().Proto,
+			status:     rwl.statusCode,
+			size:       rwl.length,
+			referer:    req.Request().Referer(),
+			userAgent:  req.Request().UserAgent(),
+			clientIP:   req.RemoteAddress(),
+			businessID: req.Request().Header.Get("X-Business-ID"),
+		}
 
-		switch r := webResponse.(type) {
+		if !r.sample(e) {
+			return
+		}
+
+		switch response := webResponse.(type) {
 		case *web.URLRedirectResponse:
-			extra.WriteString("-> " + r.URL.String())
+			e.extra = "-> " + response.URL.String()
 
 		case *web.RouteRedirectResponse:
-			extra.WriteString("-> " + r.To)
+			e.extra = "-> " + response.To
 
 		case *web.ServerErrorResponse:
-			extra.WriteString(strings.Split(fmt.Sprintf(`Error: %s`, r.Error), "\n")[0])
+			e.extra = strings.Split(fmt.Sprintf(`Error: %s`, response.Error), "\n")[0]
 		}
 
-		sizeStr := humanBytes(rwl.length)
-
-		duration := time.Since(start)
-
-		l := r.logger.
-			WithContext(ctx).
-			WithFields(
-				map[flamingo.LogKey]interface{}{
-					flamingo.LogKeyAccesslog:    1,
-					flamingo.LogKeyResponseCode: rwl.statusCode,
-					flamingo.LogKeyResponseTime: duration,
-					flamingo.LogKeyReferer:      req.Request().Referer(),
-					flamingo.LogKeyClientIP:     strings.Join(req.RemoteAddress(), ", "),
-					flamingo.LogKeyBusinessID:   req.Request().Header.Get("X-Business-ID"),
-				},
-			)
-
-		var extraStr string
-		if extra.Len() > 0 {
-			extraStr = " (" + extra.String() + ")"
+		// the request ID filter might run after the request logger, in this case the ID is taken from the response
+		e.requestID = flamingo.RequestIDFromContext(ctx)
+		if e.requestID == "" {
+			e.requestID = rwl.Header().Get(r.requestIDHeader)
 		}
+		if span := trace.FromContext(ctx); span != nil {
+			e.traceID = span.SpanContext().TraceID.String()
+			e.spanID = span.SpanContext().SpanID.String()
+		}
+
+		e.requestHeaders = r.capture(r.requestHeaders, req.Request().Header)
+		e.responseHeaders = r.capture(r.responseHeaders, rwl.Header())
 
-		l.Info(
-			fmt.Sprintf(
-				cp("%s %s %d: %s in %s%s"),
-				req.Request().Method,
-				req.Request().RequestURI,
-				rwl.statusCode,
-				sizeStr,
-				duration,
-				extraStr,
-			),
-		)
+		r.log(ctx, e)
 	}
 	return &loggedResponse{
 		result:      webResponse,
 		logCallback: logCallbackFunc,
 	}
 }
+
+// sample decides if the entry is logged: slow requests are always logged,
+// excluded paths never, and successful requests according to the sample rate
+func (r *logger) sample(e *entry) bool {
+	if r.slowThreshold > 0 && e.duration >= r.slowThreshold {
+		e.slow = true
+		return true
+	}
+
+	for _, pattern := range r.exclude {
+		if e.path == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(e.path, pattern[:len(pattern)-1])) {
+			return false
+		}
+	}
+
+	if e.status < http.StatusBadRequest && r.sampleRate < 1 {
+		return r.random() < r.sampleRate
+	}
+
+	return true
+}
+
+// capture the configured headers, redacted headers are replaced
+func (r *logger) capture(names []string, header http.Header) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	captured := make(map[string]string, len(names))
+	for _, name := range names {
+		values, ok := header[name]
+		if !ok {
+			continue
+		}
+		if r.redact[name] {
+			captured[name] = redacted
+			continue
+		}
+		captured[name] = strings.Join(values, ", ")
+	}
+
+	return captured
+}
+
+// log the entry in the configured format, either directly to the output or via the flamingo logger
+func (r *logger) log(ctx context.Context, e *entry) {
+	fields := r.fields
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+
+	var line string
+	switch r.format {
+	case formatCombined:
+		line = e.combined()
+	case formatJSON:
+		line = e.json(fields)
+	case formatLogfmt:
+		line = e.logfmt(fields)
+	default:
+		line = e.human()
+	}
+
+	if r.out != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		_, _ = io.WriteString(r.out, line+"\n")
+		return
+	}
+
+	logFields := map[flamingo.LogKey]interface{}{
+		flamingo.LogKeyAccesslog:    1,
+		flamingo.LogKeyResponseCode: e.status,
+		flamingo.LogKeyResponseTime: e.duration,
+		flamingo.LogKeyReferer:      e.referer,
+		flamingo.LogKeyClientIP:     strings.Join(e.clientIP, ", "),
+		flamingo.LogKeyBusinessID:   e.businessID,
+	}
+	if e.requestID != "" {
+		logFields[flamingo.LogKeyCorrelationID] = e.requestID
+	}
+	if e.slow {
+		logFields[logKeySlow] = true
+	}
+	if len(e.requestHeaders) > 0 {
+		logFields[logKeyRequestHeaders] = e.requestHeaders
+	}
+	if len(e.responseHeaders) > 0 {
+		logFields[logKeyResponseHeaders] = e.responseHeaders
+	}
+
+	if r.format == formatJSON {
+		// the fields are logged as structured fields, the json encoding is up to the logger
+		selected, values := e.fields(fields)
+		for i, key := range selected {
+			logFields[key] = values[i]
+		}
+		line = fmt.Sprintf("%s %s %d", e.method, e.uri, e.status)
+	}
+
+	l := r.logger.WithContext(ctx).WithFields(logFields)
+	if e.slow {
+		l.Warn(line)
+		return
+	}
+	l.Info(line)
+}
@@ -0,0 +1,235 @@
+package requestlogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type loggerConfig = struct {
+	Format          string       `inject:"config:core.requestlogger.format,optional"`
+	Output          string       `inject:"config:core.requestlogger.output,optional"`
+	Fields          config.Slice `inject:"config:core.requestlogger.fields,optional"`
+	Exclude         config.Slice `inject:"config:core.requestlogger.exclude,optional"`
+	SampleRate      float64      `inject:"config:core.requestlogger.sampleRate,optional"`
+	SlowThreshold   float64      `inject:"config:core.requestlogger.slowThreshold,optional"`
+	RequestHeaders  config.Slice `inject:"config:core.requestlogger.headers.request,optional"`
+	ResponseHeaders config.Slice `inject:"config:core.requestlogger.headers.response,optional"`
+	Redact          config.Slice `inject:"config:core.requestlogger.headers.redact,optional"`
+	RequestIDHeader string       `inject:"config:flamingo.web.requestID.header,optional"`
+}
+
+func newTestLogger(cfg *loggerConfig) (*logger, *bytes.Buffer) {
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = 1
+	}
+
+	l := new(logger)
+	l.Inject(flamingo.NullLogger{}, nil, cfg)
+	buf := new(bytes.Buffer)
+	l.out = buf
+
+	return l, buf
+}
+
+func serve(ctx context.Context, l *logger, request *http.Request, result web.Result) {
+	serveDelayed(ctx, l, request, result, 0)
+}
+
+func serveDelayed(ctx context.Context, l *logger, request *http.Request, result web.Result, delay time.Duration) {
+	req := web.CreateRequest(request, nil)
+	chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+		time.Sleep(delay)
+		return result
+	}, l)
+
+	_ = chain.Next(ctx, req, httptest.NewRecorder()).Apply(ctx, httptest.NewRecorder())
+}
+
+func dataResult(status uint, body string) web.Result {
+	return &web.Response{
+		Status: status,
+		Body:   strings.NewReader(body),
+		Header: http.Header{"Content-Type": []string{"text/plain"}, "X-Request-Id": []string{"4711"}},
+	}
+}
+
+func TestLogger_Formats(t *testing.T) {
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/products?page=2", nil)
+		r.RemoteAddr = "192.0.2.10:4711"
+		r.Header.Set("Referer", "https://example.com/")
+		r.Header.Set("User-Agent", `curl "7.0"`)
+		return r
+	}
+
+	t.Run("combined", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatCombined})
+		serve(context.Background(), l, request(), dataResult(http.StatusOK, "hello"))
+
+		assert.Regexp(t, `^192\.0\.2\.10 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /products\?page=2 HTTP/1\.1" 200 5 "https://example\.com/" "curl \\"7\.0\\""\n$`, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatJSON})
+		serve(context.Background(), l, request(), dataResult(http.StatusNotFound, "not found"))
+
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, float64(1), line["accesslog"])
+		assert.Equal(t, "GET", line["method"])
+		assert.Equal(t, "/products", line["path"])
+		assert.Equal(t, "/products?page=2", line["requested_url"])
+		assert.Equal(t, float64(404), line["response_code"])
+		assert.Equal(t, float64(9), line["response_size"])
+		assert.Equal(t, "192.0.2.10:4711", line["client_ip"])
+		assert.Equal(t, `curl "7.0"`, line["user_agent"])
+		assert.Equal(t, "4711", line["correlationId"], "the request ID is taken from the response")
+		assert.Contains(t, line, "response_time")
+		assert.NotContains(t, line, "businessId")
+		assert.True(t, strings.HasPrefix(buf.String(), `{"accesslog":1,"method":"GET",`), "fields are ordered")
+	})
+
+	t.Run("json field selection", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatJSON, Fields: config.Slice{"response_code", "method", "correlationId", "unknown"}})
+		serve(flamingo.ContextWithRequestID(context.Background(), "ctx-id"), l, request(), dataResult(http.StatusOK, ""))
+
+		assert.Equal(t, `{"response_code":200,"method":"GET","correlationId":"ctx-id"}`+"\n", buf.String())
+	})
+
+	t.Run("logfmt", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatLogfmt, Fields: config.Slice{"method", "requested_url", "response_code", "user_agent", "businessId"}})
+		serve(context.Background(), l, request(), dataResult(http.StatusOK, ""))
+
+		assert.Equal(t, `method=GET requested_url="/products?page=2" response_code=200 user_agent="curl \"7.0\""`+"\n", buf.String())
+	})
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	t.Run("exclude", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatLogfmt, Fields: config.Slice{"path"}, Exclude: config.Slice{"/health", "/static/*"}})
+
+		for _, path := range []string{"/health", "/static/app.js", "/healthy", "/"} {
+			serve(context.Background(), l, httptest.NewRequest(http.MethodGet, path, nil), dataResult(http.StatusOK, ""))
+		}
+
+		assert.Equal(t, "path=/healthy\npath=/\n", buf.String())
+	})
+
+	t.Run("sample rate", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatLogfmt, Fields: config.Slice{"response_code"}, SampleRate: 0.5})
+		random := []float64{0.7, 0.2}
+		l.random = func() float64 {
+			r := random[0]
+			random = random[1:]
+			return r
+		}
+
+		serve(context.Background(), l, httptest.NewRequest(http.MethodGet, "/", nil), dataResult(http.StatusOK, ""))
+		serve(context.Background(), l, httptest.NewRequest(http.MethodGet, "/", nil), dataResult(http.StatusCreated, ""))
+		serve(context.Background(), l, httptest.NewRequest(http.MethodGet, "/", nil), dataResult(http.StatusInternalServerError, ""))
+
+		assert.Equal(t, "response_code=201\nresponse_code=500\n", buf.String(), "errors are not sampled")
+		assert.Empty(t, random)
+	})
+
+	t.Run("slow requests", func(t *testing.T) {
+		l, buf := newTestLogger(&loggerConfig{Format: formatLogfmt, Fields: config.Slice{"path"}, Exclude: config.Slice{"/health"}, SampleRate: 0.000001, SlowThreshold: 5})
+		l.random = func() float64 { return 0.5 }
+
+		serveDelayed(context.Background(), l, httptest.NewRequest(http.MethodGet, "/health", nil), dataResult(http.StatusOK, ""), 10*time.Millisecond)
+		serveDelayed(context.Background(), l, httptest.NewRequest(http.MethodGet, "/", nil), dataResult(http.StatusOK, ""), 10*time.Millisecond)
+		serve(context.Background(), l, httptest.NewRequest(http.MethodGet, "/fast", nil), dataResult(http.StatusOK, ""))
+
+		assert.Equal(t, "path=/health slow=true\npath=/ slow=true\n", buf.String())
+	})
+}
+
+func TestLogger_Headers(t *testing.T) {
+	l, buf := newTestLogger(&loggerConfig{
+		Format:          formatJSON,
+		Fields:          config.Slice{"method"},
+		RequestHeaders:  config.Slice{"accept", "Authorization", "X-Missing"},
+		ResponseHeaders: config.Slice{"Content-Type", "set-cookie"},
+		Redact:          config.Slice{"authorization", "Set-Cookie"},
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Add("Accept", "text/html")
+	request.Header.Add("Accept", "application/json")
+	request.Header.Set("Authorization", "Bearer secret")
+	result := dataResult(http.StatusOK, "")
+	result.(*web.Response).Header.Set("Set-Cookie", "session=secret")
+
+	serve(context.Background(), l, request, result)
+
+	assert.Equal(t, `{"method":"GET","request_headers":{"Accept":"text/html, application/json","Authorization":"[REDACTED]"},"response_headers":{"Content-Type":"text/plain","Set-Cookie":"[REDACTED]"}}`+"\n", buf.String())
+}
+
+type recordingLogger struct {
+	flamingo.NullLogger
+	fields   map[flamingo.LogKey]interface{}
+	level    string
+	messages []string
+}
+
+func (l *recordingLogger) WithContext(context.Context) flamingo.Logger { return l }
+
+func (l *recordingLogger) WithFields(fields map[flamingo.LogKey]interface{}) flamingo.Logger {
+	l.fields = fields
+	return l
+}
+
+func (l *recordingLogger) Info(args ...interface{}) {
+	l.level = "info"
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Warn(args ...interface{}) {
+	l.level = "warn"
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func TestLogger_FlamingoLogger(t *testing.T) {
+	t.Run("human", func(t *testing.T) {
+		recorder := new(recordingLogger)
+		l := new(logger)
+		l.Inject(recorder, nil, nil)
+
+		serve(context.Background(), l, httptest.NewRequest(http.MethodGet, "/path", nil), dataResult(http.StatusOK, "hello"))
+
+		require.Len(t, recorder.messages, 1)
+		assert.Contains(t, recorder.messages[0], "GET /path 200: 5b in ")
+		assert.Equal(t, "info", recorder.level)
+		assert.Equal(t, 1, recorder.fields[flamingo.LogKeyAccesslog])
+		assert.Equal(t, 200, recorder.fields[flamingo.LogKeyResponseCode])
+		assert.Equal(t, "4711", recorder.fields[flamingo.LogKeyCorrelationID])
+	})
+
+	t.Run("json fields", func(t *testing.T) {
+		recorder := new(recordingLogger)
+		l := new(logger)
+		l.Inject(recorder, nil, &loggerConfig{Format: formatJSON, Fields: config.Slice{"path", "response_size"}, SampleRate: 1, SlowThreshold: 1})
+
+		serveDelayed(context.Background(), l, httptest.NewRequest(http.MethodGet, "/path?q=1", nil), dataResult(http.StatusOK, "hello"), 2*time.Millisecond)
+
+		assert.Equal(t, []string{"GET /path?q=1 200"}, recorder.messages)
+		assert.Equal(t, "warn", recorder.level, "slow requests are logged as warning")
+		assert.Equal(t, "/path", recorder.fields[flamingo.LogKeyPath])
+		assert.Equal(t, 5, recorder.fields[logKeyResponseSize])
+		assert.Equal(t, true, recorder.fields[logKeySlow])
+	})
+}
@@ -14,3 +14,22 @@ type (
 func (m *Module) Configure(injector *dingo.Injector) {
 	injector.BindMulti(new(web.Filter)).To(logger{})
 }
+
+// CueConfig for the access log
+func (m *Module) CueConfig() string {
+	return `
+core: requestlogger: {
+	format: *"human" | "combined" | "json" | "logfmt"
+	output: *"logger" | "stdout" | "stderr"
+	fields: [...string]
+	exclude: [...string]
+	sampleRate: number | *1
+	slowThreshold: number | *0
+	headers: {
+		request: [...string]
+		response: [...string]
+		redact: [...string] | *["Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"]
+	}
+}
+`
+}
@@ -0,0 +1,177 @@
+package headers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/cache"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// headersFilter adds the security headers of the default policy, or the policy configured for the route of the request
+	headersFilter struct {
+		defaultPolicy *policy
+		routes        map[string]*policy
+	}
+
+	// privateResult marks the response of the wrapped result as private, so a nonce is never served to another request by a shared cache
+	privateResult struct {
+		result web.Result
+	}
+
+	// privateResponseWriter marks the Cache-Control header as private before the header is written
+	privateResponseWriter struct {
+		rw     http.ResponseWriter
+		marked bool
+	}
+
+	// headersConfig of core.security.headers
+	headersConfig struct {
+		policyConfig
+		Report struct {
+			Enabled bool   `json:"enabled"`
+			Path    string `json:"path"`
+		} `json:"report"`
+		Routes []policyConfig `json:"routes"`
+	}
+)
+
+// Inject dependencies
+func (f *headersFilter) Inject(cfg *struct {
+	Headers config.Map `inject:"config:core.security.headers,optional"`
+}) *headersFilter {
+	var headers headersConfig
+	if cfg != nil {
+		if err := cfg.Headers.MapInto(&headers); err != nil {
+			panic(fmt.Errorf("core.security.headers: %w", err))
+		}
+	}
+
+	var reportURI string
+	if headers.Report.Enabled {
+		reportURI = headers.Report.Path
+	}
+
+	f.defaultPolicy = newPolicy(headers.policyConfig, reportURI)
+	f.routes = make(map[string]*policy)
+	for _, route := range headers.Routes {
+		p := newPolicy(headers.policyConfig.merge(route), reportURI)
+		for _, handler := range route.Handlers {
+			f.routes[handler] = p
+		}
+	}
+
+	return f
+}
+
+// policy for the route of the request
+func (f *headersFilter) policyFor(ctx context.Context) *policy {
+	if handler := web.HandlerFromContext(ctx); handler != nil {
+		if p, ok := f.routes[handler.GetHandlerName()]; ok {
+			return p
+		}
+	}
+
+	return f.defaultPolicy
+}
+
+// Filter adds the security headers, the CSP nonce is stored in the request before the action runs
+func (f *headersFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	p := f.policyFor(ctx)
+
+	var nonce string
+	if p.nonce {
+		nonce = newNonce()
+		req.Values.Store(nonceKey, nonce)
+		// the page cache may run before or after this filter, so the request is skipped explicitly
+		cache.SkipPage(req)
+	}
+
+	p.apply(w.Header(), secure(req.Request()), nonce)
+
+	result := chain.Next(ctx, req, w)
+	if result == nil || nonce == "" {
+		return result
+	}
+
+	return PrivateResult(result)
+}
+
+// PrivateResult marks the response of the result as private, for responses which must not be stored by shared caches
+func PrivateResult(result web.Result) web.Result {
+	return &privateResult{result: result}
+}
+
+// Apply the wrapped result to a response writer marking the response as private
+func (r *privateResult) Apply(ctx context.Context, rw http.ResponseWriter) error {
+	return r.result.Apply(ctx, &privateResponseWriter{rw: rw})
+}
+
+// Header of the response
+func (w *privateResponseWriter) Header() http.Header {
+	return w.rw.Header()
+}
+
+// WriteHeader marks the response as private
+func (w *privateResponseWriter) WriteHeader(status int) {
+	if !w.marked {
+		w.marked = true
+		markPrivate(w.rw.Header())
+	}
+	w.rw.WriteHeader(status)
+}
+
+// Write the body, the header is written first if it is not yet
+func (w *privateResponseWriter) Write(b []byte) (int, error) {
+	if !w.marked {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.rw.Write(b)
+}
+
+// Flush implements http.Flusher for streaming responses
+func (w *privateResponseWriter) Flush() {
+	if !w.marked {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for websocket connections
+func (w *privateResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// markPrivate adds the private directive to Cache-Control, and removes the directives allowing shared caches to store the response
+func markPrivate(header http.Header) {
+	directives := []string{"private"}
+	for _, value := range header["Cache-Control"] {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			switch strings.ToLower(strings.SplitN(directive, "=", 2)[0]) {
+			case "", "private", "public", "s-maxage":
+				continue
+			}
+			directives = append(directives, directive)
+		}
+	}
+
+	header.Set("Cache-Control", strings.Join(directives, ", "))
+}
+
+// secure requests are served via TLS, directly or behind a proxy, browsers ignore HSTS headers of other requests
+func secure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,267 @@
+package headers
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/core/cache"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type filterConfig = struct {
+	Headers config.Map `inject:"config:core.security.headers,optional"`
+}
+
+// defaultConfig mirrors the defaults of the CueConfig
+func defaultConfig() config.Map {
+	return config.Map{
+		"hsts":               config.Map{"maxAge": float64(31536000), "includeSubDomains": true, "preload": false},
+		"contentTypeOptions": "nosniff",
+		"frameOptions":       "SAMEORIGIN",
+		"referrerPolicy":     "strict-origin-when-cross-origin",
+		"contentSecurityPolicy": config.Map{
+			"reportOnly": false,
+			"directives": config.Map{
+				"default-src":     config.Slice{"'self'"},
+				"script-src":      config.Slice{"'self'", "'nonce'"},
+				"object-src":      config.Slice{"'none'"},
+				"base-uri":        config.Slice{"'self'"},
+				"frame-ancestors": config.Slice{"'self'"},
+			},
+		},
+		"report": config.Map{"enabled": false, "path": "/_security/report"},
+	}
+}
+
+// headersFor returns the headers the filter sets for the request on the route, and the nonce the action got for rendering
+func headersFor(f *headersFilter, route string, request *http.Request) (header http.Header, nonce string) {
+	ctx := context.Background()
+	if route != "" {
+		ctx = web.ContextWithHandler(ctx, web.NewRegistry().MustRoute("/", route))
+	}
+
+	chain := web.NewFilterChain(func(ctx context.Context, req *web.Request, w http.ResponseWriter) web.Result {
+		nonce = Nonce(req)
+		return &web.Response{Status: http.StatusOK}
+	}, f)
+
+	recorder := httptest.NewRecorder()
+	_ = chain.Next(ctx, web.CreateRequest(request, nil), recorder)
+
+	return recorder.Header(), nonce
+}
+
+func TestHeadersFilter(t *testing.T) {
+	f := new(headersFilter).Inject(&filterConfig{Headers: defaultConfig()})
+
+	t.Run("defaults", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.TLS = new(tls.ConnectionState)
+		header, nonce := headersFor(f, "", request)
+
+		assert.Equal(t, "max-age=31536000; includeSubDomains", header.Get("Strict-Transport-Security"))
+		assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+		assert.Equal(t, "SAMEORIGIN", header.Get("X-Frame-Options"))
+		assert.Equal(t, "strict-origin-when-cross-origin", header.Get("Referrer-Policy"))
+		assert.Empty(t, header.Get("Permissions-Policy"))
+		assert.Empty(t, header.Get("Content-Security-Policy-Report-Only"))
+
+		require.Regexp(t, `^[A-Za-z0-9+/]{22}==$`, nonce)
+		assert.Equal(t,
+			"default-src 'self'; base-uri 'self'; frame-ancestors 'self'; object-src 'none'; script-src 'self' 'nonce-"+nonce+"'",
+			header.Get("Content-Security-Policy"),
+		)
+	})
+
+	t.Run("nonce per request", func(t *testing.T) {
+		_, first := headersFor(f, "", httptest.NewRequest(http.MethodGet, "/", nil))
+		_, second := headersFor(f, "", httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("hsts only for secure requests", func(t *testing.T) {
+		header, _ := headersFor(f, "", httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, header.Get("Strict-Transport-Security"))
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Forwarded-Proto", "https")
+		header, _ = headersFor(f, "", request)
+		assert.NotEmpty(t, header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("without nonce", func(t *testing.T) {
+		cfg := defaultConfig()
+		cfg["contentSecurityPolicy"].(config.Map)["directives"].(config.Map)["script-src"] = config.Slice{"'self'"}
+		header, nonce := headersFor(new(headersFilter).Inject(&filterConfig{Headers: cfg}), "", httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Empty(t, nonce)
+		assert.NotContains(t, header.Get("Content-Security-Policy"), "nonce")
+	})
+
+	t.Run("without configuration", func(t *testing.T) {
+		header, nonce := headersFor(new(headersFilter).Inject(nil), "", httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Empty(t, nonce)
+		assert.Empty(t, header)
+	})
+}
+
+func TestHeadersFilter_Private(t *testing.T) {
+	// apply the response of the action, which allows shared caches to store it
+	apply := func(f *headersFilter) http.Header {
+		chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+			return &web.Response{Status: http.StatusOK, CacheDirective: &web.CacheDirective{Visibility: web.CacheVisibilityPublic, MaxAge: 60, SMaxAge: 300}}
+		}, f)
+
+		recorder := httptest.NewRecorder()
+		request := web.CreateRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+		require.NoError(t, chain.Next(context.Background(), request, recorder).Apply(context.Background(), recorder))
+
+		return recorder.Header()
+	}
+
+	t.Run("nonce", func(t *testing.T) {
+		assert.Equal(t, []string{"private, max-age=60"}, apply(new(headersFilter).Inject(&filterConfig{Headers: defaultConfig()}))["Cache-Control"])
+	})
+
+	t.Run("without nonce", func(t *testing.T) {
+		assert.Equal(t, "max-age=60, s-maxage=300, public", apply(new(headersFilter).Inject(nil)).Get("Cache-Control"))
+	})
+}
+
+func TestHeadersFilter_PageCache(t *testing.T) {
+	responder := new(web.Responder)
+
+	// serve two requests through the page cache and the headers filter in the given order, the action renders the nonce
+	serveTwice := func(filters func(pageCache, headers web.Filter) []web.Filter, headers *headersFilter) (first, second *httptest.ResponseRecorder) {
+		pageCache := new(cache.PageCacheFilter).Inject(flamingo.NullLogger{}, responder, &struct {
+			Backend     cache.Backend `inject:"core.cache.page,optional"`
+			QueryParams config.Slice  `inject:"config:core.cache.page.queryParams,optional"`
+			Lifetime    float64       `inject:"config:core.cache.page.lifetime,optional"`
+			Gracetime   float64       `inject:"config:core.cache.page.gracetime,optional"`
+			Header      string        `inject:"config:core.cache.page.header,optional"`
+		}{Lifetime: 60, Header: "X-Cache"})
+
+		get := func() *httptest.ResponseRecorder {
+			chain := web.NewFilterChain(func(ctx context.Context, req *web.Request, w http.ResponseWriter) web.Result {
+				return responder.Data(Nonce(req))
+			}, filters(pageCache, headers)...)
+
+			ctx := context.Background()
+			recorder := httptest.NewRecorder()
+			require.NoError(t, chain.Next(ctx, web.CreateRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil), recorder).Apply(ctx, recorder))
+			return recorder
+		}
+
+		return get(), get()
+	}
+
+	pageCacheFirst := func(pageCache, headers web.Filter) []web.Filter { return []web.Filter{pageCache, headers} }
+	headersFirst := func(pageCache, headers web.Filter) []web.Filter { return []web.Filter{headers, pageCache} }
+
+	t.Run("page cache before the headers filter", func(t *testing.T) {
+		first, second := serveTwice(pageCacheFirst, new(headersFilter).Inject(&filterConfig{Headers: defaultConfig()}))
+		assert.NotEqual(t, cache.PageCacheHit, second.Header().Get("X-Cache"))
+		assert.NotEqual(t, first.Body.String(), second.Body.String(), "the nonce is not served from cache")
+	})
+
+	t.Run("headers filter before the page cache", func(t *testing.T) {
+		first, second := serveTwice(headersFirst, new(headersFilter).Inject(&filterConfig{Headers: defaultConfig()}))
+		assert.Equal(t, cache.PageCacheBypass, second.Header().Get("X-Cache"))
+		assert.NotEqual(t, first.Body.String(), second.Body.String(), "the nonce is not served from cache")
+	})
+
+	t.Run("without nonce", func(t *testing.T) {
+		for _, filters := range []func(pageCache, headers web.Filter) []web.Filter{pageCacheFirst, headersFirst} {
+			_, second := serveTwice(filters, new(headersFilter).Inject(nil))
+			assert.Equal(t, cache.PageCacheHit, second.Header().Get("X-Cache"))
+		}
+	})
+}
+
+func TestHeadersFilter_ReportOnly(t *testing.T) {
+	cfg := defaultConfig()
+	cfg["contentSecurityPolicy"].(config.Map)["reportOnly"] = true
+	cfg["report"] = config.Map{"enabled": true, "path": "/_security/report"}
+	f := new(headersFilter).Inject(&filterConfig{Headers: cfg})
+
+	header, _ := headersFor(f, "", httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Empty(t, header.Get("Content-Security-Policy"))
+	assert.Regexp(t, `; report-uri /_security/report; script-src`, header.Get("Content-Security-Policy-Report-Only"))
+}
+
+func TestHeadersFilter_Routes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg["permissionsPolicy"] = config.Map{"camera": config.Slice{}, "geolocation": config.Slice{"self", "https://maps.example.com"}, "fullscreen": config.Slice{"*"}}
+	cfg["routes"] = config.Slice{
+		config.Map{
+			"handlers":     config.Slice{"checkout.payment", "checkout.review"},
+			"frameOptions": "",
+			"hsts":         config.Map{"preload": true},
+			"contentSecurityPolicy": config.Map{
+				"reportOnly": true,
+				"directives": config.Map{
+					"frame-ancestors":           config.Slice{"https://payment.example.com"},
+					"object-src":                nil,
+					"upgrade-insecure-requests": config.Slice{},
+				},
+			},
+			"permissionsPolicy": config.Map{"payment": config.Slice{"self"}, "camera": nil},
+		},
+	}
+	f := new(headersFilter).Inject(&filterConfig{Headers: cfg})
+
+	request := func() *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.TLS = new(tls.ConnectionState)
+		return request
+	}
+
+	t.Run("default policy", func(t *testing.T) {
+		header, _ := headersFor(f, "home", request())
+
+		assert.Equal(t, "SAMEORIGIN", header.Get("X-Frame-Options"))
+		assert.Equal(t, `camera=(), fullscreen=*, geolocation=(self "https://maps.example.com")`, header.Get("Permissions-Policy"))
+		assert.Contains(t, header.Get("Content-Security-Policy"), "frame-ancestors 'self'")
+	})
+
+	t.Run("route policy", func(t *testing.T) {
+		header, nonce := headersFor(f, "checkout.payment", request())
+
+		assert.Empty(t, header.Get("X-Frame-Options"), "empty values remove the header")
+		assert.Equal(t, "nosniff", header.Get("X-Content-Type-Options"), "unset values are taken from the default policy")
+		assert.Equal(t, "max-age=31536000; includeSubDomains; preload", header.Get("Strict-Transport-Security"))
+		assert.Equal(t, `fullscreen=*, geolocation=(self "https://maps.example.com"), payment=(self)`, header.Get("Permissions-Policy"))
+		assert.Empty(t, header.Get("Content-Security-Policy"))
+		assert.Equal(t,
+			"default-src 'self'; base-uri 'self'; frame-ancestors https://payment.example.com; script-src 'self' 'nonce-"+nonce+"'; upgrade-insecure-requests",
+			header.Get("Content-Security-Policy-Report-Only"),
+		)
+
+		other, _ := headersFor(f, "checkout.review", request())
+		assert.Equal(t, header.Get("Permissions-Policy"), other.Get("Permissions-Policy"))
+	})
+}
+
+func TestNonceFunc(t *testing.T) {
+	req := web.CreateRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	fnc := new(nonceFunc).Func(web.ContextWithRequest(context.Background(), req)).(func() string)
+	assert.Empty(t, fnc())
+
+	req.Values.Store(nonceKey, "4711")
+	assert.Equal(t, "4711", fnc())
+
+	assert.Empty(t, new(nonceFunc).Func(context.Background()).(func() string)(), "the nonce is empty without request")
+	assert.True(t, regexp.MustCompile(`^[A-Za-z0-9+/]{22}==$`).MatchString(newNonce()))
+}
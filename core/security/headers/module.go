@@ -0,0 +1,106 @@
+package headers
+
+import (
+	"flamingo.me/dingo"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// Module adds security headers like HSTS and a content security policy to every response
+	Module struct {
+		reportEnabled bool
+	}
+
+	routes struct {
+		controller *reportController
+		path       string
+	}
+)
+
+// ReportHandler is the handler name of the report endpoint
+const ReportHandler = "core.security.headers.report"
+
+// Inject dependencies
+func (m *Module) Inject(cfg *struct {
+	ReportEnabled bool `inject:"config:core.security.headers.report.enabled,optional"`
+}) *Module {
+	if cfg != nil {
+		m.reportEnabled = cfg.ReportEnabled
+	}
+	return m
+}
+
+// Configure DI
+func (m *Module) Configure(injector *dingo.Injector) {
+	injector.BindMulti(new(web.Filter)).To(new(headersFilter))
+	flamingo.BindTemplateFunc(injector, "cspNonce", new(nonceFunc))
+
+	if m.reportEnabled {
+		web.BindRoutes(injector, new(routes))
+	}
+}
+
+// Inject dependencies
+func (r *routes) Inject(controller *reportController, cfg *struct {
+	Path string `inject:"config:core.security.headers.report.path"`
+}) *routes {
+	r.controller = controller
+	r.path = cfg.Path
+	return r
+}
+
+// Routes of the report endpoint
+func (r *routes) Routes(registry *web.RouterRegistry) {
+	registry.HandlePost(ReportHandler, r.controller.Report)
+	web.MustRoute(registry.Route(r.path, ReportHandler))
+}
+
+// CueConfig schema
+func (*Module) CueConfig() string {
+	return `
+core: security: headers: {
+	hsts: {
+		maxAge: int | *31536000
+		includeSubDomains: bool | *true
+		preload: bool | *false
+	}
+	contentTypeOptions: string | *"nosniff"
+	frameOptions: string | *"SAMEORIGIN"
+	referrerPolicy: string | *"strict-origin-when-cross-origin"
+	permissionsPolicy: [string]: [...string] | null
+	contentSecurityPolicy: {
+		reportOnly: bool | *false
+		directives: {
+			"default-src": [...string] | null | *["'self'"]
+			"script-src": [...string] | null | *["'self'", "'nonce'"]
+			"object-src": [...string] | null | *["'none'"]
+			"base-uri": [...string] | null | *["'self'"]
+			"frame-ancestors": [...string] | null | *["'self'"]
+			[string]: [...string] | null
+		}
+	}
+	report: {
+		enabled: bool | *false
+		path: string | *"/_security/report"
+	}
+	routes: [...{
+		handlers: [...string]
+		hsts?: {
+			maxAge?: int
+			includeSubDomains?: bool
+			preload?: bool
+		}
+		contentTypeOptions?: string
+		frameOptions?: string
+		referrerPolicy?: string
+		permissionsPolicy?: [string]: [...string] | null
+		contentSecurityPolicy?: {
+			reportOnly?: bool
+			directives?: [string]: [...string] | null
+		}
+	}]
+}
+`
+}
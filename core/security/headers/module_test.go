@@ -0,0 +1,14 @@
+package headers_test
+
+import (
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/security/headers"
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+func TestModule_Configure(t *testing.T) {
+	if err := config.TryModules(config.Map{"core.security.headers.report.enabled": true}, new(headers.Module)); err != nil {
+		t.Error(err)
+	}
+}
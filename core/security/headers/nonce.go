@@ -0,0 +1,49 @@
+package headers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// nonceFunc renders the CSP nonce of the request
+	nonceFunc struct{}
+
+	nonceKeyType struct{}
+)
+
+// nonceKey of the nonce in web.Request.Values
+var nonceKey nonceKeyType
+
+// Nonce returns the CSP nonce of the request, it is empty if the content security policy of the route has no 'nonce' source
+func Nonce(req *web.Request) string {
+	if req == nil {
+		return ""
+	}
+
+	if nonce, ok := req.Values.Load(nonceKey); ok {
+		return nonce.(string)
+	}
+
+	return ""
+}
+
+// newNonce generates 128 random bits
+func newNonce() string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(nonce)
+}
+
+// Func returns the cspNonce template function
+func (*nonceFunc) Func(ctx context.Context) interface{} {
+	return func() string {
+		return Nonce(web.RequestFromContext(ctx))
+	}
+}
@@ -0,0 +1,224 @@
+package headers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// policy of the security headers of a response
+	policy struct {
+		header    http.Header
+		hsts      string
+		csp       string
+		cspHeader string
+		nonce     bool
+	}
+
+	// policyConfig of core.security.headers, unset fields of route policies are taken from the default policy
+	policyConfig struct {
+		Handlers              []string               `json:"handlers"`
+		HSTS                  *hstsConfig            `json:"hsts"`
+		ContentTypeOptions    *string                `json:"contentTypeOptions"`
+		FrameOptions          *string                `json:"frameOptions"`
+		ReferrerPolicy        *string                `json:"referrerPolicy"`
+		PermissionsPolicy     map[string][]string    `json:"permissionsPolicy"`
+		ContentSecurityPolicy *contentSecurityConfig `json:"contentSecurityPolicy"`
+	}
+
+	hstsConfig struct {
+		MaxAge            *float64 `json:"maxAge"`
+		IncludeSubDomains *bool    `json:"includeSubDomains"`
+		Preload           *bool    `json:"preload"`
+	}
+
+	contentSecurityConfig struct {
+		ReportOnly *bool               `json:"reportOnly"`
+		Directives map[string][]string `json:"directives"`
+	}
+)
+
+// nonceSource is replaced by the nonce of the request
+const nonceSource = "'nonce'"
+
+// merge the route policy into the default policy, directives set to null are removed
+func (c policyConfig) merge(route policyConfig) policyConfig {
+	merged := c
+	merged.Handlers = route.Handlers
+
+	if route.HSTS != nil {
+		hsts := hstsConfig{}
+		if c.HSTS != nil {
+			hsts = *c.HSTS
+		}
+		if route.HSTS.MaxAge != nil {
+			hsts.MaxAge = route.HSTS.MaxAge
+		}
+		if route.HSTS.IncludeSubDomains != nil {
+			hsts.IncludeSubDomains = route.HSTS.IncludeSubDomains
+		}
+		if route.HSTS.Preload != nil {
+			hsts.Preload = route.HSTS.Preload
+		}
+		merged.HSTS = &hsts
+	}
+	if route.ContentTypeOptions != nil {
+		merged.ContentTypeOptions = route.ContentTypeOptions
+	}
+	if route.FrameOptions != nil {
+		merged.FrameOptions = route.FrameOptions
+	}
+	if route.ReferrerPolicy != nil {
+		merged.ReferrerPolicy = route.ReferrerPolicy
+	}
+	merged.PermissionsPolicy = mergeDirectives(c.PermissionsPolicy, route.PermissionsPolicy)
+
+	if route.ContentSecurityPolicy != nil {
+		csp := contentSecurityConfig{}
+		if c.ContentSecurityPolicy != nil {
+			csp = *c.ContentSecurityPolicy
+		}
+		if route.ContentSecurityPolicy.ReportOnly != nil {
+			csp.ReportOnly = route.ContentSecurityPolicy.ReportOnly
+		}
+		csp.Directives = mergeDirectives(csp.Directives, route.ContentSecurityPolicy.Directives)
+		merged.ContentSecurityPolicy = &csp
+	}
+
+	return merged
+}
+
+func mergeDirectives(base, override map[string][]string) map[string][]string {
+	merged := make(map[string][]string, len(base)+len(override))
+	for name, values := range base {
+		merged[name] = values
+	}
+	for name, values := range override {
+		merged[name] = values
+	}
+
+	return merged
+}
+
+// newPolicy renders the headers of the policy, the report URI is added to the CSP unless it has a report-uri directive
+func newPolicy(cfg policyConfig, reportURI string) *policy {
+	p := &policy{header: make(http.Header)}
+
+	setIfNotEmpty := func(name string, value *string) {
+		if value != nil && *value != "" {
+			p.header.Set(name, *value)
+		}
+	}
+	setIfNotEmpty("X-Content-Type-Options", cfg.ContentTypeOptions)
+	setIfNotEmpty("X-Frame-Options", cfg.FrameOptions)
+	setIfNotEmpty("Referrer-Policy", cfg.ReferrerPolicy)
+
+	if permissions := permissionsPolicy(cfg.PermissionsPolicy); permissions != "" {
+		p.header.Set("Permissions-Policy", permissions)
+	}
+
+	if cfg.HSTS != nil && cfg.HSTS.MaxAge != nil && *cfg.HSTS.MaxAge > 0 {
+		p.hsts = "max-age=" + strconv.Itoa(int(*cfg.HSTS.MaxAge))
+		if cfg.HSTS.IncludeSubDomains != nil && *cfg.HSTS.IncludeSubDomains {
+			p.hsts += "; includeSubDomains"
+		}
+		if cfg.HSTS.Preload != nil && *cfg.HSTS.Preload {
+			p.hsts += "; preload"
+		}
+	}
+
+	if cfg.ContentSecurityPolicy != nil {
+		directives := cfg.ContentSecurityPolicy.Directives
+		if _, ok := directives["report-uri"]; !ok && reportURI != "" && len(directives) > 0 {
+			directives = mergeDirectives(directives, map[string][]string{"report-uri": {reportURI}})
+		}
+		p.csp = contentSecurityPolicy(directives)
+		p.nonce = strings.Contains(p.csp, nonceSource)
+
+		p.cspHeader = "Content-Security-Policy"
+		if cfg.ContentSecurityPolicy.ReportOnly != nil && *cfg.ContentSecurityPolicy.ReportOnly {
+			p.cspHeader = "Content-Security-Policy-Report-Only"
+		}
+	}
+
+	return p
+}
+
+// contentSecurityPolicy renders the directives, default-src first and the others sorted by name.
+// Directives without sources, e.g. upgrade-insecure-requests, are rendered without value, directives set to null are left out.
+func contentSecurityPolicy(directives map[string][]string) string {
+	parts := make([]string, 0, len(directives))
+	for _, name := range sortedNames(directives, "default-src") {
+		sources := directives[name]
+		if sources == nil {
+			continue
+		}
+		parts = append(parts, strings.TrimSpace(name+" "+strings.Join(sources, " ")))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// permissionsPolicy renders the features with their allowlists, e.g. `camera=(), geolocation=(self "https://maps.example.com")`
+func permissionsPolicy(features map[string][]string) string {
+	parts := make([]string, 0, len(features))
+	for _, name := range sortedNames(features, "") {
+		allowlist := features[name]
+		if allowlist == nil {
+			continue
+		}
+
+		if len(allowlist) == 1 && allowlist[0] == "*" {
+			parts = append(parts, name+"=*")
+			continue
+		}
+
+		origins := make([]string, len(allowlist))
+		for i, origin := range allowlist {
+			if origin == "self" || origin == "src" {
+				origins[i] = origin
+				continue
+			}
+			origins[i] = strconv.Quote(origin)
+		}
+		parts = append(parts, name+"=("+strings.Join(origins, " ")+")")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func sortedNames(m map[string][]string, first string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == first || names[j] == first {
+			return names[i] == first
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// apply the headers of the policy, the nonce replaces the 'nonce' sources of the CSP
+func (p *policy) apply(header http.Header, secure bool, nonce string) {
+	for name := range p.header {
+		header.Set(name, p.header.Get(name))
+	}
+
+	if secure && p.hsts != "" {
+		header.Set("Strict-Transport-Security", p.hsts)
+	}
+
+	if p.csp != "" {
+		csp := p.csp
+		if p.nonce {
+			csp = strings.ReplaceAll(csp, nonceSource, "'nonce-"+nonce+"'")
+		}
+		header.Set(p.cspHeader, csp)
+	}
+}
@@ -0,0 +1,160 @@
+package headers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// Report of a content security policy violation
+	Report struct {
+		DocumentURL        string
+		EffectiveDirective string
+		BlockedURL         string
+		SourceFile         string
+		LineNumber         int
+		ColumnNumber       int
+		Disposition        string
+		Sample             string
+	}
+
+	// ReportEvent is dispatched for every report received by the report endpoint
+	ReportEvent struct {
+		Request *web.Request
+		Report  Report
+	}
+
+	// reportController collects reports of browsers, sent via the report-uri directive or the Reporting API
+	reportController struct {
+		responder   *web.Responder
+		eventRouter flamingo.EventRouter
+		logger      flamingo.Logger
+	}
+
+	// cspReport of the report-uri directive, sent as application/csp-report
+	cspReport struct {
+		CSPReport struct {
+			DocumentURI        string `json:"document-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			BlockedURI         string `json:"blocked-uri"`
+			SourceFile         string `json:"source-file"`
+			LineNumber         int    `json:"line-number"`
+			ColumnNumber       int    `json:"column-number"`
+			Disposition        string `json:"disposition"`
+			ScriptSample       string `json:"script-sample"`
+		} `json:"csp-report"`
+	}
+
+	// reportingAPIReport of the report-to directive, sent as application/reports+json
+	reportingAPIReport struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+		Body struct {
+			DocumentURL        string `json:"documentURL"`
+			EffectiveDirective string `json:"effectiveDirective"`
+			BlockedURL         string `json:"blockedURL"`
+			SourceFile         string `json:"sourceFile"`
+			LineNumber         int    `json:"lineNumber"`
+			ColumnNumber       int    `json:"columnNumber"`
+			Disposition        string `json:"disposition"`
+			Sample             string `json:"sample"`
+		} `json:"body"`
+	}
+)
+
+// maxReportSize limits the body of report requests
+const maxReportSize = 64 << 10
+
+// Inject dependencies
+func (c *reportController) Inject(responder *web.Responder, eventRouter flamingo.EventRouter, logger flamingo.Logger) *reportController {
+	c.responder = responder
+	c.eventRouter = eventRouter
+	c.logger = logger.WithField(flamingo.LogKeyModule, "security.headers")
+	return c
+}
+
+// Report logs the received reports and dispatches a ReportEvent for each
+func (c *reportController) Report(ctx context.Context, req *web.Request) web.Result {
+	body, err := ioutil.ReadAll(io.LimitReader(req.Request().Body, maxReportSize+1))
+	if err != nil {
+		return c.responder.BadRequest(err)
+	}
+	if len(body) > maxReportSize {
+		return c.responder.BadRequest(errors.New("report too large"))
+	}
+
+	reports, err := parseReports(req.Request().Header.Get("Content-Type"), body)
+	if err != nil {
+		return c.responder.BadRequest(err)
+	}
+
+	for _, report := range reports {
+		c.logger.WithContext(ctx).Info(fmt.Sprintf("content security policy violation on %s: %s blocked %s (%s)", report.DocumentURL, report.EffectiveDirective, report.BlockedURL, report.Disposition))
+		c.eventRouter.Dispatch(ctx, &ReportEvent{Request: req, Report: report})
+	}
+
+	return &web.Response{Status: http.StatusNoContent}
+}
+
+// parseReports of the report-uri directive, or CSP violations of the Reporting API
+func parseReports(contentType string, body []byte) ([]Report, error) {
+	if strings.HasPrefix(contentType, "application/reports+json") {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return nil, err
+		}
+
+		result := make([]Report, 0, len(reports))
+		for _, report := range reports {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			documentURL := report.Body.DocumentURL
+			if documentURL == "" {
+				documentURL = report.URL
+			}
+			result = append(result, Report{
+				DocumentURL:        documentURL,
+				EffectiveDirective: report.Body.EffectiveDirective,
+				BlockedURL:         report.Body.BlockedURL,
+				SourceFile:         report.Body.SourceFile,
+				LineNumber:         report.Body.LineNumber,
+				ColumnNumber:       report.Body.ColumnNumber,
+				Disposition:        report.Body.Disposition,
+				Sample:             report.Body.Sample,
+			})
+		}
+		return result, nil
+	}
+
+	var report cspReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, err
+	}
+
+	directive := report.CSPReport.EffectiveDirective
+	if directive == "" {
+		directive = report.CSPReport.ViolatedDirective
+	}
+
+	return []Report{{
+		DocumentURL:        report.CSPReport.DocumentURI,
+		EffectiveDirective: directive,
+		BlockedURL:         report.CSPReport.BlockedURI,
+		SourceFile:         report.CSPReport.SourceFile,
+		LineNumber:         report.CSPReport.LineNumber,
+		ColumnNumber:       report.CSPReport.ColumnNumber,
+		Disposition:        report.CSPReport.Disposition,
+		Sample:             report.CSPReport.ScriptSample,
+	}}, nil
+}
@@ -0,0 +1,82 @@
+package headers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type recordingEventRouter struct {
+	events []flamingo.Event
+}
+
+func (r *recordingEventRouter) Dispatch(_ context.Context, event flamingo.Event) {
+	r.events = append(r.events, event)
+}
+
+func report(contentType, body string) (web.Result, *recordingEventRouter) {
+	eventRouter := new(recordingEventRouter)
+	controller := new(reportController).Inject(new(web.Responder), eventRouter, flamingo.NullLogger{})
+
+	request := httptest.NewRequest(http.MethodPost, "/_security/report", strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+
+	return controller.Report(context.Background(), web.CreateRequest(request, nil)), eventRouter
+}
+
+func TestReportController(t *testing.T) {
+	t.Run("report-uri", func(t *testing.T) {
+		result, eventRouter := report("application/csp-report", `{"csp-report":{
+			"document-uri":"https://example.com/checkout",
+			"violated-directive":"script-src-elem",
+			"blocked-uri":"inline",
+			"line-number":12,
+			"disposition":"report"
+		}}`)
+
+		require.IsType(t, new(web.Response), result)
+		assert.Equal(t, uint(http.StatusNoContent), result.(*web.Response).Status)
+		require.Len(t, eventRouter.events, 1)
+		assert.Equal(t, Report{
+			DocumentURL:        "https://example.com/checkout",
+			EffectiveDirective: "script-src-elem",
+			BlockedURL:         "inline",
+			LineNumber:         12,
+			Disposition:        "report",
+		}, eventRouter.events[0].(*ReportEvent).Report)
+	})
+
+	t.Run("reporting API", func(t *testing.T) {
+		result, eventRouter := report("application/reports+json", `[
+			{"type":"csp-violation","url":"https://example.com/","body":{"effectiveDirective":"img-src","blockedURL":"https://tracker.example.org/pixel.gif","disposition":"enforce"}},
+			{"type":"deprecation","url":"https://example.com/","body":{}}
+		]`)
+
+		assert.Equal(t, uint(http.StatusNoContent), result.(*web.Response).Status)
+		require.Len(t, eventRouter.events, 1, "other report types are ignored")
+		assert.Equal(t, Report{
+			DocumentURL:        "https://example.com/",
+			EffectiveDirective: "img-src",
+			BlockedURL:         "https://tracker.example.org/pixel.gif",
+			Disposition:        "enforce",
+		}, eventRouter.events[0].(*ReportEvent).Report)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{"no json", `{"csp-report":"` + strings.Repeat("a", maxReportSize) + `"}`} {
+			result, eventRouter := report("application/csp-report", body)
+
+			require.IsType(t, new(web.ServerErrorResponse), result)
+			assert.Equal(t, uint(http.StatusBadRequest), result.(*web.ServerErrorResponse).Response.Status)
+			assert.Empty(t, eventRouter.events)
+		}
+	})
+}
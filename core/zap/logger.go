@@ -39,7 +39,7 @@ type (
 // WithContext returns a logger with fields filled from the context
 // businessId:    From Header X-Business-ID
 // client_ip:     From Header X-Forwarded-For or request if header is empty
-// correlationId: The ID of the context
+// correlationId: The request ID of the context, see flamingo.ContextWithRequestID
 // method:        HTTP verb from request
 // path:          URL path from request
 // referer:       referer from request
@@ -51,6 +51,10 @@ func (l *Logger) WithContext(ctx context.Context) flamingo.Logger {
 		flamingo.LogKeySpanID:  span.SpanContext().SpanID.String(),
 	}
 
+	if id := flamingo.RequestIDFromContext(ctx); id != "" {
+		fields[flamingo.LogKeyCorrelationID] = id
+	}
+
 	req := web.RequestFromContext(ctx)
 
 	if req != nil {
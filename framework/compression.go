@@ -0,0 +1,33 @@
+package framework
+
+import (
+	"flamingo.me/dingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"flamingo.me/flamingo/v3/framework/web/filter"
+)
+
+// CompressionModule compresses responses with gzip or deflate
+type CompressionModule struct{}
+
+// Configure DI
+func (*CompressionModule) Configure(injector *dingo.Injector) {
+	injector.BindMulti(new(web.Filter)).To(new(filter.CompressionFilter))
+}
+
+// CueConfig for the compression level, the minimum size and the compressed content types
+func (*CompressionModule) CueConfig() string {
+	return `
+flamingo: web: compression: {
+	level: int | *-1
+	minSize: int | *1024
+	types: [...string]
+}
+`
+}
+
+// Depends on the InitModule
+func (*CompressionModule) Depends() []dingo.Module {
+	return []dingo.Module{
+		new(InitModule),
+	}
+}
@@ -62,6 +62,7 @@ type (
 		Path       string
 		Controller string
 		Name       string
+		Filters    []string
 	}
 )
 
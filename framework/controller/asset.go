@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// Assets serves static assets with fingerprinted names from a http.FileSystem.
+	// Fingerprints are taken from a manifest file, or calculated from the file contents.
+	Assets struct {
+		fileSystem   http.FileSystem
+		manifestFile string
+		logger       flamingo.Logger
+
+		once      sync.Once
+		manifest  map[string]string
+		originals map[string]string
+	}
+
+	// AssetFunc is exported as a template function, it resolves asset names to fingerprinted URLs
+	AssetFunc struct {
+		assets *Assets
+		router web.ReverseRouter
+		logger flamingo.Logger
+	}
+
+	assetResponse struct {
+		assets *Assets
+		r      *web.Request
+	}
+)
+
+const (
+	// AssetHandler is the handler name of the asset controller, its route needs a `name` param, e.g. `/assets/*name`
+	AssetHandler = "flamingo.static.asset"
+
+	// assetMaxAge is one year, fingerprinted assets never change
+	assetMaxAge = 365 * 24 * 60 * 60
+)
+
+// precompressedEncodings are the file extensions of precompressed siblings, ordered by preference
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{encoding: "br", extension: ".br"},
+	{encoding: "gzip", extension: ".gz"},
+}
+
+// Inject dependencies
+func (a *Assets) Inject(logger flamingo.Logger, cfg *struct {
+	Dir        string          `inject:"config:flamingo.static.assets.dir,optional"`
+	Manifest   string          `inject:"config:flamingo.static.assets.manifest,optional"`
+	FileSystem http.FileSystem `inject:"flamingo.static.assets,optional"`
+}) *Assets {
+	a.logger = logger.WithField(flamingo.LogKeyModule, "framework").WithField(flamingo.LogKeyCategory, "assets")
+	if cfg != nil {
+		a.fileSystem = cfg.FileSystem
+		if a.fileSystem == nil && cfg.Dir != "" {
+			a.fileSystem = http.Dir(cfg.Dir)
+		}
+		a.manifestFile = cfg.Manifest
+	}
+	return a
+}
+
+// Fingerprinted returns the fingerprinted name of the asset, or the name itself if the asset is unknown
+func (a *Assets) Fingerprinted(name string) string {
+	a.load()
+
+	if fingerprinted, ok := a.manifest[strings.TrimPrefix(name, "/")]; ok {
+		return fingerprinted
+	}
+	return name
+}
+
+// load reads the manifest or fingerprints the file system once
+func (a *Assets) load() {
+	a.once.Do(func() {
+		a.manifest = make(map[string]string)
+		a.originals = make(map[string]string)
+
+		if a.fileSystem == nil {
+			return
+		}
+
+		var err error
+		if a.manifestFile != "" {
+			err = a.readManifest()
+		} else {
+			err = a.fingerprint("/")
+		}
+		if err != nil && a.logger != nil {
+			a.logger.Error("assets: ", err)
+		}
+
+		for name, fingerprinted := range a.manifest {
+			a.originals[fingerprinted] = name
+		}
+	})
+}
+
+// readManifest reads a JSON object, mapping asset names to fingerprinted names
+func (a *Assets) readManifest() error {
+	file, err := a.fileSystem.Open(a.manifestFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	manifest := make(map[string]string)
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return err
+	}
+	for name, fingerprinted := range manifest {
+		a.manifest[strings.TrimPrefix(name, "/")] = strings.TrimPrefix(fingerprinted, "/")
+	}
+	return nil
+}
+
+// fingerprint walks the file system and adds the content hash of each file to its name
+func (a *Assets) fingerprint(dir string) error {
+	file, err := a.fileSystem.Open(dir)
+	if err != nil {
+		return err
+	}
+	infos, err := file.Readdir(-1)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if info.IsDir() {
+			if err := a.fingerprint(name); err != nil {
+				return err
+			}
+			continue
+		}
+		if isPrecompressed(name) {
+			continue
+		}
+
+		hash, err := a.hash(name)
+		if err != nil {
+			return err
+		}
+
+		ext := path.Ext(name)
+		name = strings.TrimPrefix(name, "/")
+		a.manifest[name] = strings.TrimSuffix(name, ext) + "." + hash + ext
+	}
+
+	return nil
+}
+
+func (a *Assets) hash(name string) (string, error) {
+	file, err := a.fileSystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// open returns the requested asset, or a precompressed sibling if the client accepts its encoding
+func (a *Assets) open(name string, acceptEncoding string) (http.File, string, error) {
+	for _, precompressed := range precompressedEncodings {
+		if !acceptsEncoding(acceptEncoding, precompressed.encoding) {
+			continue
+		}
+		if file, err := a.fileSystem.Open(name + precompressed.extension); err == nil {
+			return file, precompressed.encoding, nil
+		}
+	}
+
+	file, err := a.fileSystem.Open(name)
+	return file, "", err
+}
+
+// Asset serves a static asset, fingerprinted assets are cached for a year
+func (s *Static) Asset(ctx context.Context, r *web.Request) web.Result {
+	return &assetResponse{assets: s.assets, r: r}
+}
+
+// Apply result by serving the asset via http.ServeContent
+func (ar *assetResponse) Apply(ctx context.Context, rw http.ResponseWriter) error {
+	req := ar.r.Request()
+	if ar.assets == nil || ar.assets.fileSystem == nil {
+		http.NotFound(rw, req)
+		return nil
+	}
+	ar.assets.load()
+
+	name := strings.TrimPrefix(path.Clean("/"+ar.r.Params["name"]), "/")
+
+	// fingerprinted files either exist, if they are built along with a manifest, or are served from the original file
+	original, fingerprinted := ar.assets.originals[name]
+	if !fingerprinted {
+		original = name
+	} else if file, err := ar.assets.fileSystem.Open(name); err == nil {
+		_ = file.Close()
+		original = name
+	}
+
+	file, encoding, err := ar.assets.open(original, req.Header.Get("Accept-Encoding"))
+	if err != nil {
+		http.NotFound(rw, req)
+		return nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(rw, req)
+		return nil
+	}
+
+	header := rw.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+		contentType := mime.TypeByExtension(path.Ext(original))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+	}
+	if fingerprinted {
+		(&web.CacheDirective{Visibility: web.CacheVisibilityPublic, MaxAge: assetMaxAge, Immutable: true}).ApplyHeaders(header)
+	}
+
+	http.ServeContent(rw, req, original, info.ModTime(), file)
+	return nil
+}
+
+// Inject dependencies
+func (f *AssetFunc) Inject(assets *Assets, router web.ReverseRouter, logger flamingo.Logger) *AssetFunc {
+	f.assets = assets
+	f.router = router
+	f.logger = logger
+	return f
+}
+
+// Func returns the asset func, which resolves an asset name to the URL of the fingerprinted asset
+func (f *AssetFunc) Func(ctx context.Context) interface{} {
+	return func(name string) string {
+		u, err := f.router.Relative(AssetHandler, map[string]string{"name": f.assets.Fingerprinted(name)})
+		if err != nil {
+			f.logger.WithContext(ctx).Warn("asset ", strconv.Quote(name), ": ", err)
+			return ""
+		}
+		return u.String()
+	}
+}
+
+func isPrecompressed(name string) bool {
+	for _, precompressed := range precompressedEncodings {
+		if strings.HasSuffix(name, precompressed.extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding checks if the encoding is listed in the Accept-Encoding header, and not excluded via q=0
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(entry, ";")
+		if !strings.EqualFold(strings.TrimSpace(parts[0]), encoding) {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(param), "q="), 64); err == nil && q <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+func assetDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "flamingo-assets")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func serveAsset(assets *Assets, name string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/assets/"+name, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	req := web.CreateRequest(request, nil)
+	req.Params["name"] = name
+
+	recorder := httptest.NewRecorder()
+	_ = (&Static{assets: assets}).Asset(context.Background(), req).Apply(context.Background(), recorder)
+	return recorder
+}
+
+func TestAssets(t *testing.T) {
+	dir := assetDir(t, map[string]string{
+		"js/app.js":    "console.log(1)",
+		"js/app.js.gz": "gzipped",
+		"js/app.js.br": "brotli",
+		"LICENSE":      "MIT",
+	})
+	defer os.RemoveAll(dir)
+
+	assets := new(Assets).Inject(flamingo.NullLogger{}, &struct {
+		Dir        string          `inject:"config:flamingo.static.assets.dir,optional"`
+		Manifest   string          `inject:"config:flamingo.static.assets.manifest,optional"`
+		FileSystem http.FileSystem `inject:"flamingo.static.assets,optional"`
+	}{Dir: dir})
+
+	fingerprinted := assets.Fingerprinted("js/app.js")
+	assert.Regexp(t, `^js/app\.[0-9a-f]{16}\.js$`, fingerprinted)
+	assert.Regexp(t, `^LICENSE\.[0-9a-f]{16}$`, assets.Fingerprinted("/LICENSE"))
+	assert.Equal(t, "unknown.css", assets.Fingerprinted("unknown.css"))
+	assert.Equal(t, "js/app.js.gz", assets.Fingerprinted("js/app.js.gz"), "precompressed files are not fingerprinted")
+
+	t.Run("fingerprinted", func(t *testing.T) {
+		recorder := serveAsset(assets, fingerprinted, nil)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "console.log(1)", recorder.Body.String())
+		assert.Equal(t, "max-age=31536000, immutable, public", recorder.Header().Get("Cache-Control"))
+	})
+
+	t.Run("original", func(t *testing.T) {
+		recorder := serveAsset(assets, "js/app.js", nil)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Cache-Control"))
+	})
+
+	t.Run("precompressed", func(t *testing.T) {
+		recorder := serveAsset(assets, fingerprinted, http.Header{"Accept-Encoding": []string{"gzip, deflate, br"}})
+		assert.Equal(t, "brotli", recorder.Body.String())
+		assert.Equal(t, "br", recorder.Header().Get("Content-Encoding"))
+		assert.Contains(t, recorder.Header().Get("Content-Type"), "javascript")
+		assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+
+		recorder = serveAsset(assets, fingerprinted, http.Header{"Accept-Encoding": []string{"gzip, br;q=0"}})
+		assert.Equal(t, "gzipped", recorder.Body.String())
+		assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serveAsset(assets, "js", nil).Code)
+		assert.Equal(t, http.StatusNotFound, serveAsset(assets, "../../etc/passwd", nil).Code)
+		assert.Equal(t, http.StatusNotFound, serveAsset(new(Assets), "js/app.js", nil).Code)
+	})
+}
+
+func TestAssetsManifest(t *testing.T) {
+	dir := assetDir(t, map[string]string{
+		"manifest.json":       `{"app.css": "/app.abc123.css"}`,
+		"app.abc123.css":      "body{}",
+		"app.abc123.css.gz":   "gzipped",
+		"other/unchanged.txt": "text",
+	})
+	defer os.RemoveAll(dir)
+
+	assets := &Assets{fileSystem: http.Dir(dir), manifestFile: "manifest.json"}
+	assert.Equal(t, "app.abc123.css", assets.Fingerprinted("app.css"))
+	assert.Equal(t, "other/unchanged.txt", assets.Fingerprinted("other/unchanged.txt"))
+
+	recorder := serveAsset(assets, "app.abc123.css", nil)
+	assert.Equal(t, "body{}", recorder.Body.String())
+	assert.Contains(t, recorder.Header().Get("Cache-Control"), "immutable")
+
+	recorder = serveAsset(assets, "app.abc123.css", http.Header{"Accept-Encoding": []string{"gzip"}})
+	assert.Equal(t, "gzipped", recorder.Body.String())
+}
+
+type testAssetRouter struct{}
+
+func (testAssetRouter) Relative(to string, params map[string]string) (*url.URL, error) {
+	return &url.URL{Path: "/assets/" + params["name"]}, nil
+}
+
+func (testAssetRouter) Absolute(r *web.Request, to string, params map[string]string) (*url.URL, error) {
+	return nil, nil
+}
+
+func TestAssetFunc(t *testing.T) {
+	dir := assetDir(t, map[string]string{"app.js": "1"})
+	defer os.RemoveAll(dir)
+
+	assetFunc := new(AssetFunc).Inject(&Assets{fileSystem: http.Dir(dir)}, testAssetRouter{}, flamingo.NullLogger{})
+	asset := assetFunc.Func(context.Background()).(func(string) string)
+	assert.Regexp(t, `^/assets/app\.[0-9a-f]{16}\.js$`, asset("app.js"))
+}
@@ -17,7 +17,7 @@ func (controller *Error) Inject(responder *web.Responder) {
 	controller.responder = responder
 }
 
-// Error responder
+// Error responder, the response is chosen via the error mappings of the responder
 func (controller *Error) Error(ctx context.Context, request *web.Request) web.Result {
 	var err error
 	if ctx.Value(web.RouterError) != nil {
@@ -25,7 +25,7 @@ func (controller *Error) Error(ctx context.Context, request *web.Request) web.Re
 	} else {
 		err = errors.New("no error found in provided context")
 	}
-	return controller.responder.ServerError(err)
+	return controller.responder.Error(err)
 }
 
 // NotFound responder
@@ -24,7 +24,15 @@ func (fr fileResponse) Apply(ctx context.Context, rw http.ResponseWriter) error
 }
 
 // Static is a controller to handle file requests
-type Static struct{}
+type Static struct {
+	assets *Assets
+}
+
+// Inject dependencies
+func (s *Static) Inject(assets *Assets) *Static {
+	s.assets = assets
+	return s
+}
 
 // File returns a fileResponse which uses http.ServeFile to respond to the request
 func (*Static) File(ctx context.Context, r *web.Request) web.Result {
@@ -90,9 +90,18 @@ func (l *StdLogger) Warn(args ...interface{}) {
 	l.Print(args...)
 }
 
-// WithContext currently does nothing
+// WithContext prefixes the log messages with the request ID of the context
 func (l *StdLogger) WithContext(ctx context.Context) Logger {
-	return l
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return l
+	}
+
+	logger := new(StdLogger)
+	logger.SetOutput(l.Writer())
+	logger.SetFlags(l.Flags())
+	logger.SetPrefix(l.Prefix() + LogKeyCorrelationID + "=" + id + " ")
+	return logger
 }
 
 // WithField currently logs the field
@@ -0,0 +1,23 @@
+package flamingo
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdLogger_WithContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := new(StdLogger)
+	logger.SetOutput(buf)
+	logger.SetFlags(0)
+	logger.SetPrefix("app ")
+
+	logger.WithContext(context.Background()).Info("without ID")
+	logger.WithContext(ContextWithRequestID(context.Background(), "4711")).Info("with ID")
+	logger.Info("unchanged")
+
+	assert.Equal(t, "app without ID\napp correlationId=4711 with ID\napp unchanged\n", buf.String())
+}
@@ -0,0 +1,18 @@
+package flamingo
+
+import "context"
+
+type requestIDKeyType struct{}
+
+var requestIDKey requestIDKeyType
+
+// ContextWithRequestID returns a context carrying the request ID, which loggers add to their messages via WithContext
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID of the context, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
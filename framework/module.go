@@ -40,6 +40,7 @@ type (
 func (*InitModule) Configure(injector *dingo.Injector) {
 	injector.BindMulti(new(cobra.Command)).ToProvider(web.RoutesCmd)
 	injector.BindMulti(new(cobra.Command)).ToProvider(web.HandlerCmd)
+	injector.BindMulti(new(cobra.Command)).ToProvider(web.OpenAPICmd)
 	injector.BindMulti(new(cobra.Command)).ToProvider(config.Cmd)
 
 	web.BindRoutes(injector, new(routes))
@@ -48,7 +49,9 @@ func (*InitModule) Configure(injector *dingo.Injector) {
 
 	injector.Bind(web.Router{}).In(dingo.ChildSingleton)
 	injector.Bind(new(web.ReverseRouter)).To(web.Router{})
+	injector.Bind(web.Server{}).In(dingo.ChildSingleton)
 	injector.Bind(web.RouterRegistry{}).In(dingo.Singleton).ToProvider(web.NewRegistry)
+	injector.Bind(controller.Assets{}).In(dingo.ChildSingleton)
 	injector.BindMulti(new(web.Filter)).To(new(filter.MetricsFilter))
 
 	flamingo.BindTemplateFunc(injector, "config", new(config.TemplateFunc))
@@ -56,6 +59,7 @@ func (*InitModule) Configure(injector *dingo.Injector) {
 	flamingo.BindTemplateFunc(injector, "getPartialData", new(web.GetPartialDataFunc))
 	flamingo.BindTemplateFunc(injector, "canonicalDomain", new(web.CanonicalDomainFunc))
 	flamingo.BindTemplateFunc(injector, "isExternalUrl", new(web.IsExternalURL))
+	flamingo.BindTemplateFunc(injector, "asset", new(controller.AssetFunc))
 }
 
 // Inject controller for flamingo default handler
@@ -83,6 +87,7 @@ func (r *routes) Routes(registry *web.RouterRegistry) {
 	registry.HandleAny("flamingo.redirectPermanent", r.redirect.RedirectPermanent)
 	registry.HandleAny("flamingo.redirectPermanentUrl", r.redirect.RedirectPermanentURL)
 	registry.HandleAny("flamingo.static.file", r.static.File)
+	registry.HandleGet(controller.AssetHandler, r.static.Asset)
 
 	registry.HandleAny(web.FlamingoError, r.errorController.Error)
 	registry.HandleAny(web.FlamingoNotfound, r.errorController.NotFound)
@@ -97,6 +102,10 @@ flamingo: {
 		notfound: string | *"flamingo.notfound"
 		error: string | *"flamingo.error"
 		timeout: int | *60000
+		methodNotAllowed: bool | *true
+		autoHead: bool | *true
+		autoOptions: bool | *true
+		websocket: allowedOrigins: [...string]
 		host?: string
 		path?: string
 	}
@@ -106,6 +115,48 @@ flamingo: {
 		errWithCode: string | *"error/withCode"
 		err503: string | *"error/503"
 	}
+	web: {
+		responder: {
+			defaultMediaType: string | *"application/json"
+			autoETag: bool | *false
+		}
+		upload: {
+			tempDir: string | *""
+			maxFileSize: int | *33554432
+			maxTotalSize: int | *67108864
+		}
+		binding: {
+			maxBodySize: int | *1048576
+		}
+		openapi: {
+			title: string | *"Flamingo"
+			description: string | *""
+			version: string | *"1.0.0"
+			servers: [...string]
+			securitySchemes: {...}
+			endpoint: string | *"/openapi.json"
+		}
+		server: {
+			readTimeout: int | *0
+			readHeaderTimeout: int | *10000
+			writeTimeout: int | *0
+			idleTimeout: int | *120000
+			maxHeaderBytes: int | *1048576
+			tls: {
+				certFile: string | *""
+				keyFile: string | *""
+			}
+			h2c: bool | *false
+			drain: int | *0
+			shutdownTimeout: int | *5000
+			socket: string | *""
+			fd: int | *0
+		}
+	}
+	static: assets: {
+		dir: string | *""
+		manifest: string | *""
+	}
 	session: {
 		name: string | *"flamingo"
 		saveMode: *"Always" | "OnRead" | "OnWrite" 
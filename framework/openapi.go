@@ -0,0 +1,34 @@
+package framework
+
+import (
+	"flamingo.me/dingo"
+	"flamingo.me/flamingo/v3/framework/systemendpoint"
+	"flamingo.me/flamingo/v3/framework/systemendpoint/domain"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// OpenAPIModule serves the generated OpenAPI document of all routes via the systemendpoint
+type OpenAPIModule struct {
+	endpoint string
+}
+
+// Inject dependencies
+func (m *OpenAPIModule) Inject(cfg *struct {
+	Endpoint string `inject:"config:flamingo.web.openapi.endpoint"`
+}) *OpenAPIModule {
+	m.endpoint = cfg.Endpoint
+	return m
+}
+
+// Configure DI
+func (m *OpenAPIModule) Configure(injector *dingo.Injector) {
+	injector.BindMap((*domain.Handler)(nil), m.endpoint).To(new(web.OpenAPIGenerator))
+}
+
+// Depends on the systemendpoint and the router configuration
+func (*OpenAPIModule) Depends() []dingo.Module {
+	return []dingo.Module{
+		new(InitModule),
+		new(systemendpoint.Module),
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"net/http"
 	"path"
-	"time"
 
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/framework/config"
@@ -19,21 +18,20 @@ import (
 
 // Module for core/prefix_router
 type Module struct {
-	server                    *http.Server
-	eventRouter               flamingo.EventRouter
+	server                    *web.Server
 	logger                    flamingo.Logger
 	enableRootRedirectHandler bool
 }
 
 // Inject dependencies
 func (m *Module) Inject(
-	eventRouter flamingo.EventRouter,
+	server *web.Server,
 	logger flamingo.Logger,
 	config *struct {
 		EnableRootRedirectHandler bool `inject:"config:flamingo.prefixrouter.rootRedirectHandler.enabled,optional"`
 	},
 ) {
-	m.eventRouter = eventRouter
+	m.server = server
 	m.logger = logger
 	m.enableRootRedirectHandler = config.EnableRootRedirectHandler
 }
@@ -156,46 +154,37 @@ func (m *Module) serve(
 			}
 		}
 
-		m.logger.WithField("category", "prefixrouter").Info("Starting HTTP Server (Prefixrouter) at ", *addr, ".....")
-		m.server = &http.Server{
-			Addr: *addr,
-			Handler: &ochttp.Handler{
-				IsPublicEndpoint: true,
-				Handler:          frontRouter,
-				GetStartOptions:  opencensus.URLPrefixSampler(whitelist, blacklist, configuredURLPrefixSampler.AllowParentTrace),
-			},
-		}
-
-		e := m.listenAndServe()
+		m.logger.WithField("category", "prefixrouter").Info("Starting HTTP Server (Prefixrouter) .....")
+		e := m.server.Serve(*addr, &ochttp.Handler{
+			IsPublicEndpoint: true,
+			Handler:          frontRouter,
+			GetStartOptions:  opencensus.URLPrefixSampler(whitelist, blacklist, configuredURLPrefixSampler.AllowParentTrace),
+		})
 		if e != nil && e != http.ErrServerClosed {
 			m.logger.WithField("category", "prefixrouter").Error("Unexpected Error ", e)
 		}
 	}
 }
 
-func (m *Module) listenAndServe() error {
-	m.eventRouter.Dispatch(context.Background(), &flamingo.ServerStartEvent{Port: m.server.Addr})
-	defer m.eventRouter.Dispatch(context.Background(), &flamingo.ServerShutdownEvent{})
-
-	err := m.server.ListenAndServe()
-
-	return err
+// Notify handles the app shutdown event, and the shutdown of another server of the application
+func (m *Module) Notify(ctx context.Context, event flamingo.Event) {
+	switch event.(type) {
+	case *flamingo.ServerShutdownEvent:
+		m.shutdown(ctx)
+	case *flamingo.ShutdownEvent:
+		m.shutdown(ctx)
+	}
 }
 
-// Notify handles the app shutdown event
-func (m *Module) Notify(ctx context.Context, event flamingo.Event) {
-	if _, ok := event.(*flamingo.ServerShutdownEvent); ok {
-		if m.server == nil {
-			m.logger.WithField("category", "prefixrouter").Info("Shutdown: server not started.. ")
-			return
-		}
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-		m.logger.WithField("category", "prefixrouter").Info(fmt.Sprintf("Shutdown server on: %v ", m.server.Addr))
+func (m *Module) shutdown(ctx context.Context) {
+	if m.server.Addr() == "" {
+		m.logger.WithField("category", "prefixrouter").Info("Shutdown: server not started.. ")
+		return
+	}
+	m.logger.WithField("category", "prefixrouter").Info(fmt.Sprintf("Shutdown server on: %v ", m.server.Addr()))
 
-		err := m.server.Shutdown(ctx)
-		if err != nil {
-			m.logger.WithField("category", "prefixrouter").Error("unexpected error on server shutdown: ", err)
-		}
+	err := m.server.Shutdown(ctx)
+	if err != nil {
+		m.logger.WithField("category", "prefixrouter").Error("unexpected error on server shutdown: ", err)
 	}
 }
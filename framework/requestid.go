@@ -0,0 +1,59 @@
+package framework
+
+import (
+	"net/http"
+	"sync"
+
+	"flamingo.me/dingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"flamingo.me/flamingo/v3/framework/web/filter"
+)
+
+// RequestIDModule accepts or generates a request ID for every request, which is logged and sent along with outbound
+// requests of clients using the filter.RequestIDTransport
+type RequestIDModule struct {
+	header               string
+	wrapDefaultTransport bool
+}
+
+var requestIDTransportOnce = new(sync.Once)
+
+// Inject dependencies
+func (m *RequestIDModule) Inject(cfg *struct {
+	Header               string `inject:"config:flamingo.web.requestID.header"`
+	WrapDefaultTransport bool   `inject:"config:flamingo.web.requestID.wrapDefaultTransport"`
+}) *RequestIDModule {
+	m.header = cfg.Header
+	m.wrapDefaultTransport = cfg.WrapDefaultTransport
+	return m
+}
+
+// Configure DI
+func (m *RequestIDModule) Configure(injector *dingo.Injector) {
+	injector.BindMulti(new(web.Filter)).To(new(filter.RequestIDFilter))
+
+	// http.DefaultTransport is shared by the whole process, so it is wrapped only once, with the header of the first application
+	if m.wrapDefaultTransport {
+		requestIDTransportOnce.Do(func() {
+			http.DefaultTransport = &filter.RequestIDTransport{Header: m.header, Next: http.DefaultTransport}
+		})
+	}
+}
+
+// CueConfig for the request ID header
+func (*RequestIDModule) CueConfig() string {
+	return `
+flamingo: web: requestID: {
+	header: string | *"X-Request-ID"
+	trustIncoming: bool | *true
+	wrapDefaultTransport: bool | *false
+}
+`
+}
+
+// Depends on the InitModule
+func (*RequestIDModule) Depends() []dingo.Module {
+	return []dingo.Module{
+		new(InitModule),
+	}
+}
@@ -0,0 +1,521 @@
+package web
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"flamingo.me/dingo"
+	"go.opencensus.io/trace"
+)
+
+type (
+	// ValidationError is returned by Request.Bind if the input violates the validation rules of the target
+	ValidationError struct {
+		Fields []FieldError
+	}
+
+	// FieldError describes a violated validation rule of a single field
+	FieldError struct {
+		// Field is the name of the field in the input, e.g. `address.street` or `items[0].sku`
+		Field string
+		// Rule is the violated rule, e.g. `required`, or `type` if the value could not be converted
+		Rule string
+		// Param is the parameter of the rule, e.g. `3` for `min=3`
+		Param string
+		// Message is the untranslated message
+		Message string
+	}
+
+	// ValidationTranslator translates the messages of field errors, core/locale provides an implementation
+	ValidationTranslator interface {
+		TranslateFieldError(fieldError FieldError) string
+	}
+
+	// ValidationRule checks non-zero values of fields declaring the rule in the `validate` struct tag
+	ValidationRule struct {
+		// Check the value against the param of the rule
+		Check func(value reflect.Value, param string) bool
+		// Message of a violation, it may contain the placeholders `{{.field}}` and `{{.param}}`
+		Message string
+	}
+
+	validationRuleProvider func() map[string]ValidationRule
+)
+
+var (
+	// ErrUnsupportedMediaType is returned by Request.Bind if the Content-Type can not be bound
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+	// ErrInvalidBody is returned by Request.Bind if the request body can not be decoded
+	ErrInvalidBody = errors.New("invalid request body")
+
+	// ErrBodyTooLarge is returned by Request.Bind if a JSON body exceeds the configured size
+	ErrBodyTooLarge = errors.New("request body too large")
+
+	// builtinValidationRules are available for all requests, bound validation rules take precedence
+	builtinValidationRules = map[string]ValidationRule{
+		"min":     {Check: ruleMin, Message: "must be at least {{.param}}"},
+		"max":     {Check: ruleMax, Message: "must be at most {{.param}}"},
+		"len":     {Check: ruleLen, Message: "must have a length of {{.param}}"},
+		"email":   {Check: ruleEmail, Message: "must be a valid email address"},
+		"url":     {Check: ruleURL, Message: "must be a valid URL"},
+		"oneof":   {Check: ruleOneOf, Message: "must be one of {{.param}}"},
+		"pattern": {Check: rulePattern, Message: "must match the pattern {{.param}}"},
+	}
+
+	emailRegex    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	patternsCache sync.Map
+
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	uploadType          = reflect.TypeOf((*Upload)(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindValidationRule binds a custom validation rule for the `validate` struct tag
+func BindValidationRule(injector *dingo.Injector, name string) *dingo.Binding {
+	return injector.BindMap(new(ValidationRule), name)
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func newFieldError(field, rule, param, message string) FieldError {
+	return FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: strings.NewReplacer("{{.field}}", field, "{{.param}}", param).Replace(message),
+	}
+}
+
+// Bind decodes the request input into the target, which must be a pointer to a struct, and validates it.
+//
+// The input is chosen by the Content-Type: JSON bodies are decoded with encoding/json up to the configured size, urlencoded and multipart
+// forms are bound via the `form` struct tag (falling back to the `json` tag), requests without a body bind the URL query.
+// Multipart bodies are read via Uploads, so the upload limits apply and the files are removed after the request.
+// The files are bound to fields of type *Upload or []*Upload.
+//
+// Validation rules are declared with the `validate` struct tag, e.g. `validate:"required,min=3"`.
+// Violated rules and values which can not be converted result in a *ValidationError.
+func (r *Request) Bind(ctx context.Context, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("bind target must be a pointer to a struct")
+	}
+
+	_, span := trace.StartSpan(ctx, "flamingo/web/request/bind")
+	defer span.End()
+
+	req := r.Request()
+	tag := "form"
+	var fieldErrors []FieldError
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		tag = "json"
+		if req.Body == nil {
+			break
+		}
+		body := req.Body
+		if r.maxBodySize > 0 {
+			body = http.MaxBytesReader(nil, req.Body, r.maxBodySize)
+		}
+		err := json.NewDecoder(body).Decode(target)
+		var typeErr *json.UnmarshalTypeError
+		if err != nil && r.maxBodySize > 0 && err.Error() == "http: request body too large" {
+			// http.MaxBytesReader does not return a typed error
+			return fmt.Errorf("%w: the request exceeds %d bytes", ErrBodyTooLarge, r.maxBodySize)
+		} else if errors.As(err, &typeErr) {
+			fieldErrors = append(fieldErrors, newFieldError(typeErr.Field, "type", typeErr.Type.String(), "must be a valid {{.param}}"))
+		} else if err != nil && err != io.EOF {
+			return fmt.Errorf("%w: %v", ErrInvalidBody, err)
+		}
+
+	case mediaType == "multipart/form-data":
+		uploads, err := r.Uploads(ctx)
+		if errors.Is(err, ErrUploadTooLarge) {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBody, err)
+		}
+		files := make(map[string][]*Upload)
+		for _, upload := range uploads {
+			files[upload.Field] = append(files[upload.Field], upload)
+		}
+		fieldErrors = bindValues(rv.Elem(), "", req.Form, files)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBody, err)
+		}
+		fieldErrors = bindValues(rv.Elem(), "", req.Form, nil)
+
+	case mediaType == "" || req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0:
+		fieldErrors = bindValues(rv.Elem(), "", r.QueryAll(), nil)
+
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, mediaType)
+	}
+
+	validationErrors, err := validateStruct(rv.Elem(), tag, "", r.validationRules)
+	if err != nil {
+		return err
+	}
+	fieldErrors = append(fieldErrors, validationErrors...)
+
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Fields: fieldErrors}
+	}
+
+	return nil
+}
+
+// fieldName returns the input name of a struct field, or "-" if it is ignored
+func fieldName(field reflect.StructField, tag string) string {
+	tags := []string{tag}
+	if tag == "form" {
+		tags = append(tags, "json")
+	}
+
+	for _, t := range tags {
+		if name := strings.Split(field.Tag.Get(t), ",")[0]; name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// isNestedStruct checks if a struct type is bound field by field, instead of being converted from a single value
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func bindValues(v reflect.Value, prefix string, values url.Values, files map[string][]*Upload) []FieldError {
+	var fieldErrors []FieldError
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := fieldName(field, "form")
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		if field.Anonymous && field.Tag.Get("form") == "" && isNestedStruct(field.Type) {
+			fieldErrors = append(fieldErrors, bindValues(fieldValue, prefix, values, files)...)
+			continue
+		}
+
+		name = prefix + name
+
+		switch {
+		case field.Type == uploadType:
+			if f := files[name]; len(f) > 0 {
+				fieldValue.Set(reflect.ValueOf(f[0]))
+			}
+		case field.Type == reflect.SliceOf(uploadType):
+			if f := files[name]; len(f) > 0 {
+				fieldValue.Set(reflect.ValueOf(f))
+			}
+		case isNestedStruct(field.Type):
+			fieldErrors = append(fieldErrors, bindValues(fieldValue, name+".", values, files)...)
+		default:
+			vals, ok := values[name]
+			if !ok || len(vals) == 0 {
+				continue
+			}
+			if err := setValues(fieldValue, vals); err != nil {
+				fieldErrors = append(fieldErrors, newFieldError(name, "type", field.Type.String(), "must be a valid {{.param}}"))
+			}
+		}
+	}
+
+	return fieldErrors
+}
+
+func setValues(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), val); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	return setValue(v, vals[0])
+}
+
+func setValue(v reflect.Value, val string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), val); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(val))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		if val == "on" {
+			val = "true"
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		i, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("can not bind to %s", v.Type())
+	}
+
+	return nil
+}
+
+func validateStruct(v reflect.Value, tag, prefix string, rules map[string]ValidationRule) ([]FieldError, error) {
+	var fieldErrors []FieldError
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		name := prefix + fieldName(field, tag)
+		if field.Anonymous && field.Tag.Get(tag) == "" {
+			name = strings.TrimSuffix(prefix, ".")
+		}
+
+		errs, err := validateField(fieldValue, name, field.Tag.Get("validate"), rules)
+		if err != nil {
+			return nil, err
+		}
+		fieldErrors = append(fieldErrors, errs...)
+
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+
+		switch {
+		case isNestedStruct(fieldValue.Type()):
+			nestedPrefix := name + "."
+			if field.Anonymous && field.Tag.Get(tag) == "" {
+				nestedPrefix = prefix
+			}
+			errs, err := validateStruct(fieldValue, tag, nestedPrefix, rules)
+			if err != nil {
+				return nil, err
+			}
+			fieldErrors = append(fieldErrors, errs...)
+
+		case fieldValue.Kind() == reflect.Slice || fieldValue.Kind() == reflect.Array:
+			for j := 0; j < fieldValue.Len(); j++ {
+				elem := fieldValue.Index(j)
+				if elem.Kind() == reflect.Ptr && !elem.IsNil() {
+					elem = elem.Elem()
+				}
+				if !isNestedStruct(elem.Type()) {
+					break
+				}
+				errs, err := validateStruct(elem, tag, fmt.Sprintf("%s[%d].", name, j), rules)
+				if err != nil {
+					return nil, err
+				}
+				fieldErrors = append(fieldErrors, errs...)
+			}
+		}
+	}
+
+	return fieldErrors, nil
+}
+
+// validateField checks the rules of a field, the rules besides `required` are only checked for non-zero values
+func validateField(v reflect.Value, name, tag string, rules map[string]ValidationRule) ([]FieldError, error) {
+	if tag == "" || tag == "-" {
+		return nil, nil
+	}
+
+	var fieldErrors []FieldError
+
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			ruleName, param = rule[:i], rule[i+1:]
+		}
+
+		if ruleName == "required" {
+			if v.IsZero() {
+				return []FieldError{newFieldError(name, ruleName, param, "is required")}, nil
+			}
+			continue
+		}
+
+		r, ok := rules[ruleName]
+		if !ok {
+			r, ok = builtinValidationRules[ruleName]
+		}
+		if !ok {
+			return nil, fmt.Errorf("validation rule %q of field %q is not registered", ruleName, name)
+		}
+
+		if v.IsZero() {
+			continue
+		}
+
+		value := v
+		for value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+
+		if !r.Check(value, param) {
+			fieldErrors = append(fieldErrors, newFieldError(name, ruleName, param, r.Message))
+		}
+	}
+
+	return fieldErrors, nil
+}
+
+// size returns the number for numeric values and the length for strings, slices and maps
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+func compareSize(v reflect.Value, param string, cmp func(size, param float64) bool) bool {
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	s, ok := size(v)
+	return ok && cmp(s, p)
+}
+
+func ruleMin(v reflect.Value, param string) bool {
+	return compareSize(v, param, func(size, param float64) bool { return size >= param })
+}
+
+func ruleMax(v reflect.Value, param string) bool {
+	return compareSize(v, param, func(size, param float64) bool { return size <= param })
+}
+
+func ruleLen(v reflect.Value, param string) bool {
+	if v.Kind() != reflect.String && v.Kind() != reflect.Slice && v.Kind() != reflect.Array && v.Kind() != reflect.Map {
+		return false
+	}
+	return compareSize(v, param, func(size, param float64) bool { return size == param })
+}
+
+func ruleEmail(v reflect.Value, _ string) bool {
+	return v.Kind() == reflect.String && emailRegex.MatchString(v.String())
+}
+
+func ruleURL(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(v.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func ruleOneOf(v reflect.Value, param string) bool {
+	value := fmt.Sprint(v.Interface())
+	for _, option := range strings.Fields(param) {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func rulePattern(v reflect.Value, param string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+
+	pattern, ok := patternsCache.Load(param)
+	if !ok {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			return false
+		}
+		pattern, _ = patternsCache.LoadOrStore(param, compiled)
+	}
+
+	return pattern.(*regexp.Regexp).MatchString(v.String())
+}
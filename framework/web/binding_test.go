@@ -0,0 +1,236 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	bindAddress struct {
+		Street string `json:"street" validate:"required"`
+		Zip    string `json:"zip" validate:"pattern=^[0-9]{5}$"`
+	}
+
+	bindItem struct {
+		SKU string `json:"sku" validate:"required"`
+		Qty int    `json:"qty" validate:"min=1,max=10"`
+	}
+
+	bindCustomer struct {
+		Name       string        `json:"name" validate:"required,min=2"`
+		Email      string        `json:"email" validate:"email"`
+		Age        int           `json:"age" form:"customer_age" validate:"min=18"`
+		Country    string        `json:"country" validate:"oneof=DE FR"`
+		Tags       []string      `json:"tags" validate:"max=2"`
+		Newsletter bool          `json:"newsletter"`
+		Timeout    time.Duration `json:"timeout"`
+		Birthday   *time.Time    `json:"birthday"`
+		Address    bindAddress   `json:"address"`
+		Items      []bindItem    `json:"items"`
+		Ignored    string        `json:"-" form:"-"`
+	}
+
+	bindUpload struct {
+		Title string  `form:"title" validate:"required"`
+		File  *Upload `form:"file" validate:"required"`
+	}
+
+	testValidationTranslator struct{}
+)
+
+func (testValidationTranslator) TranslateFieldError(fieldError FieldError) string {
+	return "translated " + fieldError.Rule
+}
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a validation error, got %v", err)
+
+	fields := make(map[string]string)
+	for _, field := range validationErr.Fields {
+		fields[field.Field] = field.Rule
+	}
+	return fields
+}
+
+func TestRequestBind(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{
+			"name": "Jane", "email": "jane@example.com", "age": 30, "country": "DE", "tags": ["a"],
+			"address": {"street": "Main", "zip": "12345"}, "items": [{"sku": "1", "qty": 2}]
+		}`))
+		request.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		var customer bindCustomer
+		require.NoError(t, CreateRequest(request, nil).Bind(context.Background(), &customer))
+		assert.Equal(t, "Jane", customer.Name)
+		assert.Equal(t, "12345", customer.Address.Zip)
+		assert.Equal(t, 2, customer.Items[0].Qty)
+	})
+
+	t.Run("JSON validation", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{
+			"name": "J", "email": "jane", "age": 17, "country": "US", "tags": ["a", "b", "c"],
+			"address": {"zip": "1"}, "items": [{"sku": "1", "qty": 2}, {"qty": 11}]
+		}`))
+		request.Header.Set("Content-Type", "application/json")
+
+		err := CreateRequest(request, nil).Bind(context.Background(), new(bindCustomer))
+		assert.Equal(t, map[string]string{
+			"name":           "min",
+			"email":          "email",
+			"age":            "min",
+			"country":        "oneof",
+			"tags":           "max",
+			"address.street": "required",
+			"address.zip":    "pattern",
+			"items[1].sku":   "required",
+			"items[1].qty":   "max",
+		}, fieldErrors(t, err))
+	})
+
+	t.Run("JSON type errors and invalid bodies", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "Jane", "age": "old"}`))
+		request.Header.Set("Content-Type", "application/json")
+		err := CreateRequest(request, nil).Bind(context.Background(), new(bindCustomer))
+		assert.Equal(t, "type", fieldErrors(t, err)["age"])
+
+		request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": `))
+		request.Header.Set("Content-Type", "application/json")
+		err = CreateRequest(request, nil).Bind(context.Background(), new(bindCustomer))
+		assert.True(t, errors.Is(err, ErrInvalidBody))
+	})
+
+	t.Run("Form", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/?country=FR", strings.NewReader(
+			"name=Jane&customer_age=30&tags=a&tags=b&newsletter=on&timeout=1m&birthday=2000-01-02T00:00:00Z&address.street=Main&Ignored=x"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var customer bindCustomer
+		require.NoError(t, CreateRequest(request, nil).Bind(context.Background(), &customer))
+		assert.Equal(t, "Jane", customer.Name)
+		assert.Equal(t, 30, customer.Age)
+		assert.Equal(t, "FR", customer.Country)
+		assert.Equal(t, []string{"a", "b"}, customer.Tags)
+		assert.True(t, customer.Newsletter)
+		assert.Equal(t, time.Minute, customer.Timeout)
+		assert.Equal(t, 2000, customer.Birthday.Year())
+		assert.Equal(t, "Main", customer.Address.Street)
+		assert.Empty(t, customer.Ignored)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/?name=Jane&customer_age=young&address.street=Main", nil)
+
+		err := CreateRequest(request, nil).Bind(context.Background(), new(bindCustomer))
+		assert.Equal(t, map[string]string{"customer_age": "type"}, fieldErrors(t, err))
+	})
+
+	t.Run("Multipart", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("title", "report"))
+		part, err := writer.CreateFormFile("file", "report.txt")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("content"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body.Bytes()))
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+
+		req := CreateRequest(request, nil)
+		var upload bindUpload
+		require.NoError(t, req.Bind(context.Background(), &upload))
+		assert.Equal(t, "report", upload.Title)
+		assert.Equal(t, "report.txt", upload.File.Filename)
+		assert.FileExists(t, upload.File.Path)
+
+		req.removeUploads()
+		_, err = os.Stat(upload.File.Path)
+		assert.True(t, os.IsNotExist(err))
+
+		request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body.Bytes()))
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		req = CreateRequest(request, nil)
+		req.uploadConfig = &uploadConfig{maxFileSize: 3}
+		err = req.Bind(context.Background(), new(bindUpload))
+		assert.True(t, errors.Is(err, ErrUploadTooLarge))
+	})
+
+	t.Run("Body too large", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "Jane Doe", "email": "jane@example.com"}`))
+		request.Header.Set("Content-Type", "application/json")
+		req := CreateRequest(request, nil)
+		req.maxBodySize = 16
+
+		err := req.Bind(context.Background(), new(bindCustomer))
+		assert.True(t, errors.Is(err, ErrBodyTooLarge))
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("<xml/>"))
+		request.Header.Set("Content-Type", "application/xml")
+
+		err := CreateRequest(request, nil).Bind(context.Background(), new(bindCustomer))
+		assert.True(t, errors.Is(err, ErrUnsupportedMediaType))
+	})
+
+	t.Run("Custom rules", func(t *testing.T) {
+		rules := map[string]ValidationRule{"even": {
+			Check: func(value reflect.Value, _ string) bool {
+				return value.Int()%2 == 0
+			},
+			Message: "{{.field}} must be even",
+		}}
+
+		type target struct {
+			Number int    `form:"number" validate:"even"`
+			Other  string `form:"other" validate:"unknown"`
+		}
+
+		request := httptest.NewRequest(http.MethodGet, "/?number=3", nil)
+		err := CreateRequest(request, nil).Bind(context.Background(), &struct {
+			Number int `form:"number" validate:"even"`
+		}{})
+		assert.EqualError(t, err, `validation rule "even" of field "number" is not registered`)
+
+		req := CreateRequest(request, nil)
+		req.validationRules = rules
+		err = req.Bind(context.Background(), &struct {
+			Number int `form:"number" validate:"even"`
+		}{})
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "number must be even", validationErr.Fields[0].Message)
+
+		err = req.Bind(context.Background(), new(target))
+		assert.EqualError(t, err, `validation rule "unknown" of field "other" is not registered`)
+	})
+}
+
+func TestResponderUnprocessableEntity(t *testing.T) {
+	err := &ValidationError{Fields: []FieldError{newFieldError("name", "min", "2", "must be at least {{.param}}")}}
+
+	response := new(Responder).UnprocessableEntity(err)
+	assert.Equal(t, uint(http.StatusUnprocessableEntity), response.Response.Status)
+	assert.Equal(t, []map[string]string{{"field": "name", "rule": "min", "message": "must be at least 2"}}, response.Data.(map[string]interface{})["fields"])
+
+	responder := &Responder{validationTranslator: testValidationTranslator{}}
+	response = responder.UnprocessableEntity(err)
+	assert.Equal(t, "translated min", response.Data.(map[string]interface{})["fields"].([]map[string]string)[0]["message"])
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed is returned by Request.CheckPreconditions if the If-Match or If-Unmodified-Since precondition fails
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// CheckPreconditions evaluates the If-Match and If-Unmodified-Since headers against the current state of the resource,
+// before an unsafe method like PUT or DELETE changes it. An empty etag or a zero lastModified skip the respective check.
+func (r *Request) CheckPreconditions(etag string, lastModified time.Time) error {
+	header := r.Request().Header
+
+	if ifMatch := header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return ErrPreconditionFailed
+		}
+		return nil
+	}
+
+	if ifUnmodifiedSince := header.Get("If-Unmodified-Since"); ifUnmodifiedSince != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ifUnmodifiedSince); err == nil && lastModified.Truncate(time.Second).After(t) {
+			return ErrPreconditionFailed
+		}
+	}
+
+	return nil
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since headers of GET and HEAD requests against the response headers
+func notModified(req *http.Request, header http.Header) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return matchETag(ifNoneMatch, header.Get("ETag"), true)
+	}
+
+	ifModifiedSince, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
+
+// matchETag checks if the etag is part of the list of an If-Match or If-None-Match header.
+// The weak comparison ignores the W/ prefix, the strong comparison never matches weak etags.
+func matchETag(list string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeNotModified answers with 304, the headers describing the body are removed
+func writeNotModified(w http.ResponseWriter) {
+	header := w.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// weakETag generates a weak etag from the body
+func weakETag(body []byte) string {
+	hash := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(hash[:8]) + `"`
+}
+
+// setAutoETag adds a weak etag of the body if the response is successful and has no etag yet
+func (r *Response) setAutoETag(body []byte) {
+	if r.Status != 0 && r.Status != http.StatusOK {
+		return
+	}
+	if r.CacheDirective != nil && r.CacheDirective.ETag != "" {
+		return
+	}
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	if r.Header.Get("ETag") == "" {
+		r.Header.Set("ETag", weakETag(body))
+	}
+}
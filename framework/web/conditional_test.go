@@ -0,0 +1,123 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchETag(t *testing.T) {
+	assert.True(t, matchETag(`"a", "b"`, `"b"`, false))
+	assert.True(t, matchETag(`*`, `"b"`, false))
+	assert.False(t, matchETag(`*`, ``, true))
+	assert.False(t, matchETag(`W/"a"`, `"a"`, false))
+	assert.False(t, matchETag(`"a"`, `W/"a"`, false))
+	assert.True(t, matchETag(`W/"a"`, `"a"`, true))
+	assert.True(t, matchETag(`"x", "a"`, `W/"a"`, true))
+}
+
+func TestConditionalResponse(t *testing.T) {
+	lastModified := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	apply := func(t *testing.T, result Result, method string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		request := httptest.NewRequest(method, "/", nil)
+		request.Header = header
+		recorder := httptest.NewRecorder()
+		require.NoError(t, result.Apply(ContextWithRequest(context.Background(), CreateRequest(request, nil)), recorder))
+		return recorder
+	}
+
+	response := func() *Response {
+		return &Response{
+			Body:           strings.NewReader("body"),
+			Header:         http.Header{"Content-Type": []string{"text/plain"}},
+			CacheDirective: &CacheDirective{ETag: `"v1"`, LastModifiedSince: &lastModified},
+		}
+	}
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		recorder := apply(t, response(), http.MethodGet, http.Header{"If-None-Match": []string{`W/"v0", W/"v1"`}})
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Empty(t, recorder.Body.String())
+		assert.Empty(t, recorder.Header().Get("Content-Type"))
+		assert.Equal(t, `"v1"`, recorder.Header().Get("ETag"))
+
+		// If-None-Match takes precedence over If-Modified-Since
+		recorder = apply(t, response(), http.MethodGet, http.Header{
+			"If-None-Match":     []string{`"v0"`},
+			"If-Modified-Since": []string{lastModified.Format(http.TimeFormat)},
+		})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "body", recorder.Body.String())
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		recorder := apply(t, response(), http.MethodHead, http.Header{"If-Modified-Since": []string{lastModified.Add(time.Hour).Format(http.TimeFormat)}})
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+
+		recorder = apply(t, response(), http.MethodGet, http.Header{"If-Modified-Since": []string{lastModified.Add(-time.Hour).Format(http.TimeFormat)}})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("unsafe methods and errors are not affected", func(t *testing.T) {
+		recorder := apply(t, response(), http.MethodPost, http.Header{"If-None-Match": []string{`"v1"`}})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		errorResponse := response()
+		errorResponse.Status = http.StatusNotFound
+		recorder = apply(t, errorResponse, http.MethodGet, http.Header{"If-None-Match": []string{`"v1"`}})
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("automatic etag", func(t *testing.T) {
+		responder := &Responder{autoETag: true}
+
+		recorder := apply(t, responder.Data(map[string]int{"a": 1}), http.MethodGet, http.Header{})
+		etag := recorder.Header().Get("ETag")
+		assert.Regexp(t, `^W/"[0-9a-f]{16}"$`, etag)
+
+		recorder = apply(t, responder.Data(map[string]int{"a": 1}), http.MethodGet, http.Header{"If-None-Match": []string{etag}})
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+
+		recorder = apply(t, responder.Data(map[string]int{"a": 2}), http.MethodGet, http.Header{"If-None-Match": []string{etag}})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.NotEqual(t, etag, recorder.Header().Get("ETag"))
+
+		data := responder.Data("explicit")
+		data.CacheDirective = &CacheDirective{ETag: `"explicit"`}
+		recorder = apply(t, data, http.MethodGet, http.Header{})
+		assert.Equal(t, `"explicit"`, recorder.Header().Get("ETag"))
+
+		recorder = apply(t, new(Responder).Data("no auto etag"), http.MethodGet, http.Header{})
+		assert.Empty(t, recorder.Header().Get("ETag"))
+	})
+}
+
+func TestRequestCheckPreconditions(t *testing.T) {
+	lastModified := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	check := func(header http.Header, etag string, lastModified time.Time) error {
+		request := httptest.NewRequest(http.MethodPut, "/", nil)
+		request.Header = header
+		return CreateRequest(request, nil).CheckPreconditions(etag, lastModified)
+	}
+
+	assert.NoError(t, check(http.Header{}, `"v1"`, lastModified))
+	assert.NoError(t, check(http.Header{"If-Match": []string{`"v0", "v1"`}}, `"v1"`, lastModified))
+	assert.NoError(t, check(http.Header{"If-Match": []string{`*`}}, `"v1"`, lastModified))
+	assert.True(t, errors.Is(check(http.Header{"If-Match": []string{`"v0"`}}, `"v1"`, lastModified), ErrPreconditionFailed))
+	assert.True(t, errors.Is(check(http.Header{"If-Match": []string{`*`}}, ``, lastModified), ErrPreconditionFailed))
+
+	assert.NoError(t, check(http.Header{"If-Unmodified-Since": []string{lastModified.Format(http.TimeFormat)}}, "", lastModified))
+	err := check(http.Header{"If-Unmodified-Since": []string{lastModified.Add(-time.Hour).Format(http.TimeFormat)}}, "", lastModified)
+	assert.True(t, errors.Is(err, ErrPreconditionFailed))
+	assert.Equal(t, uint(http.StatusPreconditionFailed), new(Responder).Error(err).Response.Status)
+}
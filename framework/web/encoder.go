@@ -0,0 +1,484 @@
+package web
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"flamingo.me/dingo"
+)
+
+type (
+	// Encoder serializes the data of a DataResponse for a media type
+	Encoder interface {
+		Encode(w io.Writer, data interface{}) error
+	}
+
+	// EncoderFunc is a function which can be used as an Encoder
+	EncoderFunc func(w io.Writer, data interface{}) error
+
+	encoderProvider func() map[string]Encoder
+
+	// encoders holds the available encoders by media type and negotiates the encoder for a request
+	encoders struct {
+		encoders         map[string]Encoder
+		mediaTypes       []string
+		defaultMediaType string
+	}
+
+	acceptedMediaType struct {
+		mediaType   string
+		q           float64
+		specificity int
+	}
+)
+
+const (
+	// MediaTypeJSON is the default media type for data responses
+	MediaTypeJSON = "application/json"
+	// MediaTypeXML is used for XML encoded data responses
+	MediaTypeXML = "application/xml"
+	// MediaTypeCBOR is used for CBOR (RFC 8949) encoded data responses
+	MediaTypeCBOR = "application/cbor"
+	// MediaTypeCSV is used for CSV encoded data responses
+	MediaTypeCSV = "text/csv"
+)
+
+var defaultEncoders = newEncoders(MediaTypeJSON, nil)
+
+// BindEncoder registers an Encoder for a media type, which is used by the content negotiation of data responses
+func BindEncoder(injector *dingo.Injector, mediaType string) *dingo.Binding {
+	return injector.BindMap(new(Encoder), mediaType)
+}
+
+// Encode calls the EncoderFunc
+func (f EncoderFunc) Encode(w io.Writer, data interface{}) error {
+	return f(w, data)
+}
+
+// newEncoders creates the encoders with the built-in media types, additional encoders take precedence
+func newEncoders(defaultMediaType string, additional map[string]Encoder) *encoders {
+	e := &encoders{
+		encoders: map[string]Encoder{
+			MediaTypeJSON: EncoderFunc(encodeJSON),
+			MediaTypeXML:  EncoderFunc(encodeXML),
+			"text/xml":    EncoderFunc(encodeXML),
+			MediaTypeCBOR: EncoderFunc(encodeCBOR),
+			MediaTypeCSV:  EncoderFunc(encodeCSV),
+		},
+		defaultMediaType: MediaTypeJSON,
+	}
+
+	for mediaType, encoder := range additional {
+		e.encoders[strings.ToLower(mediaType)] = encoder
+	}
+
+	for mediaType := range e.encoders {
+		e.mediaTypes = append(e.mediaTypes, mediaType)
+	}
+	sort.Strings(e.mediaTypes)
+
+	if _, ok := e.encoders[strings.ToLower(defaultMediaType)]; ok {
+		e.defaultMediaType = strings.ToLower(defaultMediaType)
+	}
+
+	return e
+}
+
+// negotiate picks the encoder for the Accept header, ok is false if no registered media type is acceptable
+func (e *encoders) negotiate(accept string) (mediaType string, encoder Encoder, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return e.defaultMediaType, e.encoders[e.defaultMediaType], true
+	}
+
+	accepted, excluded := parseAccept(accept)
+
+	for _, a := range accepted {
+		switch {
+		case a.mediaType == "*/*":
+			if mediaType, ok := e.find("", excluded); ok {
+				return mediaType, e.encoders[mediaType], true
+			}
+		case strings.HasSuffix(a.mediaType, "/*"):
+			if mediaType, ok := e.find(strings.TrimSuffix(a.mediaType, "*"), excluded); ok {
+				return mediaType, e.encoders[mediaType], true
+			}
+		default:
+			encoder, ok := e.encoders[a.mediaType]
+			if !ok {
+				continue
+			}
+			// a media type accepted with less than the highest quality, e.g. application/xml in the Accept header of
+			// browsers, does not take precedence over the default media type if a wildcard accepts it as well
+			if a.q < accepted[0].q && e.acceptsDefault(accepted, excluded) {
+				return e.defaultMediaType, e.encoders[e.defaultMediaType], true
+			}
+			return a.mediaType, encoder, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// acceptsDefault checks if a wildcard of the Accept header matches the default media type
+func (e *encoders) acceptsDefault(accepted []acceptedMediaType, excluded map[string]bool) bool {
+	if excluded[e.defaultMediaType] {
+		return false
+	}
+
+	for _, a := range accepted {
+		if a.mediaType == "*/*" || (strings.HasSuffix(a.mediaType, "/*") && strings.HasPrefix(e.defaultMediaType, strings.TrimSuffix(a.mediaType, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find returns the default media type or the first registered media type with the prefix which is not excluded
+func (e *encoders) find(prefix string, excluded map[string]bool) (string, bool) {
+	if strings.HasPrefix(e.defaultMediaType, prefix) && !excluded[e.defaultMediaType] {
+		return e.defaultMediaType, true
+	}
+
+	for _, mediaType := range e.mediaTypes {
+		if strings.HasPrefix(mediaType, prefix) && !excluded[mediaType] {
+			return mediaType, true
+		}
+	}
+
+	return "", false
+}
+
+// parseAccept returns the accepted media types ordered by quality and specificity, and the explicitly excluded (q=0) ones
+func parseAccept(accept string) ([]acceptedMediaType, map[string]bool) {
+	var accepted []acceptedMediaType
+	excluded := make(map[string]bool)
+
+	for _, entry := range strings.Split(accept, ",") {
+		parts := strings.Split(entry, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+		if mediaType == "" {
+			continue
+		}
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+
+		q := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q <= 0 {
+			excluded[mediaType] = true
+			continue
+		}
+
+		specificity := 2
+		if mediaType == "*/*" {
+			specificity = 0
+		} else if strings.HasSuffix(mediaType, "/*") {
+			specificity = 1
+		}
+
+		accepted = append(accepted, acceptedMediaType{mediaType: mediaType, q: q, specificity: specificity})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		if accepted[i].q != accepted[j].q {
+			return accepted[i].q > accepted[j].q
+		}
+		return accepted[i].specificity > accepted[j].specificity
+	})
+
+	return accepted, excluded
+}
+
+// contentType adds the charset to textual media types
+func contentType(mediaType string) string {
+	if strings.HasPrefix(mediaType, "text/") || mediaType == MediaTypeJSON || mediaType == MediaTypeXML ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
+
+func encodeJSON(w io.Writer, data interface{}) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// genericData converts the data into its JSON representation of maps, slices and scalars,
+// so all encoders use the same field names and respect json.Marshaler implementations
+func genericData(data interface{}) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	return generic, err
+}
+
+// encodeXML uses encoding/xml, data which is not supported by encoding/xml (e.g. maps) is encoded in its generic form
+func encodeXML(w io.Writer, data interface{}) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+
+	err := xml.NewEncoder(buf).Encode(data)
+	var unsupportedTypeError *xml.UnsupportedTypeError
+	if errors.As(err, &unsupportedTypeError) {
+		generic, err := genericData(data)
+		if err != nil {
+			return err
+		}
+
+		buf.Reset()
+		buf.WriteString(xml.Header)
+		encoder := xml.NewEncoder(buf)
+		if err := encodeGenericXML(encoder, "data", generic); err != nil {
+			return err
+		}
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func encodeGenericXML(encoder *xml.Encoder, name string, data interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}}
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch data := data.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeGenericXML(encoder, k, data[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range data {
+			if err := encodeGenericXML(encoder, "item", v); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(data))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || !(r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeCSV writes a list of records, either as rows of values (e.g. [][]string) or as objects (e.g. structs or maps),
+// where the header is taken from the sorted field names
+func encodeCSV(w io.Writer, data interface{}) error {
+	generic, err := genericData(data)
+	if err != nil {
+		return err
+	}
+
+	rows, ok := generic.([]interface{})
+	if !ok {
+		rows = []interface{}{generic}
+	}
+
+	writer := csv.NewWriter(w)
+
+	var header []string
+	for _, row := range rows {
+		object, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range object {
+			if !contains(header, k) {
+				header = append(header, k)
+			}
+		}
+	}
+	sort.Strings(header)
+	if len(header) > 0 {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		var record []string
+		switch row := row.(type) {
+		case map[string]interface{}:
+			record = make([]string, len(header))
+			for i, k := range header {
+				record[i] = csvValue(row[k])
+			}
+		case []interface{}:
+			record = make([]string, len(row))
+			for i, v := range row {
+				record[i] = csvValue(v)
+			}
+		default:
+			record = []string{csvValue(row)}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// encodeCBOR writes the generic representation of the data as CBOR (RFC 8949)
+func encodeCBOR(w io.Writer, data interface{}) error {
+	generic, err := genericData(data)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writeCBOR(buf, generic); err != nil {
+		return err
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major | 27)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func writeCBOR(buf *bytes.Buffer, data interface{}) error {
+	switch data := data.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if data {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		if i, err := data.Int64(); err == nil {
+			if i >= 0 {
+				writeCBORHead(buf, 0, uint64(i))
+			} else {
+				writeCBORHead(buf, 1, uint64(-(i + 1)))
+			}
+			return nil
+		}
+		f, err := data.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xfb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		writeCBORHead(buf, 3, uint64(len(data)))
+		buf.WriteString(data)
+	case []interface{}:
+		writeCBORHead(buf, 4, uint64(len(data)))
+		for _, v := range data {
+			if err := writeCBOR(buf, v); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeCBORHead(buf, 5, uint64(len(data)))
+		for _, k := range keys {
+			writeCBORHead(buf, 3, uint64(len(k)))
+			buf.WriteString(k)
+			if err := writeCBOR(buf, data[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", data)
+	}
+
+	return nil
+}
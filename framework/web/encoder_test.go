@@ -0,0 +1,129 @@
+package web
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoderNegotiation(t *testing.T) {
+	encoders := newEncoders(MediaTypeJSON, map[string]Encoder{"application/vnd.test": EncoderFunc(encodeJSON)})
+
+	for _, tt := range []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: MediaTypeJSON},
+		{accept: "*/*", expected: MediaTypeJSON},
+		{accept: "application/xml", expected: MediaTypeXML},
+		{accept: "text/html, application/xml;q=0.9, */*;q=0.8", expected: MediaTypeJSON},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*", expected: MediaTypeJSON},
+		{accept: "text/html, application/xml;q=0.9", expected: MediaTypeXML},
+		{accept: "application/xml, */*;q=0.1", expected: MediaTypeXML},
+		{accept: "text/html, text/csv;q=0.9, text/*;q=0.8", expected: MediaTypeCSV},
+		{accept: "application/json;q=0.5, application/cbor", expected: MediaTypeCBOR},
+		{accept: "text/*", expected: MediaTypeCSV},
+		{accept: "application/json;q=0, */*", expected: MediaTypeCBOR},
+		{accept: "application/VND.test", expected: "application/vnd.test"},
+		{accept: "image/png", expected: ""},
+		{accept: "application/json;q=0", expected: ""},
+	} {
+		t.Run(tt.accept, func(t *testing.T) {
+			mediaType, encoder, ok := encoders.negotiate(tt.accept)
+			if tt.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.NotNil(t, encoder)
+			assert.Equal(t, tt.expected, mediaType)
+		})
+	}
+
+	t.Run("configured default", func(t *testing.T) {
+		mediaType, _, _ := newEncoders(MediaTypeXML, nil).negotiate("*/*")
+		assert.Equal(t, MediaTypeXML, mediaType)
+
+		mediaType, _, _ = newEncoders("application/unknown", nil).negotiate("")
+		assert.Equal(t, MediaTypeJSON, mediaType)
+	})
+}
+
+func TestEncoders(t *testing.T) {
+	type product struct {
+		ID    int     `json:"id"`
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+
+	encode := func(t *testing.T, mediaType string, data interface{}) string {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		_, encoder, ok := defaultEncoders.negotiate(mediaType)
+		require.True(t, ok)
+		require.NoError(t, encoder.Encode(recorder, data))
+		return recorder.Body.String()
+	}
+
+	t.Run("xml", func(t *testing.T) {
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<product><ID>1</ID><Name>a</Name><Price>1.5</Price></product>`,
+			encode(t, MediaTypeXML, product{ID: 1, Name: "a", Price: 1.5}))
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<data><entry key="1st">x</entry><code>404</code><list><item>a</item><item>b</item></list></data>`,
+			encode(t, MediaTypeXML, map[string]interface{}{"code": 404, "list": []string{"a", "b"}, "1st": "x"}))
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		assert.Equal(t, "id,name,price\n1,a,1.5\n2,\"b,c\",2\n",
+			encode(t, MediaTypeCSV, []product{{ID: 1, Name: "a", Price: 1.5}, {ID: 2, Name: "b,c", Price: 2}}))
+		assert.Equal(t, "a,b\nc,d\n", encode(t, MediaTypeCSV, [][]string{{"a", "b"}, {"c", "d"}}))
+	})
+
+	t.Run("cbor", func(t *testing.T) {
+		// test vectors from RFC 8949 appendix A
+		assert.Equal(t, "\x00", encode(t, MediaTypeCBOR, 0))
+		assert.Equal(t, "\x18\x64", encode(t, MediaTypeCBOR, 100))
+		assert.Equal(t, "\x39\x03\xe7", encode(t, MediaTypeCBOR, -1000))
+		assert.Equal(t, "\x1b\x00\x00\x00\xe8\xd4\xa5\x10\x00", encode(t, MediaTypeCBOR, 1000000000000))
+		assert.Equal(t, "\xfb\x3f\xf1\x99\x99\x99\x99\x99\x9a", encode(t, MediaTypeCBOR, 1.1))
+		assert.Equal(t, "\xf5\xf6", encode(t, MediaTypeCBOR, true)+encode(t, MediaTypeCBOR, nil))
+		assert.Equal(t, "\x64IETF", encode(t, MediaTypeCBOR, "IETF"))
+		assert.Equal(t, "\x83\x01\x02\x03", encode(t, MediaTypeCBOR, []int{1, 2, 3}))
+		assert.Equal(t, "\xa2\x61\x61\x01\x61\x62\x82\x02\x03", encode(t, MediaTypeCBOR, map[string]interface{}{"b": []int{2, 3}, "a": 1}))
+	})
+}
+
+func TestDataResponseNegotiation(t *testing.T) {
+	responder := &Responder{encoders: newEncoders(MediaTypeJSON, nil)}
+
+	apply := func(result Result, accept string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		recorder := httptest.NewRecorder()
+		assert.NoError(t, result.Apply(ContextWithRequest(context.Background(), CreateRequest(request, nil)), recorder))
+		return recorder
+	}
+
+	recorder := apply(responder.Data(map[string]int{"a": 1}), "")
+	assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+	assert.Equal(t, `{"a":1}`+"\n", recorder.Body.String())
+
+	recorder = apply(responder.Data(map[string]int{"a": 1}), "application/cbor")
+	assert.Equal(t, MediaTypeCBOR, recorder.Header().Get("Content-Type"))
+
+	recorder = apply(responder.Data(map[string]int{"a": 1}), "image/png")
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	body, _ := ioutil.ReadAll(recorder.Body)
+	assert.Contains(t, string(body), MediaTypeJSON)
+
+	recorder = apply(responder.ServerError(nil), "image/png")
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+}
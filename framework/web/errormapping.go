@@ -0,0 +1,127 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"flamingo.me/dingo"
+)
+
+type (
+	// ErrorMapping maps matching errors to a status code, and optionally to a template and a problem type
+	ErrorMapping struct {
+		Match       func(err error) bool
+		Status      uint
+		Template    string
+		ProblemType ProblemType
+	}
+
+	errorMappingProvider func() []ErrorMapping
+)
+
+// defaultErrorMappings are consulted after the bound error mappings
+var defaultErrorMappings = []ErrorMapping{
+	MapErrorIs(ErrRequestTimeout, http.StatusServiceUnavailable),
+	MapErrorAs(new(*ValidationError), http.StatusUnprocessableEntity),
+	MapErrorIs(ErrUnsupportedMediaType, http.StatusUnsupportedMediaType),
+	MapErrorIs(ErrInvalidBody, http.StatusBadRequest),
+	MapErrorIs(ErrUploadTooLarge, http.StatusRequestEntityTooLarge),
+	MapErrorIs(ErrBodyTooLarge, http.StatusRequestEntityTooLarge),
+	MapErrorIs(ErrPreconditionFailed, http.StatusPreconditionFailed),
+}
+
+// BindErrorMapping registers an error mapping for Responder.Error, mappings are checked in the order they are bound
+//
+//	web.BindErrorMapping(injector).ToInstance(web.MapErrorIs(domain.ErrProductNotFound, http.StatusNotFound))
+func BindErrorMapping(injector *dingo.Injector) *dingo.Binding {
+	return injector.BindMulti(new(ErrorMapping))
+}
+
+// MapErrorIs maps errors matching the target via errors.Is to the status
+func MapErrorIs(target error, status uint) ErrorMapping {
+	return ErrorMapping{
+		Match: func(err error) bool {
+			return errors.Is(err, target)
+		},
+		Status: status,
+	}
+}
+
+// MapErrorAs maps errors matching the target via errors.As to the status,
+// the target is a pointer to the error type, e.g. `new(*ValidationError)`
+func MapErrorAs(target interface{}, status uint) ErrorMapping {
+	targetType := reflect.TypeOf(target)
+	if targetType == nil || targetType.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("error mapping target must be a non-nil pointer, got %T", target))
+	}
+
+	return ErrorMapping{
+		Match: func(err error) bool {
+			return errors.As(err, reflect.New(targetType.Elem()).Interface())
+		},
+		Status: status,
+	}
+}
+
+// WithTemplate renders the mapped errors with the given template
+func (m ErrorMapping) WithTemplate(template string) ErrorMapping {
+	m.Template = template
+	return m
+}
+
+// WithProblemType renders the mapped errors with the given problem type
+func (m ErrorMapping) WithProblemType(problemType ProblemType) ErrorMapping {
+	m.ProblemType = problemType
+	return m
+}
+
+// Error creates an error response, the status, template and problem type are taken from the first matching error mapping.
+// Unmapped errors result in a 500 error response.
+func (r *Responder) Error(err error) *ServerErrorResponse {
+	for _, mappings := range [][]ErrorMapping{r.errorMappings, defaultErrorMappings} {
+		for _, mapping := range mappings {
+			if mapping.Match == nil || !mapping.Match(err) {
+				continue
+			}
+
+			response := r.errorWithStatus(err, mapping.Status)
+			if mapping.Template != "" {
+				response.Template = mapping.Template
+			}
+			if mapping.ProblemType != (ProblemType{}) {
+				response.Type(mapping.ProblemType)
+			}
+			return response
+		}
+	}
+
+	return r.ServerError(err)
+}
+
+// errorWithStatus uses the matching responder method of the status, to get the same logging and templates
+func (r *Responder) errorWithStatus(err error, status uint) *ServerErrorResponse {
+	switch status {
+	case http.StatusBadRequest:
+		return r.BadRequest(err)
+	case http.StatusForbidden:
+		return r.Forbidden(err)
+	case http.StatusNotFound:
+		return r.NotFound(err)
+	case http.StatusUnprocessableEntity:
+		return r.UnprocessableEntity(err)
+	case http.StatusInternalServerError:
+		return r.ServerError(err)
+	case http.StatusServiceUnavailable:
+		return r.Unavailable(err)
+	}
+
+	if status >= http.StatusInternalServerError {
+		r.getLogger().Error(fmt.Sprintf("%+v\n", err))
+	} else {
+		r.getLogger().Warn(err)
+	}
+
+	return r.ServerErrorWithCodeAndTemplate(err, r.templateErrorWithCode, status)
+}
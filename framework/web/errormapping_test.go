@@ -0,0 +1,65 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type testOutOfStockError struct {
+	sku string
+}
+
+func (e *testOutOfStockError) Error() string {
+	return "out of stock: " + e.sku
+}
+
+func TestResponderError(t *testing.T) {
+	errProductNotFound := errors.New("product not found")
+	outOfStock := ProblemType{URI: "https://example.com/problems/out-of-stock", Title: "Out of stock"}
+
+	responder := &Responder{
+		logger:                flamingo.NullLogger{},
+		templateNotFound:      "error/404",
+		templateErrorWithCode: "error/withCode",
+		errorMappings: []ErrorMapping{
+			MapErrorIs(errProductNotFound, http.StatusNotFound),
+			MapErrorAs(new(*testOutOfStockError), http.StatusConflict).WithTemplate("error/stock").WithProblemType(outOfStock),
+			MapErrorIs(ErrInvalidBody, http.StatusTeapot),
+		},
+	}
+
+	response := responder.Error(fmt.Errorf("loading: %w", errProductNotFound))
+	assert.Equal(t, uint(http.StatusNotFound), response.Response.Status)
+	assert.Equal(t, "error/404", response.Template)
+
+	response = responder.Error(fmt.Errorf("cart: %w", &testOutOfStockError{sku: "1"}))
+	assert.Equal(t, uint(http.StatusConflict), response.Response.Status)
+	assert.Equal(t, "error/stock", response.Template)
+	assert.Equal(t, outOfStock, response.problemType)
+
+	t.Run("bound mappings take precedence", func(t *testing.T) {
+		assert.Equal(t, uint(http.StatusTeapot), responder.Error(ErrInvalidBody).Response.Status)
+	})
+
+	t.Run("default mappings", func(t *testing.T) {
+		assert.Equal(t, uint(http.StatusServiceUnavailable), responder.Error(ErrRequestTimeout).Response.Status)
+		assert.Equal(t, uint(http.StatusUnprocessableEntity), responder.Error(&ValidationError{}).Response.Status)
+		assert.Equal(t, uint(http.StatusUnsupportedMediaType), responder.Error(ErrUnsupportedMediaType).Response.Status)
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.Equal(t, uint(http.StatusNotFound), responder.Error(panicToError(errProductNotFound)).Response.Status)
+	})
+
+	t.Run("unmapped", func(t *testing.T) {
+		assert.Equal(t, uint(http.StatusInternalServerError), responder.Error(errors.New("unknown")).Response.Status)
+	})
+
+	assert.Panics(t, func() { MapErrorAs(testOutOfStockError{}, http.StatusConflict) })
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	// Event is a single server-sent event
+	Event struct {
+		// ID is sent as event id, the client sends it back as Last-Event-ID when reconnecting
+		ID string
+		// Event is the event type, the client dispatches events without type as `message`
+		Event string
+		// Data is written as is for strings and byte slices, other values are encoded as JSON
+		Data interface{}
+		// Retry tells the client how long to wait before reconnecting
+		Retry time.Duration
+	}
+
+	// EmitFunc sends an event to the client and flushes it
+	EmitFunc func(event Event) error
+
+	// EventStreamFunc produces the events of an event stream until it returns or the context is done
+	EventStreamFunc func(ctx context.Context, emit EmitFunc) error
+
+	// EventStreamResponse streams server-sent events (text/event-stream)
+	EventStreamResponse struct {
+		Response
+		stream    EventStreamFunc
+		keepAlive time.Duration
+		logger    flamingo.Logger
+	}
+)
+
+// DefaultEventStreamKeepAlive is the default interval of keep-alive comments of event streams
+const DefaultEventStreamKeepAlive = 15 * time.Second
+
+// EventStream creates a server-sent events response.
+// The session is saved and released before the stream starts, changes to the session within the stream are not persisted.
+func (r *Responder) EventStream(stream EventStreamFunc) *EventStreamResponse {
+	return &EventStreamResponse{
+		Response: Response{
+			Status: http.StatusOK,
+			Header: make(http.Header),
+		},
+		stream:    stream,
+		keepAlive: DefaultEventStreamKeepAlive,
+		logger:    r.getLogger(),
+	}
+}
+
+// LastEventID returns the id of the last event the client received before reconnecting
+func LastEventID(ctx context.Context) string {
+	if req := RequestFromContext(ctx); req != nil {
+		return req.Request().Header.Get("Last-Event-ID")
+	}
+	return ""
+}
+
+// KeepAlive sets the interval of keep-alive comments, 0 disables them
+func (r *EventStreamResponse) KeepAlive(interval time.Duration) *EventStreamResponse {
+	r.keepAlive = interval
+	return r
+}
+
+// Apply response
+func (r *EventStreamResponse) Apply(ctx context.Context, w http.ResponseWriter) error {
+	if session := SessionFromContext(ctx); session != nil {
+		session.release()
+	}
+
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	r.Header.Set("Cache-Control", "no-cache")
+	r.Header.Set("X-Accel-Buffering", "no")
+	r.Body = nil
+
+	if err := r.Response.Apply(ctx, w); err != nil {
+		return err
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	write := func(b []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, err := w.Write(b); err != nil {
+			cancel()
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	if r.keepAlive > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(r.keepAlive)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-streamCtx.Done():
+					return
+				case <-ticker.C:
+					_ = write([]byte(": keep-alive\n\n"))
+				}
+			}
+		}()
+	}
+
+	err := r.stream(streamCtx, func(event Event) error {
+		if err := streamCtx.Err(); err != nil {
+			return err
+		}
+		b, err := event.encode()
+		if err != nil {
+			return err
+		}
+		return write(b)
+	})
+
+	cancel()
+	wg.Wait()
+
+	// the client went away, the stream ended as expected
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		return nil
+	}
+
+	// the headers are already sent, so the error can not be rendered anymore
+	if err != nil && r.logger != nil {
+		r.logger.WithContext(ctx).Error("event stream: ", err)
+	}
+
+	return nil
+}
+
+func (e Event) encode() ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	if e.ID != "" {
+		buf.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + singleLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zemirco/memorystore"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func TestEventEncode(t *testing.T) {
+	b, err := Event{ID: "1\n", Event: "update", Data: "line1\nline2", Retry: time.Second}.encode()
+	require.NoError(t, err)
+	assert.Equal(t, "id: 1\nevent: update\nretry: 1000\ndata: line1\ndata: line2\n\n", string(b))
+
+	b, err = Event{Data: map[string]int{"a": 1}}.encode()
+	require.NoError(t, err)
+	assert.Equal(t, "data: {\"a\":1}\n\n", string(b))
+}
+
+func TestEventStreamResponse(t *testing.T) {
+	sessionStore := &SessionStore{
+		logger:       flamingo.NullLogger{},
+		sessionName:  "test",
+		sessionStore: memorystore.NewMemoryStore([]byte("flamingosecret")),
+	}
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return nil },
+		routesProvider: func() []RoutesModule { return nil },
+		logger:         flamingo.NullLogger{},
+		sessionStore:   sessionStore,
+		sessionName:    "test",
+	}
+	h := router.Handler()
+	registry := NewRegistry()
+	h.(*handler).routerRegistry = registry
+
+	read := make(chan struct{})
+	done := make(chan error, 1)
+	registry.HandleGet("stream", func(ctx context.Context, req *Request) Result {
+		req.Session().Store("key", "before")
+		return new(Responder).EventStream(func(ctx context.Context, emit EmitFunc) error {
+			req.Session().Store("key", "during")
+
+			if err := emit(Event{ID: "2", Data: "resumed after " + LastEventID(ctx)}); err != nil {
+				return err
+			}
+			// the event must be flushed before the stream continues
+			<-read
+
+			for i := 0; ; i++ {
+				if err := emit(Event{Data: fmt.Sprint(i)}); err != nil {
+					done <- err
+					return err
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		}).KeepAlive(time.Millisecond)
+	})
+	registry.MustRoute("/stream", "stream")
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	require.NoError(t, err)
+	request.Header.Set("Last-Event-ID", "1")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	assert.Equal(t, "text/event-stream; charset=utf-8", response.Header.Get("Content-Type"))
+	assert.NotEmpty(t, response.Header.Get("Set-Cookie"))
+
+	reader := bufio.NewReader(response.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "id: 2\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: resumed after 1\n", line)
+	close(read)
+
+	keepAlive := false
+	for i := 0; i < 100 && !keepAlive; i++ {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		keepAlive = line == ": keep-alive\n"
+	}
+	assert.True(t, keepAlive)
+
+	require.NoError(t, response.Body.Close())
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("stream did not stop after the client disconnected")
+	}
+
+	// the session was saved before the stream started, and is not saved again afterwards
+	sessionRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range response.Cookies() {
+		sessionRequest.AddCookie(cookie)
+	}
+	session, err := sessionStore.LoadByRequest(context.Background(), sessionRequest)
+	require.NoError(t, err)
+	value, _ := session.Load("key")
+	assert.Equal(t, "before", value)
+}
+
+func TestEventStreamError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	response := (&Responder{logger: flamingo.NullLogger{}}).EventStream(func(ctx context.Context, emit EmitFunc) error {
+		if err := emit(Event{Data: "a"}); err != nil {
+			return err
+		}
+		return errors.New("stream failed")
+	})
+
+	assert.NoError(t, response.Apply(context.Background(), recorder))
+	assert.True(t, recorder.Flushed)
+	assert.True(t, strings.HasSuffix(recorder.Body.String(), "data: a\n\n"))
+}
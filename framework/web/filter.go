@@ -3,6 +3,8 @@ package web
 import (
 	"context"
 	"net/http"
+
+	"flamingo.me/dingo"
 )
 
 type (
@@ -25,6 +27,11 @@ func (fnc lastFilter) Filter(ctx context.Context, req *Request, w http.ResponseW
 	return fnc(ctx, req, w)
 }
 
+// BindNamedFilter binds a filter which can be attached to routes by its name, e.g. in the routes.yml
+func BindNamedFilter(injector *dingo.Injector, name string) *dingo.Binding {
+	return injector.BindMap(new(Filter), name)
+}
+
 // NewFilterChain constructs and sets the final filter and optional filters
 func NewFilterChain(final lastFilter, filters ...Filter) *FilterChain {
 	return &FilterChain{
@@ -45,6 +52,17 @@ func (fc *FilterChain) Next(ctx context.Context, req *Request, w http.ResponseWr
 	return next.Filter(ctx, req, w, fc)
 }
 
+// Fork returns a copy of the remaining chain, which can be processed independently of the current request,
+// e.g. to revalidate a cached response in the background. Post apply callbacks are not part of the copy.
+func (fc *FilterChain) Fork() *FilterChain {
+	filters := make([]Filter, len(fc.filters))
+	copy(filters, fc.filters)
+	return &FilterChain{
+		final:   fc.final,
+		filters: filters,
+	}
+}
+
 // AddPostApply adds a callback to be called after the response has been applied to the responsewriter
 func (fc *FilterChain) AddPostApply(callback func(err error, result Result)) {
 	fc.postApply = append(fc.postApply, callback)
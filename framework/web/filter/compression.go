@@ -0,0 +1,431 @@
+package filter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// CompressionFilter compresses responses with gzip or deflate, as negotiated via the Accept-Encoding header.
+	// Small responses, responses with a content type not on the allow-list and already encoded responses are not compressed.
+	CompressionFilter struct {
+		minSize int
+		types   []string
+		pools   map[string]*sync.Pool
+	}
+
+	// compressionResult wraps the result of the filter chain
+	compressionResult struct {
+		result      web.Result
+		filter      *CompressionFilter
+		encoding    string
+		ifNoneMatch string
+	}
+
+	// compressionResponseWriter buffers the response until it is known whether it is compressed
+	compressionResponseWriter struct {
+		rw          http.ResponseWriter
+		filter      *CompressionFilter
+		encoding    string
+		ifNoneMatch string
+		state       compressionState
+		status      int
+		buf         []byte
+		encoder     compressionEncoder
+	}
+
+	// compressionEncoder is implemented by gzip.Writer and zlib.Writer
+	compressionEncoder interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+
+	compressionState int
+)
+
+const (
+	// statePending waits for the header
+	statePending compressionState = iota
+	// stateBuffering collects the body until the minimum size is reached
+	stateBuffering
+	// statePassthrough writes the body unchanged
+	statePassthrough
+	// stateCompressing writes the body via the encoder
+	stateCompressing
+)
+
+// supported content encodings, in order of preference
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// DefaultCompressionTypes are compressed if flamingo.web.compression.types is empty
+var DefaultCompressionTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/javascript",
+	"text/xml",
+	"text/csv",
+	"application/javascript",
+	"application/json",
+	"application/problem+json",
+	"application/xml",
+	"application/cbor",
+	"image/svg+xml",
+}
+
+// Inject CompressionFilter dependencies
+func (f *CompressionFilter) Inject(cfg *struct {
+	Level   float64      `inject:"config:flamingo.web.compression.level,optional"`
+	MinSize float64      `inject:"config:flamingo.web.compression.minSize,optional"`
+	Types   config.Slice `inject:"config:flamingo.web.compression.types,optional"`
+}) *CompressionFilter {
+	level := gzip.DefaultCompression
+	var types []string
+	if cfg != nil {
+		level = int(cfg.Level)
+		f.minSize = int(cfg.MinSize)
+		if err := cfg.Types.MapInto(&types); err != nil {
+			panic(fmt.Errorf("flamingo.web.compression.types: %w", err))
+		}
+	}
+	if len(types) == 0 {
+		types = DefaultCompressionTypes
+	}
+	f.types = make([]string, len(types))
+	for i, t := range types {
+		f.types[i] = strings.ToLower(t)
+	}
+
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic(fmt.Errorf("flamingo.web.compression.level: %w", err))
+	}
+
+	f.pools = map[string]*sync.Pool{
+		encodingGzip: {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, level)
+			return w
+		}},
+		encodingDeflate: {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, level)
+			return w
+		}},
+	}
+
+	return f
+}
+
+// Filter wraps the result, the response is compressed when it is applied
+func (f *CompressionFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	return &compressionResult{
+		result:      chain.Next(ctx, req, w),
+		filter:      f,
+		encoding:    negotiateEncoding(req.Request().Header.Get("Accept-Encoding")),
+		ifNoneMatch: req.Request().Header.Get("If-None-Match"),
+	}
+}
+
+// Apply the wrapped result to a compressing response writer
+func (r *compressionResult) Apply(ctx context.Context, rw http.ResponseWriter) error {
+	if r.result == nil {
+		return nil
+	}
+
+	w := &compressionResponseWriter{
+		rw:          rw,
+		filter:      r.filter,
+		encoding:    r.encoding,
+		ifNoneMatch: r.ifNoneMatch,
+	}
+
+	err := r.result.Apply(ctx, w)
+	if closeErr := w.close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// negotiateEncoding picks the supported encoding with the highest quality, gzip is preferred on equal quality
+func negotiateEncoding(acceptEncoding string) string {
+	var encoding string
+	var quality float64
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		var candidates []string
+		switch name {
+		case encodingGzip, "x-gzip":
+			candidates = []string{encodingGzip}
+		case encodingDeflate:
+			candidates = []string{encodingDeflate}
+		case "*":
+			candidates = []string{encodingGzip, encodingDeflate}
+		}
+
+		for _, candidate := range candidates {
+			if q > quality || (q == quality && q > 0 && candidate == encodingGzip) {
+				encoding, quality = candidate, q
+			}
+		}
+	}
+
+	return encoding
+}
+
+// compressible checks the content type against the allow-list, ignoring parameters like the charset
+func (f *CompressionFilter) compressible(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" || mediaType == "text/event-stream" {
+		return false
+	}
+
+	for _, t := range f.types {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Header of the response
+func (w *compressionResponseWriter) Header() http.Header {
+	return w.rw.Header()
+}
+
+// WriteHeader decides whether the response can be compressed, the header is written once this is known
+func (w *compressionResponseWriter) WriteHeader(status int) {
+	if w.state != statePending {
+		return
+	}
+	header := w.Header()
+
+	// informational responses are followed by the final response
+	if status < http.StatusOK {
+		w.rw.WriteHeader(status)
+		return
+	}
+	w.status = status
+
+	if status == http.StatusNotModified {
+		w.notModified()
+		w.passthrough()
+		return
+	}
+
+	if status == http.StatusNoContent || status == http.StatusPartialContent ||
+		header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		w.passthrough()
+		return
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType != "" {
+		if !w.filter.compressible(contentType) {
+			w.passthrough()
+			return
+		}
+		addVary(header)
+	}
+
+	if w.encoding == "" || noTransform(header) {
+		w.passthrough()
+		return
+	}
+
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < w.filter.minSize {
+		w.passthrough()
+		return
+	}
+
+	w.state = stateBuffering
+}
+
+// Write the body, it is buffered until the minimum size is reached
+func (w *compressionResponseWriter) Write(b []byte) (int, error) {
+	if w.state == statePending {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	switch w.state {
+	case stateCompressing:
+		return w.encoder.Write(b)
+	case stateBuffering:
+		w.buf = append(w.buf, b...)
+		if len(w.buf) >= w.filter.minSize {
+			if err := w.decide(); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	default:
+		return w.rw.Write(b)
+	}
+}
+
+// Flush implements http.Flusher for streaming responses, a buffered response is compressed regardless of its size
+func (w *compressionResponseWriter) Flush() {
+	if w.state == statePending {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.state == stateBuffering {
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+	if w.state == stateCompressing {
+		if err := w.encoder.Flush(); err != nil {
+			return
+		}
+	}
+
+	if flusher, ok := w.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for websocket connections
+func (w *compressionResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	w.state = statePassthrough
+	return hijacker.Hijack()
+}
+
+// decide on the buffered body, a missing content type is sniffed like net/http does
+func (w *compressionResponseWriter) decide() error {
+	header := w.Header()
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+		if !w.filter.compressible(contentType) {
+			return w.passthrough()
+		}
+		addVary(header)
+	}
+
+	w.state = stateCompressing
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	// the compressed body differs, so a strong etag is weakened, which still matches If-None-Match
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	w.rw.WriteHeader(w.status)
+
+	w.encoder = w.filter.pools[w.encoding].Get().(compressionEncoder)
+	w.encoder.Reset(w.rw)
+
+	buf := w.buf
+	w.buf = nil
+	_, err := w.encoder.Write(buf)
+	return err
+}
+
+// passthrough writes the header and the buffered body unchanged
+func (w *compressionResponseWriter) passthrough() error {
+	w.state = statePassthrough
+	w.rw.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.rw.Write(buf)
+	return err
+}
+
+// notModified answers with the etag the client knows, if it got the weakened etag of a compressed response
+func (w *compressionResponseWriter) notModified() {
+	header := w.Header()
+	if w.encoding == "" {
+		return
+	}
+	addVary(header)
+
+	etag := header.Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return
+	}
+	for _, candidate := range strings.Split(w.ifNoneMatch, ",") {
+		if strings.TrimSpace(candidate) == "W/"+etag {
+			header.Set("ETag", "W/"+etag)
+			return
+		}
+	}
+}
+
+// close writes a buffered body which stayed below the minimum size, or finishes the compressed body
+func (w *compressionResponseWriter) close() error {
+	switch w.state {
+	case stateBuffering:
+		return w.passthrough()
+	case stateCompressing:
+		err := w.encoder.Close()
+		w.encoder.Reset(nil)
+		w.filter.pools[w.encoding].Put(w.encoder)
+		w.encoder = nil
+		return err
+	}
+
+	return nil
+}
+
+// addVary adds Accept-Encoding to the Vary header, unless it is already listed
+func addVary(header http.Header) {
+	for _, vary := range header["Vary"] {
+		for _, name := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(name), "Accept-Encoding") {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", "Accept-Encoding")
+}
+
+// noTransform checks for the Cache-Control directive no-transform, e.g. set via web.CacheDirective
+func noTransform(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-transform") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,295 @@
+package filter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type compressionConfig = struct {
+	Level   float64      `inject:"config:flamingo.web.compression.level,optional"`
+	MinSize float64      `inject:"config:flamingo.web.compression.minSize,optional"`
+	Types   config.Slice `inject:"config:flamingo.web.compression.types,optional"`
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+// compress applies the result through the filter, the request is available in the context like in the router
+func compress(t *testing.T, f *CompressionFilter, request *http.Request, result web.Result) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := web.CreateRequest(request, nil)
+	ctx := web.ContextWithRequest(context.Background(), req)
+	chain := web.NewFilterChain(func(context.Context, *web.Request, http.ResponseWriter) web.Result {
+		return result
+	}, f)
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, chain.Next(ctx, req, recorder).Apply(ctx, recorder))
+
+	return recorder
+}
+
+func compressionRequest(acceptEncoding string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return request
+}
+
+func textResponse(contentType, body string) *web.Response {
+	return &web.Response{
+		Status: http.StatusOK,
+		Header: http.Header{"Content-Type": []string{contentType}},
+		Body:   strings.NewReader(body),
+	}
+}
+
+func gunzip(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	reader, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestCompressionFilter(t *testing.T) {
+	f := new(CompressionFilter).Inject(&compressionConfig{Level: -1, MinSize: 100})
+	body := strings.Repeat(`{"product":"flamingo"}`, 20)
+
+	t.Run("gzip", func(t *testing.T) {
+		response := textResponse("application/json; charset=utf-8", body)
+		response.Header.Set("Content-Length", "440")
+		recorder := compress(t, f, compressionRequest("deflate, gzip;q=1.0, br;q=0.5"), response)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+		assert.Empty(t, recorder.Header().Get("Content-Length"))
+		assert.Less(t, recorder.Body.Len(), len(body))
+		assert.Equal(t, body, gunzip(t, recorder))
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		recorder := compress(t, f, compressionRequest("gzip;q=0.5, deflate"), textResponse("text/html", body))
+
+		assert.Equal(t, "deflate", recorder.Header().Get("Content-Encoding"))
+		reader, err := zlib.NewReader(recorder.Body)
+		require.NoError(t, err)
+		decompressed, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(decompressed))
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "br", "gzip;q=0, deflate;q=0"} {
+			recorder := compress(t, f, compressionRequest(acceptEncoding), textResponse("text/html", body))
+
+			assert.Empty(t, recorder.Header().Get("Content-Encoding"), acceptEncoding)
+			assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"), "caches have to tell the encodings apart")
+			assert.Equal(t, body, recorder.Body.String())
+		}
+	})
+
+	t.Run("small body", func(t *testing.T) {
+		recorder := compress(t, f, compressionRequest("gzip"), textResponse("text/html", "<p>small</p>"))
+
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, "<p>small</p>", recorder.Body.String())
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		recorder := compress(t, f, compressionRequest("gzip"), textResponse("image/png", body))
+
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Empty(t, recorder.Header().Get("Vary"))
+		assert.Equal(t, body, recorder.Body.String())
+	})
+
+	t.Run("sniffed content type", func(t *testing.T) {
+		html := "<html><body>" + strings.Repeat("flamingo ", 20) + "</body></html>"
+		recorder := compress(t, f, compressionRequest("gzip"), &web.Response{Status: http.StatusOK, Body: strings.NewReader(html)})
+
+		assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, html, gunzip(t, recorder))
+	})
+
+	t.Run("already encoded", func(t *testing.T) {
+		response := textResponse("text/css", body)
+		response.Header.Set("Content-Encoding", "br")
+		recorder := compress(t, f, compressionRequest("gzip"), response)
+
+		assert.Equal(t, "br", recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, body, recorder.Body.String())
+	})
+
+	t.Run("no-transform", func(t *testing.T) {
+		response := textResponse("text/html", body)
+		response.CacheDirective = &web.CacheDirective{NoTransform: true}
+		recorder := compress(t, f, compressionRequest("gzip"), response)
+
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, body, recorder.Body.String())
+	})
+
+	t.Run("configured types", func(t *testing.T) {
+		f := new(CompressionFilter).Inject(&compressionConfig{Level: 1, Types: config.Slice{"Text/*"}})
+
+		assert.Equal(t, "gzip", compress(t, f, compressionRequest("gzip"), textResponse("text/markdown", body)).Header().Get("Content-Encoding"))
+		assert.Empty(t, compress(t, f, compressionRequest("gzip"), textResponse("application/json", body)).Header().Get("Content-Encoding"))
+		assert.Empty(t, compress(t, f, compressionRequest("gzip"), textResponse("text/event-stream", body)).Header().Get("Content-Encoding"))
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		assert.Panics(t, func() { new(CompressionFilter).Inject(&compressionConfig{Level: 10}) })
+	})
+}
+
+func TestCompressionFilter_ETag(t *testing.T) {
+	f := new(CompressionFilter).Inject(&compressionConfig{Level: -1, MinSize: 10})
+	body := strings.Repeat("flamingo ", 20)
+
+	response := func() *web.Response {
+		response := textResponse("text/plain", body)
+		response.CacheDirective = &web.CacheDirective{ETag: `"v1"`}
+		return response
+	}
+
+	t.Run("strong etags are weakened", func(t *testing.T) {
+		recorder := compress(t, f, compressionRequest("gzip"), response())
+		assert.Equal(t, `W/"v1"`, recorder.Header().Get("ETag"))
+
+		recorder = compress(t, f, compressionRequest(""), response())
+		assert.Equal(t, `"v1"`, recorder.Header().Get("ETag"), "uncompressed responses keep the etag")
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		request := compressionRequest("gzip")
+		request.Header.Set("If-None-Match", `W/"v1"`)
+		recorder := compress(t, f, request, response())
+
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Equal(t, `W/"v1"`, recorder.Header().Get("ETag"))
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+		assert.Empty(t, recorder.Body.String())
+	})
+
+	t.Run("weak etags are kept", func(t *testing.T) {
+		weak := textResponse("text/plain", body)
+		weak.Header.Set("ETag", `W/"v2"`)
+		recorder := compress(t, f, compressionRequest("gzip"), weak)
+
+		assert.Equal(t, `W/"v2"`, recorder.Header().Get("ETag"))
+	})
+}
+
+func TestCompressionFilter_Streaming(t *testing.T) {
+	f := new(CompressionFilter).Inject(&compressionConfig{Level: -1, MinSize: 1024})
+
+	t.Run("flush", func(t *testing.T) {
+		req := web.CreateRequest(compressionRequest("gzip"), nil)
+		recorder := httptest.NewRecorder()
+		result := &compressionResult{
+			result: resultFunc(func(_ context.Context, w http.ResponseWriter) error {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"chunk":1}`))
+				w.(http.Flusher).Flush()
+				assert.True(t, recorder.Flushed)
+				assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"), "flushed responses are compressed regardless of the size")
+				_, _ = w.Write([]byte(`{"chunk":2}`))
+				return nil
+			}),
+			filter:   f,
+			encoding: negotiateEncoding(req.Request().Header.Get("Accept-Encoding")),
+		}
+
+		require.NoError(t, result.Apply(context.Background(), recorder))
+		assert.Equal(t, `{"chunk":1}{"chunk":2}`, gunzip(t, recorder))
+	})
+
+	t.Run("event stream", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		result := &compressionResult{
+			result: resultFunc(func(_ context.Context, w http.ResponseWriter) error {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("data: 1\n\n"))
+				w.(http.Flusher).Flush()
+				assert.Equal(t, "data: 1\n\n", recorder.Body.String(), "events are written immediately")
+				return nil
+			}),
+			filter:   f,
+			encoding: encodingGzip,
+		}
+
+		require.NoError(t, result.Apply(context.Background(), recorder))
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+		result := &compressionResult{
+			result: resultFunc(func(_ context.Context, w http.ResponseWriter) error {
+				_, _, err := w.(http.Hijacker).Hijack()
+				return err
+			}),
+			filter:   f,
+			encoding: encodingGzip,
+		}
+
+		require.NoError(t, result.Apply(context.Background(), recorder))
+		assert.True(t, recorder.hijacked)
+	})
+}
+
+type resultFunc func(ctx context.Context, w http.ResponseWriter) error
+
+func (f resultFunc) Apply(ctx context.Context, w http.ResponseWriter) error {
+	return f(ctx, w)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for acceptEncoding, expected := range map[string]string{
+		"":                          "",
+		"gzip":                      "gzip",
+		"GZIP":                      "gzip",
+		"x-gzip":                    "gzip",
+		"deflate":                   "deflate",
+		"deflate, gzip":             "gzip",
+		"gzip;q=0.5, deflate":       "deflate",
+		"gzip;q=0":                  "",
+		"*":                         "gzip",
+		"br, *;q=0.1":               "gzip",
+		"identity":                  "",
+		"gzip;q=0.8, deflate;q=0.9": "deflate",
+	} {
+		assert.Equal(t, expected, negotiateEncoding(acceptEncoding), acceptEncoding)
+	}
+}
@@ -1,7 +1,9 @@
 package filter
 
 import (
+	"bufio"
 	"context"
+	"net"
 	"net/http"
 	"strconv"
 
@@ -62,6 +64,26 @@ func (r *responseWriterMetrics) WriteHeader(statusCode int) {
 	r.rw.WriteHeader(statusCode)
 }
 
+// Flush implements http.Flusher for streaming responses
+func (r *responseWriterMetrics) Flush() {
+	if flusher, ok := r.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for websocket connections
+func (r *responseWriterMetrics) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
 // Apply metricsFilter to request
 func (r responseMetrics) Apply(ctx context.Context, rw http.ResponseWriter) error {
 	var err error
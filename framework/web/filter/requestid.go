@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"context"
+	"net/http"
+
+	uuid "github.com/satori/go.uuid"
+	"go.opencensus.io/trace"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// RequestIDFilter accepts the request ID of an incoming request, or generates a new one.
+	// The ID is stored in the context, see flamingo.RequestIDFromContext, and echoed in the response header.
+	RequestIDFilter struct {
+		header        string
+		trustIncoming bool
+	}
+
+	// RequestIDTransport adds the request ID of the request context to outbound requests.
+	// The header defaults to RequestIDHeader, and requests are sent via the current http.DefaultTransport if Next is nil.
+	RequestIDTransport struct {
+		Header string
+		Next   http.RoundTripper
+	}
+)
+
+const (
+	// RequestIDHeader is the default header of the request ID
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+// Inject RequestIDFilter dependencies
+func (f *RequestIDFilter) Inject(cfg *struct {
+	Header        string `inject:"config:flamingo.web.requestID.header,optional"`
+	TrustIncoming bool   `inject:"config:flamingo.web.requestID.trustIncoming,optional"`
+}) *RequestIDFilter {
+	if cfg != nil {
+		f.header = cfg.Header
+		f.trustIncoming = cfg.TrustIncoming
+	}
+	if f.header == "" {
+		f.header = RequestIDHeader
+	}
+	return f
+}
+
+// Inject RequestIDTransport dependencies, the header is taken from flamingo.web.requestID.header
+func (t *RequestIDTransport) Inject(cfg *struct {
+	Header string `inject:"config:flamingo.web.requestID.header,optional"`
+}) *RequestIDTransport {
+	if cfg != nil {
+		t.Header = cfg.Header
+	}
+	return t
+}
+
+// Filter a web request
+func (f *RequestIDFilter) Filter(ctx context.Context, req *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	id := req.Request().Header.Get(f.header)
+	if !f.trustIncoming || !validRequestID(id) {
+		id = uuid.NewV4().String()
+	}
+
+	req.Request().Header.Set(f.header, id)
+	w.Header().Set(f.header, id)
+	trace.FromContext(ctx).AddAttributes(trace.StringAttribute("requestID", id))
+
+	return chain.Next(flamingo.ContextWithRequestID(ctx, id), req, w)
+}
+
+// validRequestID checks for printable ASCII without spaces, so IDs can be logged safely
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RoundTrip adds the request ID header, if the request does not set it already
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header := t.Header
+	if header == "" {
+		header = RequestIDHeader
+	}
+
+	if id := flamingo.RequestIDFromContext(req.Context()); id != "" && req.Header.Get(header) == "" {
+		// a RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set(header, id)
+	}
+
+	return t.next().RoundTrip(req)
+}
+
+// CloseIdleConnections of the next transport
+func (t *RequestIDTransport) CloseIdleConnections() {
+	if closer, ok := t.next().(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// next transport, http.DefaultTransport is resolved per request, so wrappers installed at startup, e.g. by opencensus, are used
+func (t *RequestIDTransport) next() http.RoundTripper {
+	if t.Next != nil {
+		return t.Next
+	}
+	return http.DefaultTransport
+}
@@ -0,0 +1,160 @@
+package filter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRequestIDFilter(t *testing.T) {
+	filter := func(f *RequestIDFilter, incoming string) (string, string, string) {
+		t.Helper()
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			request.Header.Set(f.header, incoming)
+		}
+		req := web.CreateRequest(request, nil)
+		recorder := httptest.NewRecorder()
+
+		var contextID, headerID string
+		chain := web.NewFilterChain(func(ctx context.Context, r *web.Request, w http.ResponseWriter) web.Result {
+			contextID = flamingo.RequestIDFromContext(ctx)
+			headerID = r.Request().Header.Get(f.header)
+			return nil
+		}, f)
+		assert.Nil(t, chain.Next(context.Background(), req, recorder))
+
+		return contextID, headerID, recorder.Header().Get(f.header)
+	}
+
+	trusting := new(RequestIDFilter).Inject(&struct {
+		Header        string `inject:"config:flamingo.web.requestID.header,optional"`
+		TrustIncoming bool   `inject:"config:flamingo.web.requestID.trustIncoming,optional"`
+	}{TrustIncoming: true})
+
+	t.Run("generated", func(t *testing.T) {
+		contextID, headerID, responseID := filter(trusting, "")
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`, contextID)
+		assert.Equal(t, contextID, headerID)
+		assert.Equal(t, contextID, responseID)
+
+		otherID, _, _ := filter(trusting, "")
+		assert.NotEqual(t, contextID, otherID)
+	})
+
+	t.Run("trusted incoming", func(t *testing.T) {
+		contextID, headerID, responseID := filter(trusting, "lb-4711")
+		assert.Equal(t, "lb-4711", contextID)
+		assert.Equal(t, "lb-4711", headerID)
+		assert.Equal(t, "lb-4711", responseID)
+	})
+
+	t.Run("invalid incoming", func(t *testing.T) {
+		for _, invalid := range []string{"with space", "new\nline", strings.Repeat("a", 129)} {
+			contextID, _, responseID := filter(trusting, invalid)
+			assert.NotEqual(t, invalid, contextID)
+			assert.Equal(t, contextID, responseID)
+		}
+	})
+
+	t.Run("untrusted incoming", func(t *testing.T) {
+		f := new(RequestIDFilter).Inject(&struct {
+			Header        string `inject:"config:flamingo.web.requestID.header,optional"`
+			TrustIncoming bool   `inject:"config:flamingo.web.requestID.trustIncoming,optional"`
+		}{Header: "X-Correlation-ID"})
+
+		contextID, headerID, responseID := filter(f, "lb-4711")
+		assert.NotEqual(t, "lb-4711", contextID)
+		assert.Equal(t, contextID, headerID)
+		assert.Equal(t, contextID, responseID)
+	})
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var sent *http.Request
+	transport := &RequestIDTransport{Header: RequestIDHeader, Next: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})}
+
+	t.Run("propagated", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(flamingo.ContextWithRequestID(context.Background(), "4711"))
+
+		_, err = transport.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, "4711", sent.Header.Get(RequestIDHeader))
+		assert.Empty(t, req.Header.Get(RequestIDHeader), "the original request is not modified")
+	})
+
+	t.Run("explicit header", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "explicit")
+		req = req.WithContext(flamingo.ContextWithRequestID(context.Background(), "4711"))
+
+		_, err = transport.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, "explicit", sent.Header.Get(RequestIDHeader))
+	})
+
+	t.Run("default header", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(flamingo.ContextWithRequestID(context.Background(), "4711"))
+
+		_, err = (&RequestIDTransport{Next: transport.Next}).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, "4711", sent.Header.Get(RequestIDHeader))
+	})
+
+	t.Run("without request ID", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		_, err = transport.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Empty(t, sent.Header.Get(RequestIDHeader))
+	})
+}
+
+func TestRequestIDTransport_DefaultTransport(t *testing.T) {
+	var sent *http.Request
+	defaultTransport := http.DefaultTransport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	transport := new(RequestIDTransport).Inject(&struct {
+		Header string `inject:"config:flamingo.web.requestID.header,optional"`
+	}{Header: "X-Correlation-ID"})
+
+	// the default transport is resolved per request, so it can be replaced after the transport is created
+	http.DefaultTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	require.NoError(t, err)
+	req = req.WithContext(flamingo.ContextWithRequestID(context.Background(), "4711"))
+
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.NotNil(t, sent)
+	assert.Equal(t, "4711", sent.Header.Get("X-Correlation-ID"))
+}
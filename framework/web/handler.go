@@ -2,15 +2,18 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"time"
 
 	"flamingo.me/flamingo/v3/framework/flamingo"
 	"flamingo.me/flamingo/v3/framework/opencensus"
+	"github.com/gorilla/websocket"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
@@ -29,6 +32,21 @@ type (
 		sessionName  string
 		prefix       string
 		responder    *Responder
+
+		methodNotAllowed bool
+		autoHead         bool
+		autoOptions      bool
+		timeout          time.Duration
+
+		webSocketUpgrader *websocket.Upgrader
+		uploadConfig      *uploadConfig
+		maxBodySize       int64
+		validationRules   map[string]ValidationRule
+	}
+
+	// headResponseWriter suppresses the body for HEAD requests served by GET actions
+	headResponseWriter struct {
+		http.ResponseWriter
 	}
 
 	panicError struct {
@@ -38,18 +56,25 @@ type (
 )
 
 var (
-	rt = stats.Int64("flamingo/router/controller", "controller request times", stats.UnitMilliseconds)
+	rt       = stats.Int64("flamingo/router/controller", "controller request times", stats.UnitMilliseconds)
+	timeouts = stats.Int64("flamingo/router/timeout", "controller request timeouts", stats.UnitDimensionless)
 	// ControllerKey exposes the current controller/handler key
 	ControllerKey, _ = tag.NewKey("controller")
 
 	// RouterError defines error value for issues appearing during routing process
 	RouterError contextKeyType = "error"
+
+	// ErrRequestTimeout is passed to the FlamingoError controller if a request exceeded its timeout
+	ErrRequestTimeout = errors.New("request timeout")
 )
 
 func init() {
 	if err := opencensus.View("flamingo/router/controller", rt, view.Distribution(100, 500, 1000, 2500, 5000, 10000), ControllerKey); err != nil {
 		panic(err)
 	}
+	if err := opencensus.View("flamingo/router/timeout", timeouts, view.Count(), ControllerKey); err != nil {
+		panic(err)
+	}
 }
 
 func (e *panicError) Error() string {
@@ -80,6 +105,18 @@ func (e *panicError) Format(s fmt.State, verb rune) {
 	}
 }
 
+// Write discards the body
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Flush implements http.Flusher
+func (w *headResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func panicToError(p interface{}) error {
 	if p == nil {
 		return nil
@@ -98,6 +135,38 @@ func panicToError(p interface{}) error {
 	return err
 }
 
+// allowedMethods returns the allowed methods for a request which could not be matched because of its method
+func (h *handler) allowedMethods(req *http.Request) []string {
+	allowed := h.routerRegistry.allowedMethods(req)
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	// the method is registered, the handler did not match for other reasons
+	if contains(allowed, req.Method) || (h.autoHead && req.Method == http.MethodHead && contains(allowed, http.MethodGet)) {
+		return nil
+	}
+
+	if h.autoHead && contains(allowed, http.MethodGet) && !contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if h.autoOptions && !contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+
+	return allowed
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	httpRequest.URL.Path = strings.TrimPrefix(httpRequest.URL.Path, h.prefix)
 
@@ -112,6 +181,32 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	_, span = trace.StartSpan(ctx, "router/matchRequest")
 	controller, params, handler := h.routerRegistry.matchRequest(httpRequest)
 
+	// method is the action method to call, which differs from the request method for HEAD served via GET
+	method := httpRequest.Method
+	if handler == nil && h.autoHead && method == http.MethodHead {
+		getRequest := *httpRequest
+		getRequest.Method = http.MethodGet
+		if controller, params, handler = h.routerRegistry.matchRequest(&getRequest); handler != nil {
+			method = http.MethodGet
+			rw = &headResponseWriter{ResponseWriter: rw}
+		}
+	}
+
+	// preflightRoute is the route of the method requested by a CORS preflight request
+	var preflightRoute *Handler
+	if requested := httpRequest.Header.Get("Access-Control-Request-Method"); method == http.MethodOptions && requested != "" {
+		preflightRequest := *httpRequest
+		preflightRequest.Method = requested
+		_, _, preflightRoute = h.routerRegistry.matchRequest(&preflightRequest)
+	}
+
+	var paramErr error
+	var allowed []string
+	if handler == nil {
+		paramErr = h.routerRegistry.matchParamError(httpRequest)
+		allowed = h.allowedMethods(httpRequest)
+	}
+
 	if handler != nil {
 		ctx, _ = tag.New(ctx, tag.Upsert(ControllerKey, handler.GetHandlerName()), tag.Insert(opencensus.KeyArea, "-"))
 		httpRequest = httpRequest.WithContext(ctx)
@@ -125,13 +220,29 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 		request: *httpRequest,
 		session: Session{s: session.s, sessionSaveMode: session.sessionSaveMode},
 		Params:  params,
+
+		uploadConfig:    h.uploadConfig,
+		maxBodySize:     h.maxBodySize,
+		paramTypes:      h.routerRegistry.paramTypes,
+		validationRules: h.validationRules,
 	}
 	ctx = ContextWithRequest(ContextWithSession(ctx, req.Session()), req)
+	if handler != nil {
+		ctx = ContextWithHandler(ctx, handler)
+	}
+	if preflightRoute != nil {
+		ctx = ContextWithPreflightHandler(ctx, preflightRoute)
+	}
+	if handler != nil && handler.problemDetails {
+		ctx = context.WithValue(ctx, contextProblemDetails, true)
+	}
 
 	var finishErr error
 	defer func() {
 		// fire finish event
 		h.eventRouter.Dispatch(ctx, &OnFinishEvent{OnRequestEvent{req, rw}, finishErr})
+		// uploads are available for the finish event subscribers, and removed afterwards
+		req.removeUploads()
 	}()
 
 	h.eventRouter.Dispatch(ctx, &OnRequestEvent{req, rw})
@@ -141,8 +252,14 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	ctx, span = trace.StartSpan(ctx, "router/request")
 	defer span.End()
 
+	filters := h.filter
+	if handler != nil && len(handler.filters) > 0 {
+		filters = make([]Filter, 0, len(h.filter)+len(handler.filters))
+		filters = append(append(filters, h.filter...), handler.filters...)
+	}
+
 	chain := &FilterChain{
-		filters: h.filter,
+		filters: filters,
 		final: func(ctx context.Context, r *Request, rw http.ResponseWriter) (response Result) {
 			ctx, span := trace.StartSpan(ctx, "router/controller")
 			defer span.End()
@@ -154,23 +271,60 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 				}
 			}()
 
-			defer h.eventRouter.Dispatch(ctx, &OnResponseEvent{OnRequestEvent{req, rw}, response})
+			// a forked chain passes its own request and response writer
+			defer h.eventRouter.Dispatch(ctx, &OnResponseEvent{OnRequestEvent{r, rw}, response})
 
-			if c, ok := controller.method[req.Request().Method]; ok && c != nil {
+			if c, ok := controller.method[method]; ok && c != nil {
 				response = c(ctx, r)
 			} else if controller.any != nil {
 				response = controller.any(ctx, r)
+			} else if len(allowed) > 0 && h.autoOptions && method == http.MethodOptions {
+				response = &Response{
+					Status: http.StatusNoContent,
+					Header: http.Header{"Allow": []string{strings.Join(allowed, ", ")}},
+				}
+			} else if len(allowed) > 0 && h.methodNotAllowed {
+				err := fmt.Errorf("method %q not allowed, allowed methods: %s", r.Request().Method, strings.Join(allowed, ", "))
+				response = h.responder.MethodNotAllowed(err, allowed...)
+				span.SetStatus(trace.Status{Code: trace.StatusCodeUnimplemented, Message: "method not allowed"})
+			} else if paramErr != nil {
+				response = h.responder.BadRequest(paramErr)
+				span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: "invalid param"})
 			} else {
-				err := fmt.Errorf("action for method %q not found and no \"any\" fallback", req.Request().Method)
+				err := fmt.Errorf("action for method %q not found and no \"any\" fallback", r.Request().Method)
 				response = h.routerRegistry.handler[FlamingoNotfound].any(context.WithValue(ctx, RouterError, err), r)
 				span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: "action not found"})
 			}
 
+			if ws, ok := response.(*webSocketResponse); ok {
+				ws.upgrader = h.webSocketUpgrader
+				ws.eventRouter = h.eventRouter
+			}
+
 			return h.responder.completeResult(response)
 		},
 	}
 
-	result := chain.Next(ctx, req, rw)
+	timeout := h.timeout
+	if handler != nil && handler.timeout != nil {
+		timeout = *handler.timeout
+	}
+
+	chainCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		chainCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result := chain.Next(chainCtx, req, rw)
+
+	if errors.Is(chainCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		stats.Record(ctx, timeouts.M(1))
+		err := fmt.Errorf("%w: %q exceeded %s", ErrRequestTimeout, req.Request().URL.Path, timeout)
+		result = h.responder.completeResult(h.routerRegistry.handler[FlamingoError].any(context.WithValue(ctx, RouterError, err), req))
+		span.SetStatus(trace.Status{Code: trace.StatusCodeDeadlineExceeded, Message: "request timeout"})
+	}
 
 	if header, err := h.sessionStore.Save(ctx, req.Session()); err == nil {
 		AddHTTPHeader(rw.Header(), header)
@@ -0,0 +1,533 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	// RouteDocumentation describes a route in the generated OpenAPI document
+	RouteDocumentation struct {
+		Summary     string
+		Description string
+		Tags        []string
+		// Methods documents routes of HandleAny actions, which are documented as GET otherwise
+		Methods []string
+		// Request is the request body, e.g. CreateProduct{}
+		Request interface{}
+		// Responses are the response bodies by status code, a nil body documents a response without content
+		Responses  map[int]interface{}
+		Security   []map[string][]string
+		Deprecated bool
+	}
+
+	// OpenAPIDocument is an OpenAPI 3 document
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components *OpenAPIComponents                      `json:"components,omitempty"`
+	}
+
+	// OpenAPIInfo describes the API
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	// OpenAPIServer is a base URL of the API
+	OpenAPIServer struct {
+		URL string `json:"url"`
+	}
+
+	// OpenAPIComponents holds the schemas of named types and the security schemes
+	OpenAPIComponents struct {
+		Schemas         map[string]*OpenAPISchema `json:"schemas,omitempty"`
+		SecuritySchemes map[string]interface{}    `json:"securitySchemes,omitempty"`
+	}
+
+	// OpenAPIOperation describes a method of a path
+	OpenAPIOperation struct {
+		OperationID string                     `json:"operationId"`
+		Summary     string                     `json:"summary,omitempty"`
+		Description string                     `json:"description,omitempty"`
+		Tags        []string                   `json:"tags,omitempty"`
+		Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]OpenAPIResponse `json:"responses"`
+		Security    []map[string][]string      `json:"security,omitempty"`
+		Deprecated  bool                       `json:"deprecated,omitempty"`
+	}
+
+	// OpenAPIParameter is a path or query parameter
+	OpenAPIParameter struct {
+		Name     string         `json:"name"`
+		In       string         `json:"in"`
+		Required bool           `json:"required,omitempty"`
+		Schema   *OpenAPISchema `json:"schema"`
+	}
+
+	// OpenAPIRequestBody describes the request body
+	OpenAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]OpenAPIMediaType `json:"content"`
+	}
+
+	// OpenAPIResponse describes a response
+	OpenAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	// OpenAPIMediaType describes the body of a media type
+	OpenAPIMediaType struct {
+		Schema *OpenAPISchema `json:"schema"`
+	}
+
+	// OpenAPISchema is the JSON schema subset of OpenAPI 3
+	OpenAPISchema struct {
+		Ref                  string                    `json:"$ref,omitempty"`
+		Type                 string                    `json:"type,omitempty"`
+		Format               string                    `json:"format,omitempty"`
+		Pattern              string                    `json:"pattern,omitempty"`
+		Minimum              *float64                  `json:"minimum,omitempty"`
+		Default              interface{}               `json:"default,omitempty"`
+		Items                *OpenAPISchema            `json:"items,omitempty"`
+		Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+		AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+		Required             []string                  `json:"required,omitempty"`
+	}
+
+	// OpenAPIGenerator generates the OpenAPI document of the router, it serves the document as http.Handler as well
+	OpenAPIGenerator struct {
+		router   *Router
+		document OpenAPIDocument
+		logger   flamingo.Logger
+
+		once      sync.Once
+		generated []byte
+	}
+
+	openAPISchemas struct {
+		schemas map[string]*OpenAPISchema
+		names   map[reflect.Type]string
+	}
+)
+
+// openAPIParamSchemas are the schemas of the built-in param types
+var openAPIParamSchemas = map[string]func() *OpenAPISchema{
+	"int":   func() *OpenAPISchema { return &OpenAPISchema{Type: "integer"} },
+	"uint":  func() *OpenAPISchema { return &OpenAPISchema{Type: "integer", Minimum: new(float64)} },
+	"float": func() *OpenAPISchema { return &OpenAPISchema{Type: "number"} },
+	"bool":  func() *OpenAPISchema { return &OpenAPISchema{Type: "boolean"} },
+	"date":  func() *OpenAPISchema { return &OpenAPISchema{Type: "string", Format: "date"} },
+	"uuid":  func() *OpenAPISchema { return &OpenAPISchema{Type: "string", Format: "uuid"} },
+}
+
+// WithDocumentation attaches the documentation used for the generated OpenAPI document
+func (handler *Handler) WithDocumentation(documentation RouteDocumentation) *Handler {
+	handler.documentation = &documentation
+	return handler
+}
+
+// OpenAPI adds the paths of all routes to the document, which provides the info, servers and security schemes
+func (registry *RouterRegistry) OpenAPI(document OpenAPIDocument) *OpenAPIDocument {
+	return openAPIDocument(document, map[string]*RouterRegistry{"": registry})
+}
+
+// openAPIDocument adds the paths of the registries to the document, prefixed with the path the registry is served at
+func openAPIDocument(document OpenAPIDocument, registries map[string]*RouterRegistry) *OpenAPIDocument {
+	if document.OpenAPI == "" {
+		document.OpenAPI = "3.0.3"
+	}
+	document.Paths = make(map[string]map[string]*OpenAPIOperation)
+
+	schemas := &openAPISchemas{schemas: make(map[string]*OpenAPISchema), names: make(map[reflect.Type]string)}
+	operationIDs := make(map[string]int)
+
+	prefixes := make([]string, 0, len(registries))
+	for prefix := range registries {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		registries[prefix].openAPIPaths(&document, prefix, schemas, operationIDs)
+	}
+
+	if len(schemas.schemas) > 0 {
+		if document.Components == nil {
+			document.Components = new(OpenAPIComponents)
+		}
+		document.Components.Schemas = schemas.schemas
+	}
+
+	return &document
+}
+
+func (registry *RouterRegistry) openAPIPaths(document *OpenAPIDocument, prefix string, schemas *openAPISchemas, operationIDs map[string]int) {
+	for _, route := range registry.routes {
+		action, ok := registry.handler[route.handler]
+		if !ok {
+			continue
+		}
+		documentation := route.documentation
+		if documentation == nil {
+			documentation = new(RouteDocumentation)
+		}
+
+		methods := make([]string, 0, len(action.method))
+		for method := range action.method {
+			methods = append(methods, method)
+		}
+		if len(methods) == 0 && action.any != nil {
+			methods = documentation.Methods
+			if len(methods) == 0 {
+				methods = []string{http.MethodGet}
+			}
+		}
+		sort.Strings(methods)
+
+		path, parameters := route.openAPIParameters()
+		path = strings.TrimRight(prefix, "/") + path
+
+		for _, method := range methods {
+			method = strings.ToLower(method)
+			if document.Paths[path] == nil {
+				document.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			// the first route for a path handles the request
+			if _, ok := document.Paths[path][method]; ok {
+				continue
+			}
+
+			operationID := route.handler
+			if len(methods) > 1 {
+				operationID += "." + method
+			}
+			operationIDs[operationID]++
+			if count := operationIDs[operationID]; count > 1 {
+				operationID += "." + strconv.Itoa(count)
+			}
+
+			document.Paths[path][method] = &OpenAPIOperation{
+				OperationID: operationID,
+				Summary:     documentation.Summary,
+				Description: documentation.Description,
+				Tags:        documentation.Tags,
+				Parameters:  parameters,
+				RequestBody: schemas.requestBody(documentation.Request),
+				Responses:   schemas.responses(documentation.Responses),
+				Security:    documentation.Security,
+				Deprecated:  documentation.Deprecated,
+			}
+		}
+	}
+}
+
+// openAPIParameters converts the path to the OpenAPI template syntax, e.g. `/product/{id}`, and returns the
+// path params and the query params of the handler
+func (handler *Handler) openAPIParameters() (string, []OpenAPIParameter) {
+	var path strings.Builder
+	var parameters []OpenAPIParameter
+
+	for _, p := range handler.path.parts {
+		path.WriteByte('/')
+		switch p := p.(type) {
+		case *partFixed:
+			path.WriteString(p.part)
+		case *partParam:
+			path.WriteString("{" + p.name + "}" + p.suffix)
+			schema := &OpenAPISchema{Type: "string"}
+			if paramSchema, ok := openAPIParamSchemas[p.typeName]; ok {
+				schema = paramSchema()
+			}
+			parameters = append(parameters, OpenAPIParameter{Name: p.name, In: "path", Required: true, Schema: schema})
+		case *partRegex:
+			path.WriteString("{" + p.name + "}")
+			schema := &OpenAPISchema{Type: "string", Pattern: p.regex.String()}
+			parameters = append(parameters, OpenAPIParameter{Name: p.name, In: "path", Required: true, Schema: schema})
+		case *partWildcard:
+			path.WriteString("{" + p.name + "}")
+			parameters = append(parameters, OpenAPIParameter{Name: p.name, In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"}})
+		}
+	}
+	if path.Len() == 0 || handler.path.trailingSlash {
+		path.WriteByte('/')
+	}
+
+	inPath := make(map[string]bool, len(handler.path.params))
+	for _, name := range handler.path.params {
+		inPath[name] = true
+	}
+
+	names := make([]string, 0, len(handler.params))
+	for name := range handler.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := handler.params[name]
+		// params with a fixed value, e.g. `flamingo.render(tpl="home")`, are not part of the request
+		if inPath[name] || (p.value != "" && !p.optional) {
+			continue
+		}
+		schema := &OpenAPISchema{Type: "string"}
+		if p.value != "" {
+			schema.Default = p.value
+		}
+		parameters = append(parameters, OpenAPIParameter{Name: name, In: "query", Required: !p.optional, Schema: schema})
+	}
+
+	return path.String(), parameters
+}
+
+func (s *openAPISchemas) requestBody(body interface{}) *OpenAPIRequestBody {
+	if body == nil {
+		return nil
+	}
+	return &OpenAPIRequestBody{
+		Required: true,
+		Content:  map[string]OpenAPIMediaType{"application/json": {Schema: s.schema(reflect.TypeOf(body))}},
+	}
+}
+
+func (s *openAPISchemas) responses(bodies map[int]interface{}) map[string]OpenAPIResponse {
+	if len(bodies) == 0 {
+		return map[string]OpenAPIResponse{strconv.Itoa(http.StatusOK): {Description: http.StatusText(http.StatusOK)}}
+	}
+
+	responses := make(map[string]OpenAPIResponse, len(bodies))
+	for status, body := range bodies {
+		response := OpenAPIResponse{Description: http.StatusText(status)}
+		if body != nil {
+			response.Content = map[string]OpenAPIMediaType{"application/json": {Schema: s.schema(reflect.TypeOf(body))}}
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	return responses
+}
+
+// schema of the type, named structs are added to the components and referenced
+func (s *openAPISchemas) schema(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Minimum: new(float64)}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+
+	return &OpenAPISchema{}
+}
+
+// component registers the named type, with a unique name
+func (s *openAPISchemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	for i := 2; s.schemas[name] != nil; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+	s.names[t] = name
+	// reserve the name before the properties are resolved, to support recursive types
+	s.schemas[name] = new(OpenAPISchema)
+	*s.schemas[name] = *s.object(t)
+
+	return name
+}
+
+// object schema of the exported struct fields, based on the json and validate tags
+func (s *openAPISchemas) object(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	s.fields(t, schema)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (s *openAPISchemas) fields(t reflect.Type, schema *OpenAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && tag[0] == "" && fieldType.Kind() == reflect.Struct {
+			s.fields(fieldType, schema)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = s.schema(field.Type)
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "required" {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+}
+
+// Inject dependencies
+func (g *OpenAPIGenerator) Inject(router *Router, logger flamingo.Logger, cfg *struct {
+	Title           string       `inject:"config:flamingo.web.openapi.title,optional"`
+	Description     string       `inject:"config:flamingo.web.openapi.description,optional"`
+	Version         string       `inject:"config:flamingo.web.openapi.version,optional"`
+	Servers         config.Slice `inject:"config:flamingo.web.openapi.servers,optional"`
+	SecuritySchemes config.Map   `inject:"config:flamingo.web.openapi.securitySchemes,optional"`
+}) *OpenAPIGenerator {
+	g.router = router
+	g.logger = logger
+	if cfg != nil {
+		g.document.Info = OpenAPIInfo{Title: cfg.Title, Description: cfg.Description, Version: cfg.Version}
+
+		var servers []string
+		_ = cfg.Servers.MapInto(&servers)
+		for _, server := range servers {
+			g.document.Servers = append(g.document.Servers, OpenAPIServer{URL: server})
+		}
+
+		if len(cfg.SecuritySchemes) > 0 {
+			g.document.Components = &OpenAPIComponents{SecuritySchemes: make(map[string]interface{})}
+			_ = cfg.SecuritySchemes.MapInto(&g.document.Components.SecuritySchemes)
+		}
+	}
+	return g
+}
+
+// Document generates the OpenAPI document of all routes.
+// If areas are served by the prefixrouter, the routes of each area are documented with the prefix of the area.
+// The routes are registered in new registries, so the registry of the running router is not changed.
+func (g *OpenAPIGenerator) Document() *OpenAPIDocument {
+	registries, err := g.registries()
+	if err != nil {
+		g.logger.Error("openapi: ", err)
+		registries = map[string]*RouterRegistry{"": g.router.newRegistry()}
+	}
+
+	return openAPIDocument(g.document, registries)
+}
+
+// registries returns a registry per prefixrouter prefix, or the registry of the router if no area has a prefix
+func (g *OpenAPIGenerator) registries() (map[string]*RouterRegistry, error) {
+	if g.router.configArea == nil {
+		return map[string]*RouterRegistry{"": g.router.newRegistry()}, nil
+	}
+
+	areas, err := g.router.configArea.Flat()
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes := areaPrefixes(areas)
+	if len(prefixes) == 0 {
+		return map[string]*RouterRegistry{"": g.router.newRegistry()}, nil
+	}
+
+	names := make([]string, 0, len(prefixes))
+	for name := range prefixes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registries := make(map[string]*RouterRegistry, len(prefixes))
+	for _, name := range names {
+		// the host of a prefix is not part of the OpenAPI path, the first area serving a path is documented
+		prefix := prefixes[name]
+		prefix = prefix[strings.Index(prefix, "/"):]
+		if _, ok := registries[prefix]; ok {
+			continue
+		}
+
+		if name == g.router.configArea.Name {
+			registries[prefix] = g.router.newRegistry()
+			continue
+		}
+
+		injector, err := areas[name].GetInitializedInjector()
+		if err != nil {
+			return nil, err
+		}
+		router, err := injector.GetInstance(Router{})
+		if err != nil {
+			return nil, err
+		}
+
+		registries[prefix] = router.(*Router).newRegistry()
+	}
+
+	return registries, nil
+}
+
+// ServeHTTP serves the OpenAPI document as JSON, it is generated once
+func (g *OpenAPIGenerator) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	g.once.Do(func() {
+		var err error
+		g.generated, err = json.Marshal(g.Document())
+		if err != nil {
+			g.logger.Error("openapi: ", err)
+		}
+	})
+
+	if g.generated == nil {
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, _ = rw.Write(g.generated)
+}
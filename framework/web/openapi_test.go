@@ -0,0 +1,175 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	openAPITestProduct struct {
+		ID       int                  `json:"id"`
+		Name     string               `json:"name" validate:"required"`
+		Tags     []string             `json:"tags,omitempty"`
+		Created  time.Time            `json:"created"`
+		Variants []openAPITestProduct `json:"variants"`
+		internal string
+		Ignored  string `json:"-"`
+		openAPITestMeta
+	}
+
+	openAPITestMeta struct {
+		Source string
+	}
+)
+
+func openAPITestRegistry(t *testing.T) *RouterRegistry {
+	t.Helper()
+
+	registry := NewRegistry()
+	openAPITestRoutes(registry)
+
+	return registry
+}
+
+func openAPITestRoutes(registry *RouterRegistry) {
+	registry.HandleGet("product.view", testController)
+	registry.HandlePut("product.view", testController)
+	registry.HandleAny("search", testController)
+	registry.HandleAny("flamingo.render", testController)
+	registry.HandleData("data.only", nil)
+
+	registry.MustRoute("/product/:id<int>/:slug.html", "product.view").WithDocumentation(RouteDocumentation{
+		Summary:   "Product",
+		Tags:      []string{"catalog"},
+		Request:   openAPITestProduct{},
+		Responses: map[int]interface{}{http.StatusOK: new(openAPITestProduct), http.StatusNotFound: nil},
+		Security:  []map[string][]string{{"bearer": {}}},
+	})
+	registry.MustRoute("/search/$term<[a-z]+>", "search(term, page?=\"1\", sort)")
+	registry.MustRoute("/", `flamingo.render(tpl="home")`)
+	registry.MustRoute("/data", "data.only")
+	registry.MustRoute("/unknown", "unknown")
+}
+
+func TestRouterRegistry_OpenAPI(t *testing.T) {
+	document := openAPITestRegistry(t).OpenAPI(OpenAPIDocument{Info: OpenAPIInfo{Title: "Shop", Version: "1"}})
+
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Equal(t, "Shop", document.Info.Title)
+	assert.Len(t, document.Paths, 3)
+
+	t.Run("methods and documentation", func(t *testing.T) {
+		product := document.Paths["/product/{id}/{slug}.html"]
+		require.Len(t, product, 2)
+
+		get := product["get"]
+		require.NotNil(t, get)
+		assert.Equal(t, "product.view.get", get.OperationID)
+		assert.Equal(t, "product.view.put", product["put"].OperationID)
+		assert.Equal(t, "Product", get.Summary)
+		assert.Equal(t, []string{"catalog"}, get.Tags)
+		assert.Equal(t, []map[string][]string{{"bearer": {}}}, get.Security)
+		assert.Equal(t, []OpenAPIParameter{
+			{Name: "id", In: "path", Required: true, Schema: &OpenAPISchema{Type: "integer"}},
+			{Name: "slug", In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"}},
+		}, get.Parameters)
+
+		require.NotNil(t, get.RequestBody)
+		assert.Equal(t, "#/components/schemas/openAPITestProduct", get.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, "#/components/schemas/openAPITestProduct", get.Responses["200"].Content["application/json"].Schema.Ref)
+		assert.Equal(t, OpenAPIResponse{Description: "Not Found"}, get.Responses["404"])
+	})
+
+	t.Run("any handlers and query params", func(t *testing.T) {
+		search := document.Paths["/search/{term}"]
+		require.Len(t, search, 1)
+		assert.Equal(t, "search", search["get"].OperationID)
+		assert.Equal(t, []OpenAPIParameter{
+			{Name: "term", In: "path", Required: true, Schema: &OpenAPISchema{Type: "string", Pattern: "^[a-z]+"}},
+			{Name: "page", In: "query", Required: false, Schema: &OpenAPISchema{Type: "string", Default: "1"}},
+			{Name: "sort", In: "query", Required: true, Schema: &OpenAPISchema{Type: "string"}},
+		}, search["get"].Parameters)
+		assert.Equal(t, map[string]OpenAPIResponse{"200": {Description: "OK"}}, search["get"].Responses)
+
+		home := document.Paths["/"]
+		require.NotNil(t, home["get"])
+		assert.Empty(t, home["get"].Parameters, "fixed params are not part of the request")
+	})
+
+	t.Run("schemas", func(t *testing.T) {
+		require.NotNil(t, document.Components)
+		product := document.Components.Schemas["openAPITestProduct"]
+		require.NotNil(t, product)
+
+		assert.Equal(t, "object", product.Type)
+		assert.Equal(t, []string{"name"}, product.Required)
+		assert.Equal(t, &OpenAPISchema{Type: "integer", Format: "int32"}, product.Properties["id"])
+		assert.Equal(t, &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "string"}}, product.Properties["tags"])
+		assert.Equal(t, &OpenAPISchema{Type: "string", Format: "date-time"}, product.Properties["created"])
+		assert.Equal(t, "#/components/schemas/openAPITestProduct", product.Properties["variants"].Items.Ref)
+		assert.Equal(t, &OpenAPISchema{Type: "string"}, product.Properties["Source"], "embedded fields are flattened")
+		assert.Len(t, product.Properties, 6)
+	})
+}
+
+func TestOpenAPIGenerator(t *testing.T) {
+	router := &Router{
+		routesProvider: func() []RoutesModule { return []RoutesModule{testRoutesModule(openAPITestRoutes)} },
+	}
+	generator := new(OpenAPIGenerator).Inject(router, flamingo.NullLogger{}, nil)
+	generator.document.Info.Title = "Shop"
+
+	t.Run("router registry", func(t *testing.T) {
+		generator.Document()
+		assert.Nil(t, router.routerRegistry, "the registry of the router must not be replaced")
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		generator.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		document := new(OpenAPIDocument)
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), document))
+		assert.Equal(t, "Shop", document.Info.Title)
+		assert.Contains(t, document.Paths, "/search/{term}")
+	})
+
+	t.Run("command", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		cmd := OpenAPICmd(generator)
+		cmd.SetOut(out)
+		cmd.SetArgs(nil)
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, out.String(), `"operationId": "product.view.get"`)
+	})
+}
+
+func TestOpenAPIDocument_Prefixes(t *testing.T) {
+	shop := NewRegistry()
+	shop.HandleGet("product.view", testController)
+	shop.MustRoute("/product/:id", "product.view")
+
+	api := NewRegistry()
+	api.HandleGet("product.view", testController)
+	api.MustRoute("/product/:id", "product.view")
+	api.MustRoute("/", "product.view")
+
+	document := openAPIDocument(OpenAPIDocument{}, map[string]*RouterRegistry{"/": shop, "/api": api})
+
+	assert.Len(t, document.Paths, 3)
+	assert.Contains(t, document.Paths, "/product/{id}")
+	assert.Contains(t, document.Paths, "/api/product/{id}")
+	assert.Contains(t, document.Paths, "/api/")
+	assert.Equal(t, "product.view", document.Paths["/product/{id}"]["get"].OperationID)
+	assert.Equal(t, "product.view.2", document.Paths["/api/product/{id}"]["get"].OperationID)
+}
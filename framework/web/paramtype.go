@@ -0,0 +1,169 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"flamingo.me/dingo"
+)
+
+type (
+	// ParamType validates and converts typed route parameters, such as `/product/:id<int>`
+	ParamType interface {
+		// Parse converts the raw value, an error marks the value as invalid for this type
+		Parse(value string) (interface{}, error)
+	}
+
+	// ParamTypeFunc is a function which can be used as a ParamType
+	ParamTypeFunc func(value string) (interface{}, error)
+
+	paramTypeProvider func() map[string]ParamType
+
+	// ParamError is returned if a route parameter does not match the declared type
+	ParamError struct {
+		Name  string
+		Type  string
+		Value string
+		Err   error
+	}
+)
+
+var (
+	// ErrParamNotFound is returned by the typed RequestParams getters for unknown params
+	ErrParamNotFound = errors.New("param not found")
+
+	// builtinParamTypes are available for all routes, bound param types take precedence
+	builtinParamTypes = map[string]ParamType{
+		"int":   ParamTypeFunc(func(value string) (interface{}, error) { return strconv.Atoi(value) }),
+		"uint":  ParamTypeFunc(func(value string) (interface{}, error) { return strconv.ParseUint(value, 10, 64) }),
+		"float": ParamTypeFunc(func(value string) (interface{}, error) { return strconv.ParseFloat(value, 64) }),
+		"bool":  ParamTypeFunc(func(value string) (interface{}, error) { return strconv.ParseBool(value) }),
+		"date":  ParamTypeFunc(func(value string) (interface{}, error) { return time.Parse("2006-01-02", value) }),
+		"uuid":  ParamTypeFunc(parseUUID),
+	}
+
+	uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Parse calls the ParamTypeFunc
+func (f ParamTypeFunc) Parse(value string) (interface{}, error) {
+	return f(value)
+}
+
+func parseUUID(value string) (interface{}, error) {
+	if !uuidRegex.MatchString(value) {
+		return nil, errors.New("invalid uuid")
+	}
+	return value, nil
+}
+
+// BindParamType binds a custom param type for routes, e.g. `:sku<sku>`
+func BindParamType(injector *dingo.Injector, name string) *dingo.Binding {
+	return injector.BindMap(new(ParamType), name)
+}
+
+// lookupParamType returns the param type from the bound param types, or the built-in types
+func lookupParamType(paramTypes map[string]ParamType, name string) (ParamType, error) {
+	if paramType, ok := paramTypes[name]; ok {
+		return paramType, nil
+	}
+	if paramType, ok := builtinParamTypes[name]; ok {
+		return paramType, nil
+	}
+	return nil, fmt.Errorf("param type %q is not registered", name)
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("param %s: %q is not a valid %s", e.Name, e.Value, e.Type)
+}
+
+// Unwrap returns the type conversion error
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Parse converts the param with the given built-in param type, Request.ParseParam supports bound param types as well
+func (p RequestParams) Parse(name, paramType string) (interface{}, error) {
+	return p.parse(nil, name, paramType)
+}
+
+// ParseParam converts the request param with the given param type, bound via BindParamType or built-in
+func (r *Request) ParseParam(name, paramType string) (interface{}, error) {
+	return r.Params.parse(r.paramTypes, name, paramType)
+}
+
+func (p RequestParams) parse(paramTypes map[string]ParamType, name, paramType string) (interface{}, error) {
+	value, ok := p[name]
+	if !ok {
+		return nil, ErrParamNotFound
+	}
+
+	t, err := lookupParamType(paramTypes, paramType)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := t.Parse(value)
+	if err != nil {
+		return nil, &ParamError{Name: name, Type: paramType, Value: value, Err: err}
+	}
+
+	return converted, nil
+}
+
+// Int returns the param converted to an int
+func (p RequestParams) Int(name string) (int, error) {
+	v, err := p.Parse(name, "int")
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// Uint returns the param converted to an uint64
+func (p RequestParams) Uint(name string) (uint64, error) {
+	v, err := p.Parse(name, "uint")
+	if err != nil {
+		return 0, err
+	}
+	return v.(uint64), nil
+}
+
+// Float returns the param converted to a float64
+func (p RequestParams) Float(name string) (float64, error) {
+	v, err := p.Parse(name, "float")
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// Bool returns the param converted to a bool
+func (p RequestParams) Bool(name string) (bool, error) {
+	v, err := p.Parse(name, "bool")
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Date returns the param converted from the format 2006-01-02
+func (p RequestParams) Date(name string) (time.Time, error) {
+	v, err := p.Parse(name, "date")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.(time.Time), nil
+}
+
+// UUID returns the param if it is a valid UUID
+func (p RequestParams) UUID(name string) (string, error) {
+	v, err := p.Parse(name, "uuid")
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
@@ -0,0 +1,194 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func TestTypedParams(t *testing.T) {
+	t.Run("Path matching", func(t *testing.T) {
+		path, err := NewPath(`/product/:id<int>/view`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id"}, path.params)
+		assert.NotNil(t, path.Match(`/product/123/view`))
+		assert.Equal(t, "123", path.Match(`/product/123/view`).Values["id"])
+		assert.Nil(t, path.Match(`/product/abc/view`))
+
+		path, err = NewPath(`/day/:date<date>.html`)
+		require.NoError(t, err)
+		assert.Equal(t, "2020-02-29", path.Match(`/day/2020-02-29.html`).Values["date"])
+		assert.Nil(t, path.Match(`/day/2020-02-30.html`))
+
+		path, err = NewPath(`/sku/:sku<uuid>`)
+		require.NoError(t, err)
+		assert.NotNil(t, path.Match(`/sku/0f8fad5b-d9cb-469f-a165-70867728950e`))
+		assert.Nil(t, path.Match(`/sku/0f8fad5b`))
+
+		_, err = NewPath(`/product/:id<unknown>`)
+		assert.Error(t, err)
+
+		_, err = NewPath(`/product/:id<int`)
+		assert.Error(t, err)
+	})
+
+	t.Run("Custom param type", func(t *testing.T) {
+		even := ParamTypeFunc(func(value string) (interface{}, error) {
+			if len(value)%2 != 0 {
+				return nil, errors.New("odd")
+			}
+			return strings.ToUpper(value), nil
+		})
+
+		_, err := NewPath(`/:code<even>`)
+		assert.Error(t, err)
+
+		path, err := newPath(`/:code<even>`, map[string]ParamType{"even": even})
+		require.NoError(t, err)
+		assert.NotNil(t, path.Match(`/ab`))
+		assert.Nil(t, path.Match(`/abc`))
+
+		registry := NewRegistry()
+		registry.paramTypes = map[string]ParamType{"even": even}
+		registry.HandleAny("code", testController)
+		_, err = registry.Route("/:code<even>", "code")
+		require.NoError(t, err)
+
+		_, params, handler := registry.matchRequest(httptest.NewRequest(http.MethodGet, "/ab", nil))
+		require.NotNil(t, handler)
+		assert.Equal(t, "ab", params["code"])
+
+		_, err = RequestParams{"code": "ab"}.Parse("code", "even")
+		assert.Error(t, err, "RequestParams.Parse only knows the built-in param types")
+
+		request := &Request{Params: RequestParams{"code": "ab", "id": "1"}, paramTypes: registry.paramTypes}
+		code, err := request.ParseParam("code", "even")
+		require.NoError(t, err)
+		assert.Equal(t, "AB", code)
+		id, err := request.ParseParam("id", "int")
+		require.NoError(t, err)
+		assert.Equal(t, 1, id)
+		_, err = request.ParseParam("id", "even")
+		assert.IsType(t, new(ParamError), err)
+	})
+
+	t.Run("Typed accessors", func(t *testing.T) {
+		params := RequestParams{"id": "12", "price": "1.5", "flag": "true", "date": "2020-01-02", "name": "foo"}
+
+		i, err := params.Int("id")
+		assert.NoError(t, err)
+		assert.Equal(t, 12, i)
+
+		f, err := params.Float("price")
+		assert.NoError(t, err)
+		assert.Equal(t, 1.5, f)
+
+		b, err := params.Bool("flag")
+		assert.NoError(t, err)
+		assert.True(t, b)
+
+		d, err := params.Date("date")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), d)
+
+		_, err = params.Int("name")
+		var paramErr *ParamError
+		assert.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "name", paramErr.Name)
+		assert.Equal(t, "int", paramErr.Type)
+
+		_, err = params.Int("unknown")
+		assert.Equal(t, ErrParamNotFound, err)
+	})
+
+	t.Run("Route fallthrough and reverse routing", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.HandleAny("product.id", testController)
+		registry.HandleAny("product.slug", testController)
+		_, err := registry.Route("/product/:id<int>", "product.id")
+		require.NoError(t, err)
+		_, err = registry.Route("/product/:slug", "product.slug")
+		require.NoError(t, err)
+
+		request, _ := http.NewRequest(http.MethodGet, "/product/12", nil)
+		_, params, handler := registry.matchRequest(request)
+		require.NotNil(t, handler)
+		assert.Equal(t, "product.id", handler.GetHandlerName())
+		assert.Equal(t, "12", params["id"])
+
+		request, _ = http.NewRequest(http.MethodGet, "/product/foo", nil)
+		_, params, handler = registry.matchRequest(request)
+		require.NotNil(t, handler)
+		assert.Equal(t, "product.slug", handler.GetHandlerName())
+		assert.Equal(t, "foo", params["slug"])
+
+		p, err := registry.Reverse("product.id", map[string]string{"id": "12"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/product/12", p)
+
+		_, err = registry.Reverse("product.id", map[string]string{"id": "foo"})
+		assert.EqualError(t, err, "param id in wrong format")
+	})
+
+	t.Run("Invalid params result in a bad request", func(t *testing.T) {
+		router := &Router{
+			eventRouter:    new(flamingo.DefaultEventRouter),
+			filterProvider: func() []Filter { return nil },
+			routesProvider: func() []RoutesModule { return nil },
+			logger:         flamingo.NullLogger{},
+		}
+		h := router.Handler()
+
+		registry := NewRegistry()
+		h.(*handler).routerRegistry = registry
+		registry.HandleGet("product", func(context.Context, *Request) Result { return &Response{Status: http.StatusOK} })
+		_, err := registry.Route("/product/:id<int>", "product")
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/product/12", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		recorder = httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/product/foo", nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Param errors are found in the route tree", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.HandleGet("product", testController)
+		registry.HandlePost("variant", testController)
+		registry.HandleGet("variant", testController)
+		_, err := registry.Route("/product/:id<int>/:page<uint>", "product")
+		require.NoError(t, err)
+		_, err = registry.Route("/product/:id/variant/:variant<int>", "variant")
+		require.NoError(t, err)
+
+		err = registry.matchParamError(httptest.NewRequest(http.MethodGet, "/product/foo/-1", nil))
+		var paramErr *ParamError
+		require.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "id", paramErr.Name)
+		assert.Equal(t, "foo", paramErr.Value)
+
+		err = registry.matchParamError(httptest.NewRequest(http.MethodGet, "/product/12/-1", nil))
+		require.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "page", paramErr.Name)
+
+		err = registry.matchParamError(httptest.NewRequest(http.MethodPost, "/product/12/variant/x", nil))
+		require.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "variant", paramErr.Name)
+
+		assert.NoError(t, registry.matchParamError(httptest.NewRequest(http.MethodPost, "/product/12/-1", nil)))
+		assert.NoError(t, registry.matchParamError(httptest.NewRequest(http.MethodGet, "/product/12/1", nil)))
+		assert.NoError(t, registry.matchParamError(httptest.NewRequest(http.MethodGet, "/category/foo", nil)))
+	})
+}
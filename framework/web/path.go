@@ -37,6 +37,8 @@ type (
 
 	partParam struct {
 		name, suffix string
+		typeName     string
+		paramType    ParamType
 	}
 
 	partRegex struct {
@@ -81,16 +83,16 @@ func (p *partFixed) render(values map[string]string, normalize map[string]struct
 func (p *partParam) read(path string) (string, string, error) {
 	parts := strings.SplitN(path, "/", 2)
 
-	if len(parts) > 0 {
-		p.name = parts[0][1:]
+	segment, err := p.readType(parts[0][1:])
+	if err != nil {
+		return "", "", err
 	}
+	p.name = segment
 
 	if len(parts) > 1 {
 		return `/` + parts[1], p.name, nil
 	}
 
-	p.name = path[1:]
-
 	parts = strings.SplitN(p.name, ".", 2)
 	if len(parts) > 1 {
 		p.suffix = `.` + parts[1]
@@ -100,7 +102,32 @@ func (p *partParam) read(path string) (string, string, error) {
 	return "", p.name, nil
 }
 
+// readType extracts an optional type declaration, e.g. `id<int>`
+func (p *partParam) readType(segment string) (string, error) {
+	start := strings.IndexByte(segment, '<')
+	if start < 0 {
+		return segment, nil
+	}
+
+	end := strings.IndexByte(segment[start:], '>')
+	if end < 0 {
+		return "", fmt.Errorf("param type for %q not closed", segment)
+	}
+
+	p.typeName = segment[start+1 : start+end]
+
+	return segment[:start] + segment[start+end+1:], nil
+}
+
 func (p *partParam) match(path string) (matched bool, key, value string, length int) {
+	matched, key, value, length = p.matchUntyped(path)
+	if matched && !p.valid(value) {
+		return false, "", "", 0
+	}
+	return matched, key, value, length
+}
+
+func (p *partParam) matchUntyped(path string) (matched bool, key, value string, length int) {
 	parts := strings.SplitN(path, "/", 2)
 
 	if len(parts) < 1 {
@@ -115,11 +142,28 @@ func (p *partParam) match(path string) (matched bool, key, value string, length
 	return true, p.name, val, len(parts[0])
 }
 
+func (p *partParam) valid(value string) bool {
+	if p.paramType == nil {
+		return true
+	}
+	_, err := p.paramType.Parse(value)
+	return err == nil
+}
+
+// paramError returns the *ParamError of an invalid value
+func (p *partParam) paramError(value string) *ParamError {
+	_, err := p.paramType.Parse(value)
+	return &ParamError{Name: p.name, Type: p.typeName, Value: value, Err: err}
+}
+
 func (p *partParam) render(values map[string]string, normalize map[string]struct{}) (string, []string, error) {
 	if value, ok := values[p.name]; ok {
 		if _, ok := normalize[p.name]; ok {
 			value = URLTitle(value)
 		}
+		if !p.valid(value) {
+			return "", []string{}, errors.New("param " + p.name + " in wrong format")
+		}
 		return url.QueryEscape(value) + p.suffix, []string{p.name}, nil
 	}
 	return "", []string{}, errors.New("param " + p.name + " not found")
@@ -189,8 +233,13 @@ func (p *partWildcard) render(values map[string]string, normalize map[string]str
 	return "", []string{}, nil
 }
 
-// NewPath returns a new path
+// NewPath returns a new path, typed params can use the built-in param types
 func NewPath(path string) (*Path, error) {
+	return newPath(path, nil)
+}
+
+// newPath returns a new path, typed params can use the given or the built-in param types
+func newPath(path string, paramTypes map[string]ParamType) (*Path, error) {
 	var newPath = &Path{
 		path:          path,
 		trailingSlash: strings.HasSuffix(path, "/"),
@@ -208,12 +257,17 @@ func NewPath(path string) (*Path, error) {
 
 		switch path[0] {
 		case ':':
-			current = new(partParam)
-			path, param, err = current.read(path)
+			typed := new(partParam)
+			path, param, err = typed.read(path)
 			if err != nil {
 				return nil, err
 			}
-			newPath.parts = append(newPath.parts, current)
+			if typed.typeName != "" {
+				if typed.paramType, err = lookupParamType(paramTypes, typed.typeName); err != nil {
+					return nil, err
+				}
+			}
+			newPath.parts = append(newPath.parts, typed)
 
 		case '$':
 			current = new(partRegex)
@@ -0,0 +1,153 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type (
+	// Problem is a RFC 7807 problem details object, rendered as application/problem+json
+	Problem struct {
+		Type     string
+		Title    string
+		Status   uint
+		Detail   string
+		Instance string
+		// Extensions are additional members, they can not override the standard members
+		Extensions map[string]interface{}
+	}
+
+	// ProblemType identifies a class of problems, e.g. `https://example.com/problems/out-of-stock`
+	ProblemType struct {
+		URI   string
+		Title string
+	}
+)
+
+const (
+	// MediaTypeProblemJSON is used for RFC 7807 problem details
+	MediaTypeProblemJSON = "application/problem+json"
+
+	contextProblemDetails contextKeyType = "problemDetails"
+)
+
+// MarshalJSON flattens the extension members into the problem object
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for name, value := range p.Extensions {
+		members[name] = value
+	}
+
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	} else {
+		delete(members, "detail")
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	} else {
+		delete(members, "instance")
+	}
+
+	return json.Marshal(members)
+}
+
+// WithProblemDetails renders all error responses of this route as application/problem+json, regardless of the Accept header
+func (handler *Handler) WithProblemDetails() *Handler {
+	handler.problemDetails = true
+	return handler
+}
+
+// AsProblem renders the error as application/problem+json, regardless of the Accept header
+func (r *ServerErrorResponse) AsProblem() *ServerErrorResponse {
+	r.asProblem = true
+	return r
+}
+
+// Type sets the problem type, used if the error is rendered as problem details
+func (r *ServerErrorResponse) Type(problemType ProblemType) *ServerErrorResponse {
+	r.problemType = problemType
+	return r
+}
+
+// Extension adds an extension member, used if the error is rendered as problem details
+func (r *ServerErrorResponse) Extension(name string, value interface{}) *ServerErrorResponse {
+	if r.extensions == nil {
+		r.extensions = make(map[string]interface{})
+	}
+	r.extensions[name] = value
+	return r
+}
+
+// Problem returns the problem details of the error response
+func (r *ServerErrorResponse) Problem(ctx context.Context) Problem {
+	status := r.RenderResponse.DataResponse.Response.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	problem := Problem{
+		Type:       r.problemType.URI,
+		Title:      r.problemType.Title,
+		Status:     status,
+		Detail:     r.ErrString,
+		Extensions: r.extensions,
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(int(status))
+	}
+	if req := RequestFromContext(ctx); req != nil {
+		problem.Instance = req.Request().URL.RequestURI()
+	}
+
+	return problem
+}
+
+// problemDetails decides if the error is rendered as problem details, either forced for the response or the route,
+// or negotiated because the client explicitly accepts application/problem+json
+func (r *ServerErrorResponse) problemDetails(ctx context.Context) (problem bool, negotiated bool) {
+	if r.asProblem {
+		return true, false
+	}
+	if forced, _ := ctx.Value(contextProblemDetails).(bool); forced {
+		return true, false
+	}
+
+	req := RequestFromContext(ctx)
+	if req == nil {
+		return false, false
+	}
+	accepted, _ := parseAccept(req.Request().Header.Get("Accept"))
+	for _, mediaType := range accepted {
+		if mediaType.mediaType == MediaTypeProblemJSON {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (r *ServerErrorResponse) applyProblem(ctx context.Context, w http.ResponseWriter, negotiated bool) error {
+	body, err := json.Marshal(r.Problem(ctx))
+	if err != nil {
+		return err
+	}
+
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	if negotiated {
+		r.Header.Add("Vary", "Accept")
+	}
+	r.Header.Set("Content-Type", contentType(MediaTypeProblemJSON))
+	r.Body = bytes.NewReader(body)
+
+	return r.Response.Apply(ctx, w)
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func TestProblemMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Problem{Title: "Not Found", Status: 404, Extensions: map[string]interface{}{"sku": "1", "status": 200}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404, "sku": "1"}`, string(b))
+}
+
+func TestServerErrorResponseProblem(t *testing.T) {
+	responder := &Responder{logger: flamingo.NullLogger{}, encoders: newEncoders(MediaTypeJSON, nil)}
+
+	apply := func(t *testing.T, ctx context.Context, result Result, accept string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		request := httptest.NewRequest(http.MethodGet, "/product/1?a=b", nil)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		recorder := httptest.NewRecorder()
+		require.NoError(t, result.Apply(ContextWithRequest(ctx, CreateRequest(request, nil)), recorder))
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder, body
+	}
+
+	t.Run("negotiated", func(t *testing.T) {
+		recorder, body := apply(t, context.Background(), responder.NotFound(errors.New("product 1 not found")), "application/problem+json, application/json;q=0.9")
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, "application/problem+json; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+		assert.Equal(t, map[string]interface{}{
+			"type":     "about:blank",
+			"title":    "Not Found",
+			"status":   float64(404),
+			"detail":   "product 1 not found",
+			"instance": "/product/1?a=b",
+		}, body)
+
+		recorder, body = apply(t, context.Background(), responder.NotFound(errors.New("product 1 not found")), "application/json")
+		assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "product 1 not found", body["error"])
+	})
+
+	t.Run("type and extensions", func(t *testing.T) {
+		outOfStock := ProblemType{URI: "https://example.com/problems/out-of-stock", Title: "Out of stock"}
+		response := responder.Forbidden(errors.New("not available")).Type(outOfStock).Extension("sku", "1").AsProblem()
+
+		_, body := apply(t, context.Background(), response, "text/html")
+		assert.Equal(t, "https://example.com/problems/out-of-stock", body["type"])
+		assert.Equal(t, "Out of stock", body["title"])
+		assert.Equal(t, float64(http.StatusForbidden), body["status"])
+		assert.Equal(t, "1", body["sku"])
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		err := &ValidationError{Fields: []FieldError{newFieldError("name", "required", "", "is required")}}
+		_, body := apply(t, context.Background(), responder.UnprocessableEntity(err), MediaTypeProblemJSON)
+		assert.Equal(t, float64(http.StatusUnprocessableEntity), body["status"])
+		assert.Len(t, body["fields"], 1)
+	})
+
+	t.Run("route", func(t *testing.T) {
+		router := &Router{
+			eventRouter:    new(flamingo.DefaultEventRouter),
+			filterProvider: func() []Filter { return nil },
+			routesProvider: func() []RoutesModule { return nil },
+			logger:         flamingo.NullLogger{},
+		}
+		h := router.Handler()
+		registry := NewRegistry()
+		h.(*handler).routerRegistry = registry
+
+		registry.HandleAny(FlamingoError, func(ctx context.Context, req *Request) Result {
+			return responder.ServerError(ctx.Value(RouterError).(error))
+		})
+		registry.HandleGet("api", func(ctx context.Context, req *Request) Result {
+			panic("broken")
+		})
+		registry.MustRoute("/api", "api").WithProblemDetails()
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api", nil))
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, "application/problem+json; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.Empty(t, recorder.Header().Get("Vary"))
+	})
+}
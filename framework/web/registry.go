@@ -1,10 +1,13 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"flamingo.me/dingo"
 )
@@ -22,6 +25,12 @@ type (
 		handler map[string]handlerAction
 		routes  []*Handler
 		alias   map[string]*Handler
+
+		// paramTypes are the bound param types, in addition to the built-in types
+		paramTypes map[string]ParamType
+
+		treeMu sync.RWMutex
+		tree   *routeTree
 	}
 
 	// Handler defines a concrete Controller
@@ -30,6 +39,18 @@ type (
 		handler  string
 		params   map[string]*param
 		catchall bool
+		filters  []Filter
+		timeout  *time.Duration
+
+		problemDetails bool
+		documentation  *RouteDocumentation
+	}
+
+	// RouteGroup registers routes with a shared path prefix and shared filters
+	RouteGroup struct {
+		registry *RouterRegistry
+		prefix   string
+		filters  []Filter
 	}
 
 	handlerAction struct {
@@ -44,7 +65,7 @@ type (
 		match         *Match
 	}
 
-	matchedHandlers []*matchedHandler
+	matchedHandlers []matchedHandler
 
 	param struct {
 		value    string
@@ -86,9 +107,9 @@ func (ha *handlerAction) setData(data DataAction) {
 }
 
 func (mh matchedHandlers) getHandleAny() *matchedHandler {
-	for _, matched := range mh {
-		if matched.handlerAction.any != nil {
-			return matched
+	for i := range mh {
+		if mh[i].handlerAction.any != nil {
+			return &mh[i]
 		}
 	}
 
@@ -193,7 +214,7 @@ func (registry *RouterRegistry) Route(path, handler string) (*Handler, error) {
 	var h = parseHandler(handler)
 	var err error
 
-	h.path, err = NewPath(path)
+	h.path, err = newPath(path, registry.paramTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -202,10 +223,48 @@ func (registry *RouterRegistry) Route(path, handler string) (*Handler, error) {
 		h.params, h.catchall = parseParams(strings.Join(h.path.params, ", "))
 	}
 
+	registry.treeMu.Lock()
 	registry.routes = append(registry.routes, h)
+	registry.tree = nil
+	registry.treeMu.Unlock()
+
 	return h, nil
 }
 
+// Group creates a RouteGroup, all routes registered via the group are prefixed with the prefix
+// and run the filters after the global filters
+func (registry *RouterRegistry) Group(prefix string, filters ...Filter) *RouteGroup {
+	return &RouteGroup{
+		registry: registry,
+		prefix:   strings.TrimRight(prefix, "/"),
+		filters:  filters,
+	}
+}
+
+// Group creates a nested RouteGroup, which inherits the prefix and filters of the parent group
+func (group *RouteGroup) Group(prefix string, filters ...Filter) *RouteGroup {
+	return &RouteGroup{
+		registry: group.registry,
+		prefix:   group.prefix + strings.TrimRight(prefix, "/"),
+		filters:  append(group.filters[:len(group.filters):len(group.filters)], filters...),
+	}
+}
+
+// Route assigns a prefixed route to a Handler, the group's filters are attached to the route
+func (group *RouteGroup) Route(path, handler string) (*Handler, error) {
+	h, err := group.registry.Route(group.prefix+path, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.WithFilters(group.filters...), nil
+}
+
+// MustRoute makes a checked Route call
+func (group *RouteGroup) MustRoute(path, handler string) *Handler {
+	return MustRoute(group.Route(path, handler))
+}
+
 // GetRoutes returns registered Routes
 func (registry *RouterRegistry) GetRoutes() []*Handler {
 	return registry.routes
@@ -412,26 +471,72 @@ catchallrouteloop:
 	return "", fmt.Errorf("reverse for %q not found, parameters: %v", name, params)
 }
 
+// routeTree returns the compiled route index, it is (re)built lazily after routes have been added
+func (registry *RouterRegistry) routeTree() *routeTree {
+	registry.treeMu.RLock()
+	tree := registry.tree
+	registry.treeMu.RUnlock()
+
+	if tree != nil {
+		return tree
+	}
+
+	registry.treeMu.Lock()
+	defer registry.treeMu.Unlock()
+
+	if registry.tree == nil {
+		registry.tree = newRouteTree(registry.routes)
+	}
+
+	return registry.tree
+}
+
+// matchPath returns all handlers matching the path, in the order of their registration
+func (registry *RouterRegistry) matchPath(path string) matchedHandlers {
+	tree := registry.routeTree()
+	matches := tree.match(path, false)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	matchedHandlers := make(matchedHandlers, len(matches))
+	for i, m := range matches {
+		handler := tree.routes[m.index]
+		matchedHandlers[i] = matchedHandler{
+			handlerAction: registry.handler[handler.handler],
+			handler:       handler,
+			match:         &Match{Values: m.values},
+		}
+	}
+
+	return matchedHandlers
+}
+
 // Match a request path
 func (registry *RouterRegistry) match(path string) (handler handlerAction, params map[string]string) {
-	for _, route := range registry.routes {
-		if match := route.path.Match(path); match != nil {
-			handler = registry.handler[route.handler]
-			params = make(map[string]string)
-			for k, param := range route.params {
-				params[k] = param.value
-			}
-			for k, v := range match.Values {
-				params[k] = v
-			}
-			return
-		}
+	matched := registry.matchPath(path)
+	if len(matched) == 0 {
+		return
+	}
+
+	handler = matched[0].handlerAction
+	params = make(map[string]string)
+	for k, param := range matched[0].handler.params {
+		params[k] = param.value
+	}
+	for k, v := range matched[0].match.Values {
+		params[k] = v
 	}
 	return
 }
 
 // matchRequest matches a http Request (with query and path parameters)
 func (registry *RouterRegistry) matchRequest(req *http.Request) (handlerAction, map[string]string, *Handler) {
+	return registry.traceRequest(req, nil)
+}
+
+// traceRequest matches the request like matchRequest, the trace records why the matching routes are selected or skipped
+func (registry *RouterRegistry) traceRequest(req *http.Request, trace *routeTrace) (handlerAction, map[string]string, *Handler) {
 	var path = req.URL.Path
 	if req.URL.RawPath != "" {
 		path = req.URL.RawPath
@@ -439,34 +544,21 @@ func (registry *RouterRegistry) matchRequest(req *http.Request) (handlerAction,
 
 	path = "/" + strings.TrimLeft(path, "/")
 
-	var matchedHandlers matchedHandlers
-	for _, handler := range registry.routes {
-		if match := handler.path.Match(path); match != nil {
-			controller := registry.handler[handler.handler]
-			matchedHandler := &matchedHandler{
-				handlerAction: controller,
-				handler:       handler,
-				match:         match,
-			}
-			matchedHandlers = append(matchedHandlers, matchedHandler)
-		}
-	}
+	matchedHandlers := registry.matchPath(path)
 
 	if any := matchedHandlers.getHandleAny(); any != nil && !matchedHandlers.hasMethod(req.Method) {
-		return registry.makeHandler(req, *any)
+		trace.useFallback()
+		return registry.traceHandler(req, *any, trace)
 	}
 
 	for _, matched := range matchedHandlers {
-		if matched == nil {
-			continue
-		}
-
 		controller := matched.handlerAction
 		if _, ok := controller.method[req.Method]; !ok && len(controller.method) > 0 {
+			trace.skip(matched.handler, "method "+req.Method+" not registered")
 			continue
 		}
 
-		controller, params, handler := registry.makeHandler(req, *matched)
+		controller, params, handler := registry.traceHandler(req, matched, trace)
 		if handler == nil {
 			continue
 		}
@@ -476,24 +568,116 @@ func (registry *RouterRegistry) matchRequest(req *http.Request) (handlerAction,
 	return handlerAction{}, nil, nil
 }
 
-func (registry *RouterRegistry) makeHandler(req *http.Request, matched matchedHandler) (handlerAction, map[string]string, *Handler) {
+// traceHandler makes the handler and records the sources of its params, or the missing params if it is skipped
+func (registry *RouterRegistry) traceHandler(req *http.Request, matched matchedHandler, trace *routeTrace) (handlerAction, map[string]string, *Handler) {
+	sources := trace.paramSources()
+	controller, params, handler := registry.makeHandler(req, matched, sources)
+	if handler == nil {
+		trace.skip(matched.handler, "required param "+strings.Join(missingParams(sources), ", ")+" missing")
+		return controller, params, handler
+	}
+
+	trace.selectHandler(handler, params, sources)
+	return controller, params, handler
+}
+
+// allowedMethods returns the sorted HTTP methods registered for all handlers matching the request path.
+// If one of the handlers has an "any" fallback nil is returned, because every method is allowed.
+func (registry *RouterRegistry) allowedMethods(req *http.Request) []string {
+	var path = req.URL.Path
+	if req.URL.RawPath != "" {
+		path = req.URL.RawPath
+	}
+
+	path = "/" + strings.TrimLeft(path, "/")
+
+	methods := make(map[string]struct{})
+	for _, matched := range registry.matchPath(path) {
+		if matched.handlerAction.any != nil {
+			return nil
+		}
+		for method := range matched.handlerAction.method {
+			methods[method] = struct{}{}
+		}
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	return allowed
+}
+
+// matchParamError returns a *ParamError for the first route which would match the request, if not for an invalid typed param
+func (registry *RouterRegistry) matchParamError(req *http.Request) error {
+	var path = req.URL.Path
+	if req.URL.RawPath != "" {
+		path = req.URL.RawPath
+	}
+
+	path = "/" + strings.TrimLeft(path, "/")
+
+	tree := registry.routeTree()
+	for _, m := range tree.match(path, true) {
+		if m.err == nil {
+			continue
+		}
+
+		controller := registry.handler[tree.routes[m.index].handler]
+		if _, ok := controller.method[req.Method]; !ok && len(controller.method) > 0 && controller.any == nil {
+			continue
+		}
+
+		return m.err
+	}
+
+	return nil
+}
+
+// makeHandler resolves the params of the matched handler, a nil handler is returned if a required param is missing.
+// If sources is not nil, the source of each param is recorded, and all missing params are marked as "missing".
+func (registry *RouterRegistry) makeHandler(req *http.Request, matched matchedHandler, sources map[string]string) (handlerAction, map[string]string, *Handler) {
+	record := func(name, source string) {
+		if sources != nil {
+			sources[name] = source
+		}
+	}
+
 	params := make(map[string]string)
+	missing := false
 	if len(matched.handler.params) > 0 {
 		for k, param := range matched.handler.params {
 			if !param.optional && param.value != "" {
 				params[k] = param.value
+				record(k, "fixed")
 			} else if v, ok := matched.match.Values[k]; ok {
 				params[k] = v
+				record(k, "path")
 			} else if val := req.URL.Query().Get(k); val != "" {
 				params[k] = val
+				record(k, "query")
 			} else if !param.optional && param.value == "" {
-				return handlerAction{}, nil, nil
+				if sources == nil {
+					return handlerAction{}, nil, nil
+				}
+				missing = true
+				record(k, "missing")
 			} else {
 				params[k] = param.value
+				record(k, "default")
 			}
 		}
 	} else {
 		params = matched.match.Values
+		for k := range params {
+			record(k, "path")
+		}
+	}
+
+	if missing {
+		return handlerAction{}, nil, nil
 	}
 	return matched.handlerAction, params, matched.handler
 }
@@ -508,6 +692,33 @@ func (handler *Handler) GetHandlerName() string {
 	return handler.handler
 }
 
+const (
+	contextHandler          contextKeyType = "handler"
+	contextPreflightHandler contextKeyType = "preflightHandler"
+)
+
+// HandlerFromContext returns the route the request belongs to, if any
+func HandlerFromContext(ctx context.Context) *Handler {
+	handler, _ := ctx.Value(contextHandler).(*Handler)
+	return handler
+}
+
+// ContextWithHandler stores the route of the request in a new context
+func ContextWithHandler(ctx context.Context, handler *Handler) context.Context {
+	return context.WithValue(ctx, contextHandler, handler)
+}
+
+// PreflightHandlerFromContext returns the route of the method requested by a CORS preflight request, if any
+func PreflightHandlerFromContext(ctx context.Context) *Handler {
+	handler, _ := ctx.Value(contextPreflightHandler).(*Handler)
+	return handler
+}
+
+// ContextWithPreflightHandler stores the route of the method requested by a CORS preflight request in a new context
+func ContextWithPreflightHandler(ctx context.Context, handler *Handler) context.Context {
+	return context.WithValue(ctx, contextPreflightHandler, handler)
+}
+
 // Normalize enforces a normalization of passed parameters
 func (handler *Handler) Normalize(params ...string) *Handler {
 	if handler.path.normalize == nil {
@@ -518,3 +729,20 @@ func (handler *Handler) Normalize(params ...string) *Handler {
 	}
 	return handler
 }
+
+// WithFilters attaches filters to the route, they run after the global filters and only if this route handles the request
+func (handler *Handler) WithFilters(filters ...Filter) *Handler {
+	handler.filters = append(handler.filters, filters...)
+	return handler
+}
+
+// GetFilters getter
+func (handler *Handler) GetFilters() []Filter {
+	return handler.filters
+}
+
+// WithTimeout overrides the flamingo.router.timeout for this route, a timeout of 0 disables the deadline
+func (handler *Handler) WithTimeout(timeout time.Duration) *Handler {
+	handler.timeout = &timeout
+	return handler
+}
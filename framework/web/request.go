@@ -16,6 +16,12 @@ type (
 		session Session
 		Params  RequestParams
 		Values  sync.Map
+
+		upload          uploadState
+		uploadConfig    *uploadConfig
+		maxBodySize     int64
+		paramTypes      map[string]ParamType
+		validationRules map[string]ValidationRule
 	}
 
 	// RequestParams store string->string values for request data
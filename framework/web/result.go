@@ -9,9 +9,11 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +34,11 @@ type (
 		logger flamingo.Logger
 		debug  bool
 
+		encoders             *encoders
+		validationTranslator ValidationTranslator
+		errorMappings        []ErrorMapping
+		autoETag             bool
+
 		templateForbidden     string
 		templateNotFound      string
 		templateUnavailable   string
@@ -61,10 +68,12 @@ type (
 		URL *url.URL
 	}
 
-	// DataResponse returns a response containing data, e.g. as JSON
+	// DataResponse returns a response containing data, encoded according to the request's Accept header
 	DataResponse struct {
 		Response
-		Data interface{}
+		Data     interface{}
+		encoders *encoders
+		autoETag bool
 	}
 
 	// RenderResponse renders data
@@ -77,8 +86,11 @@ type (
 	// ServerErrorResponse returns a server error, by default http 500
 	ServerErrorResponse struct {
 		RenderResponse
-		Error     error
-		ErrString string
+		Error       error
+		ErrString   string
+		asProblem   bool
+		problemType ProblemType
+		extensions  map[string]interface{}
 	}
 
 	// CacheDirectiveBuilder constructs a CacheDirective with the most commonly used options
@@ -110,6 +122,8 @@ type (
 		MaxAge int
 		// SMaxAge defines the maxAge for shared caches. Supposed to override max-age for CDN for example
 		SMaxAge int
+		// Immutable tells caches that the response will not change while it is fresh, so it is not revalidated
+		Immutable bool
 		// ETag the key for the Response
 		ETag string
 		// LastModifiedSince indicates the time a document last changed
@@ -127,13 +141,19 @@ const (
 // Inject Responder dependencies
 func (r *Responder) Inject(router *Router, logger flamingo.Logger, cfg *struct {
 	Engine                flamingo.TemplateEngine `inject:",optional"`
+	ValidationTranslator  ValidationTranslator    `inject:",optional"`
 	Debug                 bool                    `inject:"config:flamingo.debug.mode"`
 	TemplateForbidden     string                  `inject:"config:flamingo.template.err403"`
 	TemplateNotFound      string                  `inject:"config:flamingo.template.err404"`
 	TemplateUnavailable   string                  `inject:"config:flamingo.template.err503"`
 	TemplateErrorWithCode string                  `inject:"config:flamingo.template.errWithCode"`
+	DefaultMediaType      string                  `inject:"config:flamingo.web.responder.defaultMediaType,optional"`
+	AutoETag              bool                    `inject:"config:flamingo.web.responder.autoETag,optional"`
+	Encoders              encoderProvider         `inject:",optional"`
+	ErrorMappings         errorMappingProvider    `inject:",optional"`
 }) *Responder {
 	r.engine = cfg.Engine
+	r.validationTranslator = cfg.ValidationTranslator
 	r.router = router
 	r.templateForbidden = cfg.TemplateForbidden
 	r.templateNotFound = cfg.TemplateNotFound
@@ -141,6 +161,21 @@ func (r *Responder) Inject(router *Router, logger flamingo.Logger, cfg *struct {
 	r.templateErrorWithCode = cfg.TemplateErrorWithCode
 	r.logger = logger.WithField("module", "framework.web").WithField("category", "responder")
 	r.debug = cfg.Debug
+	r.autoETag = cfg.AutoETag
+
+	var additional map[string]Encoder
+	if cfg.Encoders != nil {
+		additional = cfg.Encoders()
+	}
+	r.encoders = newEncoders(cfg.DefaultMediaType, additional)
+	if cfg.DefaultMediaType != "" && r.encoders.defaultMediaType != strings.ToLower(cfg.DefaultMediaType) {
+		r.logger.Warn(fmt.Sprintf("no encoder for default media type %q, using %q", cfg.DefaultMediaType, r.encoders.defaultMediaType))
+	}
+
+	if cfg.ErrorMappings != nil {
+		r.errorMappings = cfg.ErrorMappings()
+	}
+
 	return r
 }
 
@@ -156,8 +191,12 @@ func (r *Responder) HTTP(status uint, body io.Reader) *Response {
 }
 
 // Apply response
+// Successful responses to GET and HEAD requests are answered with 304 if the If-None-Match or If-Modified-Since headers match.
 func (r *Response) Apply(c context.Context, w http.ResponseWriter) error {
 	if r.CacheDirective != nil {
+		if r.Header == nil {
+			r.Header = make(http.Header)
+		}
 		r.CacheDirective.ApplyHeaders(r.Header)
 	}
 	for name, vals := range r.Header {
@@ -168,6 +207,10 @@ func (r *Response) Apply(c context.Context, w http.ResponseWriter) error {
 	if r.Status == 0 {
 		r.Status = http.StatusOK
 	}
+	if req := RequestFromContext(c); req != nil && r.Status == http.StatusOK && notModified(req.Request(), w.Header()) {
+		writeNotModified(w)
+		return nil
+	}
 	w.WriteHeader(int(r.Status))
 	if r.Body == nil {
 		return nil
@@ -266,7 +309,9 @@ func (r *URLRedirectResponse) SetNoCache() *URLRedirectResponse {
 // Data returns a data response which can be serialized
 func (r *Responder) Data(data interface{}) *DataResponse {
 	return &DataResponse{
-		Data: data,
+		Data:     data,
+		encoders: r.encoders,
+		autoETag: r.autoETag,
 		Response: Response{
 			Status: http.StatusOK,
 			Header: make(http.Header),
@@ -275,17 +320,46 @@ func (r *Responder) Data(data interface{}) *DataResponse {
 }
 
 // Apply response
-// todo: support more than json
+// The encoder is negotiated via the request's Accept header, a 406 is returned if no media type is acceptable.
+// Error responses fall back to the default media type instead.
 func (r *DataResponse) Apply(c context.Context, w http.ResponseWriter) error {
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(r.Data); err != nil {
-		return err
+	encoders := r.encoders
+	if encoders == nil {
+		encoders = defaultEncoders
 	}
-	r.Body = buf
+
+	var accept string
+	if req := RequestFromContext(c); req != nil {
+		accept = req.Request().Header.Get("Accept")
+	}
+
 	if r.Response.Header == nil {
 		r.Response.Header = make(http.Header)
 	}
-	r.Response.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if len(encoders.mediaTypes) > 1 {
+		r.Response.Header.Add("Vary", "Accept")
+	}
+
+	mediaType, encoder, ok := encoders.negotiate(accept)
+	if !ok && r.Response.Status >= http.StatusBadRequest {
+		mediaType, encoder, ok = encoders.negotiate("")
+	}
+	if !ok {
+		r.Response.Status = http.StatusNotAcceptable
+		r.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		r.Body = strings.NewReader("not acceptable, available media types: " + strings.Join(encoders.mediaTypes, ", "))
+		return r.Response.Apply(c, w)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := encoder.Encode(buf, r.Data); err != nil {
+		return err
+	}
+	if r.autoETag {
+		r.Response.setAutoETag(buf.Bytes())
+	}
+	r.Body = buf
+	r.Response.Header.Set("Content-Type", contentType(mediaType))
 	return r.Response.Apply(c, w)
 }
 
@@ -369,6 +443,14 @@ func (r *RenderResponse) Apply(c context.Context, w http.ResponseWriter) error {
 	if err != nil {
 		return err
 	}
+	if r.autoETag && r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Response.setAutoETag(body)
+		r.Body = bytes.NewReader(body)
+	}
 	return r.Response.Apply(c, w)
 }
 
@@ -384,6 +466,13 @@ func (r *ServerErrorResponse) Apply(c context.Context, w http.ResponseWriter) er
 		r.RenderResponse.DataResponse.Response.Status = http.StatusInternalServerError
 	}
 
+	if problem, negotiated := r.problemDetails(c); problem {
+		if err := r.applyProblem(c, w, negotiated); err != nil {
+			http.Error(w, r.ErrString, int(r.RenderResponse.DataResponse.Response.Status))
+		}
+		return nil
+	}
+
 	if err := r.RenderResponse.Apply(c, w); err != nil {
 		http.Error(w, r.ErrString, int(r.RenderResponse.DataResponse.Response.Status))
 	}
@@ -416,6 +505,7 @@ func (r *Responder) ServerErrorWithCodeAndTemplate(err error, tpl string, status
 					"code":  status,
 					"error": errstr,
 				},
+				encoders: r.encoders,
 				Response: Response{
 					Status: status,
 					Header: make(http.Header),
@@ -439,6 +529,59 @@ func (r *Responder) Unavailable(err error) *ServerErrorResponse {
 	return r.ServerErrorWithCodeAndTemplate(err, r.templateUnavailable, http.StatusServiceUnavailable)
 }
 
+// BadRequest creates a 400 error response
+func (r *Responder) BadRequest(err error) *ServerErrorResponse {
+	r.getLogger().Warn(err)
+
+	return r.ServerErrorWithCodeAndTemplate(err, r.templateErrorWithCode, http.StatusBadRequest)
+}
+
+// MethodNotAllowed creates a 405 error response with the allowed methods
+func (r *Responder) MethodNotAllowed(err error, allowed ...string) *ServerErrorResponse {
+	r.getLogger().Warn(err)
+
+	response := r.ServerErrorWithCodeAndTemplate(err, r.templateErrorWithCode, http.StatusMethodNotAllowed)
+	response.Header.Set("Allow", strings.Join(allowed, ", "))
+	return response
+}
+
+// TooManyRequests creates a 429 error response, the client may retry after the given duration
+func (r *Responder) TooManyRequests(err error, retryAfter time.Duration) *ServerErrorResponse {
+	r.getLogger().Info(err)
+
+	response := r.ServerErrorWithCodeAndTemplate(err, r.templateErrorWithCode, http.StatusTooManyRequests)
+	response.Header.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return response
+}
+
+// UnprocessableEntity creates a 422 error response, the field errors of a ValidationError are added as `fields`.
+// The messages are translated if a ValidationTranslator is bound, e.g. by core/locale.
+func (r *Responder) UnprocessableEntity(err error) *ServerErrorResponse {
+	r.getLogger().Info(err)
+
+	response := r.ServerErrorWithCodeAndTemplate(err, r.templateErrorWithCode, http.StatusUnprocessableEntity)
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		fields := make([]map[string]string, len(validationErr.Fields))
+		for i, fieldError := range validationErr.Fields {
+			message := fieldError.Message
+			if r.validationTranslator != nil {
+				message = r.validationTranslator.TranslateFieldError(fieldError)
+			}
+			fields[i] = map[string]string{
+				"field":   fieldError.Field,
+				"rule":    fieldError.Rule,
+				"message": message,
+			}
+		}
+		response.Data.(map[string]interface{})["fields"] = fields
+		response.Extension("fields", fields)
+	}
+
+	return response
+}
+
 // NotFound creates a 404 error response
 func (r *Responder) NotFound(err error) *ServerErrorResponse {
 	r.getLogger().Warn(err)
@@ -476,10 +619,19 @@ func (r *Responder) getLogger() flamingo.Logger {
 
 func (r *Responder) completeResult(result Result) Result {
 	switch result := result.(type) {
+	case *DataResponse:
+		if result.encoders == nil {
+			result.encoders = r.encoders
+		}
+		result.autoETag = result.autoETag || r.autoETag
 	case *RenderResponse:
 		if result.engine == nil {
 			result.engine = r.engine
 		}
+		if result.encoders == nil {
+			result.encoders = r.encoders
+		}
+		result.autoETag = result.autoETag || r.autoETag
 	case *RouteRedirectResponse:
 		if result.router == nil {
 			result.router = r.router
@@ -488,6 +640,9 @@ func (r *Responder) completeResult(result Result) Result {
 		if result.engine == nil {
 			result.engine = r.engine
 		}
+		if result.encoders == nil {
+			result.encoders = r.encoders
+		}
 	}
 	return result
 }
@@ -513,7 +668,7 @@ func (c *CacheDirective) ApplyHeaders(header http.Header) {
 		cacheControlValues = append(cacheControlValues, "no-cache")
 	} else {
 		if c.MaxAge > 0 {
-			header.Set("Expires", time.Now().Add(time.Duration(int64(c.MaxAge))*time.Second).UTC().Format(time.RFC1123))
+			header.Set("Expires", time.Now().Add(time.Duration(int64(c.MaxAge))*time.Second).UTC().Format(http.TimeFormat))
 			cacheControlValues = append(cacheControlValues, fmt.Sprintf("max-age=%d", c.MaxAge))
 		}
 		if c.SMaxAge > 0 {
@@ -526,13 +681,16 @@ func (c *CacheDirective) ApplyHeaders(header http.Header) {
 		header.Set("ETag", c.ETag)
 	}
 	if c.LastModifiedSince != nil {
-		header.Set("Last-Modified", c.LastModifiedSince.UTC().Format(time.RFC1123))
+		header.Set("Last-Modified", c.LastModifiedSince.UTC().Format(http.TimeFormat))
 	}
 
 	// Other directives for caches
 	if c.NoTransform {
 		cacheControlValues = append(cacheControlValues, "no-transform")
 	}
+	if c.Immutable {
+		cacheControlValues = append(cacheControlValues, "immutable")
+	}
 	if c.Visibility == "public" {
 		cacheControlValues = append(cacheControlValues, "public")
 	}
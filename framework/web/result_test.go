@@ -2,9 +2,11 @@ package web
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +34,12 @@ func TestEmptyResults(t *testing.T) {
 	assert.NoError(t, new(ServerErrorResponse).Apply(context.Background(), recorder))
 	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
 }
+
+func TestResponder_TooManyRequests(t *testing.T) {
+	response := new(Responder).TooManyRequests(errors.New("limit exceeded"), 1500*time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	assert.NoError(t, response.Apply(context.Background(), recorder))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("Retry-After"), "Retry-After is rounded up to full seconds")
+}
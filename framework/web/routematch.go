@@ -0,0 +1,377 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"github.com/spf13/cobra"
+)
+
+type (
+	// routeCandidate is a route matching the request path, with the reason why it is selected or skipped
+	routeCandidate struct {
+		handler  *Handler
+		methods  []string
+		params   map[string]string
+		sources  map[string]string
+		selected bool
+		reason   string
+	}
+
+	// routeMatchExplanation describes how the router resolves a request
+	routeMatchExplanation struct {
+		method     string
+		path       string
+		candidates []*routeCandidate
+		selected   *routeCandidate
+		autoHead   bool
+	}
+
+	// routeTrace records why matchRequest selects or skips the routes matching the request path, a nil trace records nothing
+	routeTrace struct {
+		fallback bool
+		skipped  map[*Handler]string
+		selected *Handler
+		params   map[string]string
+		sources  map[string]string
+	}
+)
+
+// explainMatch lists all routes matching the request path in the order of their registration, and why matchRequest
+// selects or skips them
+func (registry *RouterRegistry) explainMatch(req *http.Request) *routeMatchExplanation {
+	var p = req.URL.Path
+	if req.URL.RawPath != "" {
+		p = req.URL.RawPath
+	}
+	p = "/" + strings.TrimLeft(p, "/")
+
+	explanation := &routeMatchExplanation{method: req.Method, path: p}
+	candidates := make(map[*Handler]*routeCandidate)
+
+	tree := registry.routeTree()
+	for _, m := range tree.match(p, true) {
+		handler := tree.routes[m.index]
+		candidates[handler] = &routeCandidate{handler: handler}
+		if m.err != nil {
+			candidates[handler].reason = "skipped: " + m.err.Error()
+		}
+	}
+
+	trace := &routeTrace{skipped: make(map[*Handler]string)}
+	registry.traceRequest(req, trace)
+
+	for handler, reason := range trace.skipped {
+		candidates[handler].reason = "skipped: " + reason
+	}
+
+	if trace.selected != nil {
+		candidate := candidates[trace.selected]
+		candidate.selected = true
+		candidate.params, candidate.sources = trace.params, trace.sources
+
+		switch {
+		case trace.fallback:
+			candidate.reason = "selected: any fallback, no route for this path registers " + req.Method
+		case len(registry.handler[trace.selected.handler].method) == 0:
+			candidate.reason = "selected: any method"
+		default:
+			candidate.reason = "selected: registers " + req.Method
+		}
+		explanation.selected = candidate
+	}
+
+	unreached := "skipped: not reached, an earlier route is selected"
+	if trace.fallback {
+		unreached = "skipped: no route for this path registers " + req.Method + ", the any fallback is used"
+	}
+
+	for _, handler := range registry.routes {
+		candidate, ok := candidates[handler]
+		if !ok {
+			continue
+		}
+		if candidate.reason == "" {
+			candidate.reason = unreached
+		}
+		candidate.methods = registry.handler[handler.handler].methodNames()
+		explanation.candidates = append(explanation.candidates, candidate)
+	}
+
+	return explanation
+}
+
+// useFallback records that the any fallback is used, because no route for the path registers the method
+func (t *routeTrace) useFallback() {
+	if t != nil {
+		t.fallback = true
+	}
+}
+
+// skip records the reason a route is skipped
+func (t *routeTrace) skip(handler *Handler, reason string) {
+	if t != nil {
+		t.skipped[handler] = reason
+	}
+}
+
+// paramSources returns the map makeHandler records the param sources in, nil if nothing is traced
+func (t *routeTrace) paramSources() map[string]string {
+	if t == nil {
+		return nil
+	}
+	return make(map[string]string)
+}
+
+// selectHandler records the selected route and its params
+func (t *routeTrace) selectHandler(handler *Handler, params, sources map[string]string) {
+	if t != nil {
+		t.selected, t.params, t.sources = handler, params, sources
+	}
+}
+
+func missingParams(sources map[string]string) []string {
+	var missing []string
+	for name, source := range sources {
+		if source == "missing" {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// methodNames lists the registered methods, ANY for the fallback and DATA for data actions
+func (ha handlerAction) methodNames() []string {
+	methods := make([]string, 0, len(ha.method)+2)
+	for method := range ha.method {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	if ha.any != nil {
+		methods = append(methods, "ANY")
+	}
+	if ha.data != nil {
+		methods = append(methods, "DATA")
+	}
+	return methods
+}
+
+// explain how the router resolves the request, HEAD requests fall back to GET like the handler does
+func (r *Router) explain(req *http.Request) *routeMatchExplanation {
+	if r.routerRegistry == nil {
+		r.Handler()
+	}
+
+	req.URL.Path = strings.TrimPrefix(req.URL.Path, strings.TrimRight(r.Base().Path, "/"))
+
+	explanation := r.routerRegistry.explainMatch(req)
+	if explanation.selected == nil && r.autoHead && req.Method == http.MethodHead {
+		getRequest := *req
+		getRequest.Method = http.MethodGet
+		if get := r.routerRegistry.explainMatch(&getRequest); get.selected != nil {
+			get.method = http.MethodHead
+			get.autoHead = true
+			return get
+		}
+	}
+
+	return explanation
+}
+
+// routesMatchCmd prints how a request is resolved by the router
+func routesMatchCmd(router *Router, area *config.Area) *cobra.Command {
+	var contextName string
+
+	cmd := &cobra.Command{
+		Use:   "match <METHOD> <URL>",
+		Short: "Show which route and handler a request resolves to",
+		Long: `Show which route and handler a request resolves to.
+If the URL matches a prefixrouter prefix, the prefix is stripped and the request is matched against the router of its area.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			u, err := url.Parse(args[1])
+			if err != nil {
+				return err
+			}
+
+			areaName, areaRouter, routed, err := routerForURL(router, area, contextName, u)
+			if err != nil {
+				return err
+			}
+
+			printMatch(cmd.OutOrStdout(), areaName, areaRouter, strings.ToUpper(args[0]), u, routed)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&contextName, "context", "c", "", "name of the config area, by default the area is chosen by its prefixrouter prefix")
+
+	return cmd
+}
+
+// routerForURL returns the router of the named area, or of the area whose prefixrouter prefix matches the URL.
+// The returned URL is the URL as routed by the prefixrouter, without the prefix.
+func routerForURL(router *Router, area *config.Area, contextName string, u *url.URL) (string, *Router, *url.URL, error) {
+	if area == nil {
+		return "", router, u, nil
+	}
+
+	areas, err := area.Flat()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if contextName != "" {
+		if _, ok := areas[contextName]; !ok {
+			return "", nil, nil, fmt.Errorf("config area %q not found", contextName)
+		}
+	}
+
+	prefixes := make(map[string]string)
+	for name, prefix := range areaPrefixes(areas) {
+		if contextName == "" || contextName == name {
+			prefixes[prefix] = name
+		}
+	}
+
+	routed := u
+	if prefixed, rest := matchPrefix(prefixes, u); prefixed != "" {
+		contextName = prefixed
+		if routed, err = url.Parse(rest); err != nil {
+			return "", nil, nil, err
+		}
+		routed.Path = "/" + strings.TrimLeft(routed.Path, "/")
+	}
+
+	if contextName == "" || contextName == area.Name {
+		return area.Name, router, routed, nil
+	}
+
+	injector, err := areas[contextName].GetInitializedInjector()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	i, err := injector.GetInstance(Router{})
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return contextName, i.(*Router), routed, nil
+}
+
+// areaPrefixes returns the prefixrouter prefixes of all areas with a configured router path or host
+func areaPrefixes(areas map[string]*config.Area) map[string]string {
+	prefixes := make(map[string]string)
+
+	for name, area := range areas {
+		pathValue, pathSet := area.Configuration.Get("flamingo.router.path")
+		hostValue, hostSet := area.Configuration.Get("flamingo.router.host")
+		if !pathSet && !hostSet {
+			continue
+		}
+
+		prefix := "/"
+		if pathSet {
+			prefix = path.Join("/", pathValue.(string), "/")
+		}
+		if hostSet && hostValue != "" {
+			prefix = hostValue.(string) + prefix
+		}
+
+		prefixes[name] = prefix
+	}
+
+	return prefixes
+}
+
+// matchPrefix returns the area of the longest matching prefix and the rest of the URL, like the prefixrouter does.
+// Prefixes with a host take precedence over prefixes without.
+func matchPrefix(prefixes map[string]string, u *url.URL) (string, string) {
+	host := u.Hostname()
+	requestURI := "/" + strings.TrimLeft(u.RequestURI(), "/")
+
+	var matched string
+	for prefix := range prefixes {
+		if strings.HasPrefix(host+requestURI, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched != "" {
+		return prefixes[matched], requestURI[len(matched)-len(host):]
+	}
+
+	for prefix := range prefixes {
+		if strings.HasPrefix(requestURI, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched != "" {
+		return prefixes[matched], requestURI[len(matched):]
+	}
+
+	return "", ""
+}
+
+func printMatch(out io.Writer, areaName string, router *Router, method string, u, routed *url.URL) {
+	routedCopy := *routed
+	explanation := router.explain(&http.Request{Method: method, URL: &routedCopy, Host: u.Host})
+
+	fmt.Fprintf(out, "%s %s\n", method, u.String())
+	if areaName != "" {
+		fmt.Fprintf(out, "area: %s\n", areaName)
+	}
+	fmt.Fprintf(out, "routed path: %s\n", explanation.path)
+	if explanation.autoHead {
+		fmt.Fprintln(out, "HEAD is served via GET")
+	}
+
+	fmt.Fprintln(out)
+	if len(explanation.candidates) == 0 {
+		fmt.Fprintln(out, "no route matches the path")
+	} else {
+		fmt.Fprintln(out, "candidates:")
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for i, candidate := range explanation.candidates {
+			fmt.Fprintf(w, "  %d.\t%s\t%s\t[%s]\t%s\n", i+1, candidate.handler.path.path, candidate.handler.handler, strings.Join(candidate.methods, ", "), candidate.reason)
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Fprintln(out)
+	selected := explanation.selected
+	if selected == nil {
+		fmt.Fprintln(out, "no route selected, the request is answered by "+FlamingoNotfound+" or 405 Method Not Allowed")
+		return
+	}
+
+	fmt.Fprintf(out, "selected: %s\n", selected.handler.handler)
+
+	if len(selected.params) > 0 {
+		fmt.Fprintln(out, "params:")
+		names := make([]string, 0, len(selected.params))
+		for name := range selected.params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s\t= %q\t(%s)\n", name, selected.params[name], selected.sources[name])
+		}
+		_ = w.Flush()
+	}
+
+	if canonical, err := router.Relative(selected.handler.handler, selected.params); err == nil {
+		fmt.Fprintf(out, "canonical URL: %s\n", canonical)
+	} else {
+		fmt.Fprintf(out, "canonical URL: %v\n", err)
+	}
+}
@@ -0,0 +1,255 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+func routeMatchTestRegistry(t *testing.T) *RouterRegistry {
+	t.Helper()
+
+	action := func(context.Context, *Request) Result { return nil }
+
+	registry := NewRegistry()
+	registry.HandleGet("product.view", action)
+	registry.HandlePost("product.save", action)
+	registry.HandleAny("product.fallback", action)
+	registry.HandleGet("search", action)
+	registry.HandleGet("page", action)
+
+	registry.MustRoute("/product/:id<int>", "product.save")
+	registry.MustRoute("/product/:id<int>", "product.view")
+	registry.MustRoute("/product/$id<.*>", "product.fallback")
+	registry.MustRoute("/search", "search(term, page?=\"1\")")
+	registry.MustRoute("/search", `search(term="all", page?="1")`)
+	registry.MustRoute("/page/:name", "page")
+
+	return registry
+}
+
+func TestRouterRegistry_explainMatch(t *testing.T) {
+	registry := routeMatchTestRegistry(t)
+
+	tests := []struct {
+		method, target string
+		selected       string
+		reasons        []string
+		params         map[string]string
+		sources        map[string]string
+	}{
+		{
+			method:   http.MethodGet,
+			target:   "/product/1",
+			selected: "product.view",
+			reasons: []string{
+				"skipped: method GET not registered",
+				"selected: registers GET",
+				"skipped: not reached, an earlier route is selected",
+			},
+			params:  map[string]string{"id": "1"},
+			sources: map[string]string{"id": "path"},
+		},
+		{
+			method:   http.MethodDelete,
+			target:   "/product/1",
+			selected: "product.fallback",
+			reasons: []string{
+				"skipped: no route for this path registers DELETE, the any fallback is used",
+				"skipped: no route for this path registers DELETE, the any fallback is used",
+				"selected: any fallback, no route for this path registers DELETE",
+			},
+			params:  map[string]string{"id": "1"},
+			sources: map[string]string{"id": "path"},
+		},
+		{
+			method:   http.MethodGet,
+			target:   "/product/abc",
+			selected: "product.fallback",
+			reasons: []string{
+				`skipped: param id: "abc" is not a valid int`,
+				`skipped: param id: "abc" is not a valid int`,
+				"selected: any fallback, no route for this path registers GET",
+			},
+			params:  map[string]string{"id": "abc"},
+			sources: map[string]string{"id": "path"},
+		},
+		{
+			method:   http.MethodGet,
+			target:   "/search?term=shoes",
+			selected: "search",
+			reasons: []string{
+				"selected: registers GET",
+				"skipped: not reached, an earlier route is selected",
+			},
+			params:  map[string]string{"term": "shoes", "page": "1"},
+			sources: map[string]string{"term": "query", "page": "default"},
+		},
+		{
+			method:   http.MethodGet,
+			target:   "/search?page=2",
+			selected: "search",
+			reasons: []string{
+				"skipped: required param term missing",
+				"selected: registers GET",
+			},
+			params:  map[string]string{"term": "all", "page": "2"},
+			sources: map[string]string{"term": "fixed", "page": "query"},
+		},
+		{
+			method:  http.MethodPost,
+			target:  "/page/home",
+			reasons: []string{"skipped: method POST not registered"},
+		},
+		{
+			method: http.MethodGet,
+			target: "/unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			explanation := registry.explainMatch(req)
+
+			var reasons []string
+			for _, candidate := range explanation.candidates {
+				reasons = append(reasons, candidate.reason)
+			}
+			assert.Equal(t, tt.reasons, reasons)
+
+			_, params, handler := registry.matchRequest(req)
+			if tt.selected == "" {
+				assert.Nil(t, explanation.selected)
+				assert.Nil(t, handler)
+				return
+			}
+
+			require.NotNil(t, explanation.selected)
+			assert.Equal(t, tt.selected, explanation.selected.handler.GetHandlerName())
+			assert.Same(t, handler, explanation.selected.handler, "explainMatch selects the route matchRequest selects")
+			assert.Equal(t, params, explanation.selected.params)
+			assert.Equal(t, tt.params, explanation.selected.params)
+			assert.Equal(t, tt.sources, explanation.selected.sources)
+		})
+	}
+}
+
+func TestRoutesMatchCmd(t *testing.T) {
+	router := &Router{routerRegistry: routeMatchTestRegistry(t)}
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		out := new(bytes.Buffer)
+		cmd := routesMatchCmd(router, nil)
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	t.Run("selected route", func(t *testing.T) {
+		out := run(t, "get", "/search?page=2")
+		assert.Contains(t, out, "GET /search?page=2\n")
+		assert.Regexp(t, `1\.\s+/search\s+search\s+\[GET\]\s+skipped: required param term missing`, out)
+		assert.Regexp(t, `2\.\s+/search\s+search\s+\[GET\]\s+selected: registers GET`, out)
+		assert.Contains(t, out, "selected: search\n")
+		assert.Regexp(t, `page\s+= "2"\s+\(query\)`, out)
+		assert.Regexp(t, `term\s+= "all"\s+\(fixed\)`, out)
+		assert.Contains(t, out, "canonical URL: /search?page=2&term=all\n")
+	})
+
+	t.Run("head served via get", func(t *testing.T) {
+		router.autoHead = true
+		defer func() { router.autoHead = false }()
+
+		out := run(t, "HEAD", "/page/home")
+		assert.Contains(t, out, "HEAD is served via GET\n")
+		assert.Contains(t, out, "selected: page\n")
+		assert.Contains(t, out, "canonical URL: /page/home\n")
+	})
+
+	t.Run("no route", func(t *testing.T) {
+		out := run(t, "GET", "/unknown")
+		assert.Contains(t, out, "no route matches the path\n")
+		assert.Contains(t, out, "no route selected")
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		cmd := routesMatchCmd(router, nil)
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+		cmd.SetArgs([]string{"GET"})
+		assert.Error(t, cmd.Execute())
+	})
+}
+
+func TestMatchPrefix(t *testing.T) {
+	en := config.NewArea("en", nil)
+	en.Configuration = config.Map{"flamingo": config.Map{"router": config.Map{"path": "en"}}}
+	enShop := config.NewArea("en-shop", nil)
+	enShop.Configuration = config.Map{"flamingo": config.Map{"router": config.Map{"path": "/en/shop/"}}}
+	host := config.NewArea("host", nil)
+	host.Configuration = config.Map{"flamingo": config.Map{"router": config.Map{"path": "en", "host": "example.com"}}}
+	none := config.NewArea("none", nil)
+
+	prefixes := areaPrefixes(map[string]*config.Area{"root/en": en, "root/en-shop": enShop, "root/host": host, "root/none": none})
+	assert.Equal(t, map[string]string{"root/en": "/en", "root/en-shop": "/en/shop", "root/host": "example.com/en"}, prefixes)
+
+	byPrefix := make(map[string]string)
+	for name, prefix := range prefixes {
+		byPrefix[prefix] = name
+	}
+
+	tests := []struct {
+		url, area, rest string
+	}{
+		{url: "/en/product/1?x=y", area: "root/en", rest: "/product/1?x=y"},
+		{url: "/en/shop/cart", area: "root/en-shop", rest: "/cart"},
+		{url: "http://example.com:3322/en/shop/cart", area: "root/host", rest: "/shop/cart"},
+		{url: "http://example.org/en/shop/cart", area: "root/en-shop", rest: "/cart"},
+		{url: "/de/product/1", area: "", rest: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			area, rest := matchPrefix(byPrefix, u)
+			assert.Equal(t, tt.area, area)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+}
+
+func TestRouterForURL(t *testing.T) {
+	router := new(Router)
+	en := config.NewArea("en", nil)
+	en.Configuration = config.Map{"flamingo": config.Map{"router": config.Map{"path": "en"}}}
+	root := config.NewArea("root", nil, en)
+
+	u, err := url.Parse("/en/product/1")
+	require.NoError(t, err)
+
+	t.Run("selected area", func(t *testing.T) {
+		name, areaRouter, routed, err := routerForURL(router, root, "root", u)
+		require.NoError(t, err)
+		assert.Equal(t, "root", name)
+		assert.Same(t, router, areaRouter)
+		assert.Equal(t, "/en/product/1", routed.Path, "the prefix of other areas is not stripped")
+	})
+
+	t.Run("unknown area", func(t *testing.T) {
+		_, _, _, err := routerForURL(router, root, "root/de", u)
+		assert.EqualError(t, err, `config area "root/de" not found`)
+	})
+}
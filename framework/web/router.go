@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
@@ -27,9 +28,10 @@ type (
 		Absolute(r *Request, to string, params map[string]string) (*url.URL, error)
 	}
 
-	filterProvider    func() []Filter
-	routesProvider    func() []RoutesModule
-	responderProvider func() *Responder
+	filterProvider      func() []Filter
+	namedFilterProvider func() map[string]Filter
+	routesProvider      func() []RoutesModule
+	responderProvider   func() *Responder
 
 	// Router represents actual implementation of ReverseRouter interface
 	Router struct {
@@ -44,6 +46,17 @@ type (
 		sessionStore      *SessionStore
 		sessionName       string
 		responderProvider responderProvider
+		methodNotAllowed  bool
+		autoHead          bool
+		autoOptions       bool
+		timeout           time.Duration
+		webSocketOrigins  []string
+		uploadConfig      uploadConfig
+		maxBodySize       int64
+
+		namedFilterProvider    namedFilterProvider
+		paramTypeProvider      paramTypeProvider
+		validationRuleProvider validationRuleProvider
 	}
 )
 
@@ -63,6 +76,26 @@ func (r *Router) Inject(
 		Path        string `inject:"config:flamingo.router.path,optional"`
 		External    string `inject:"config:flamingo.router.external,optional"`
 		SessionName string `inject:"config:flamingo.session.name,optional"`
+		// method handling
+		MethodNotAllowed bool `inject:"config:flamingo.router.methodNotAllowed,optional"`
+		AutoHead         bool `inject:"config:flamingo.router.autoHead,optional"`
+		AutoOptions      bool `inject:"config:flamingo.router.autoOptions,optional"`
+		// request timeout in milliseconds
+		Timeout float64 `inject:"config:flamingo.router.timeout,optional"`
+		// origins allowed to open websocket connections
+		WebSocketAllowedOrigins config.Slice `inject:"config:flamingo.router.websocket.allowedOrigins,optional"`
+		// upload limits in bytes
+		UploadTempDir      string  `inject:"config:flamingo.web.upload.tempDir,optional"`
+		UploadMaxFileSize  float64 `inject:"config:flamingo.web.upload.maxFileSize,optional"`
+		UploadMaxTotalSize float64 `inject:"config:flamingo.web.upload.maxTotalSize,optional"`
+		// body limit of Request.Bind in bytes
+		BindingMaxBodySize float64 `inject:"config:flamingo.web.binding.maxBodySize,optional"`
+		// NamedFilters are bound via BindNamedFilter, to be referenced by the routes.yml
+		NamedFilters namedFilterProvider `inject:",optional"`
+		// ParamTypes are bound via BindParamType
+		ParamTypes paramTypeProvider `inject:",optional"`
+		// ValidationRules are bound via BindValidationRule
+		ValidationRules validationRuleProvider `inject:",optional"`
 	},
 	sessionStore *SessionStore,
 	eventRouter flamingo.EventRouter,
@@ -95,24 +128,27 @@ func (r *Router) Inject(
 		r.sessionName = cfg.SessionName
 	}
 	r.responderProvider = responderProvider
+	r.methodNotAllowed = cfg.MethodNotAllowed
+	r.autoHead = cfg.AutoHead
+	r.autoOptions = cfg.AutoOptions
+	r.timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	if err := cfg.WebSocketAllowedOrigins.MapInto(&r.webSocketOrigins); err != nil {
+		r.logger.Warn("WebSocket allowed origins error: ", err)
+	}
+	r.uploadConfig = uploadConfig{
+		tempDir:      cfg.UploadTempDir,
+		maxFileSize:  int64(cfg.UploadMaxFileSize),
+		maxTotalSize: int64(cfg.UploadMaxTotalSize),
+	}
+	r.maxBodySize = int64(cfg.BindingMaxBodySize)
+	r.namedFilterProvider = cfg.NamedFilters
+	r.paramTypeProvider = cfg.ParamTypes
+	r.validationRuleProvider = cfg.ValidationRules
 }
 
 // Handler creates and returns new instance of http.Handler interface
 func (r *Router) Handler() http.Handler {
-	r.routerRegistry = NewRegistry()
-
-	if r.configArea != nil {
-		for _, route := range r.configArea.Routes {
-			r.routerRegistry.MustRoute(route.Path, route.Controller)
-			if route.Name != "" {
-				r.routerRegistry.Alias(route.Name, route.Controller)
-			}
-		}
-	}
-
-	for _, m := range r.routesProvider() {
-		m.Routes(r.routerRegistry)
-	}
+	r.routerRegistry = r.newRegistry()
 
 	for _, handler := range r.routerRegistry.routes {
 		if _, ok := r.routerRegistry.handler[handler.handler]; !ok {
@@ -124,18 +160,67 @@ func (r *Router) Handler() http.Handler {
 		r.responderProvider = func() *Responder { return new(Responder) }
 	}
 
+	var validationRules map[string]ValidationRule
+	if r.validationRuleProvider != nil {
+		validationRules = r.validationRuleProvider()
+	}
+
 	return &handler{
-		routerRegistry: r.routerRegistry,
-		filter:         r.filterProvider(),
-		eventRouter:    r.eventRouter,
-		logger:         r.logger.WithField(flamingo.LogKeyModule, "web").WithField(flamingo.LogKeyCategory, "handler"),
-		sessionStore:   r.sessionStore,
-		sessionName:    r.sessionName,
-		prefix:         strings.TrimRight(r.Base().Path, "/"),
-		responder:      r.responderProvider(),
+		routerRegistry:   r.routerRegistry,
+		filter:           r.filterProvider(),
+		eventRouter:      r.eventRouter,
+		logger:           r.logger.WithField(flamingo.LogKeyModule, "web").WithField(flamingo.LogKeyCategory, "handler"),
+		sessionStore:     r.sessionStore,
+		sessionName:      r.sessionName,
+		prefix:           strings.TrimRight(r.Base().Path, "/"),
+		responder:        r.responderProvider(),
+		methodNotAllowed: r.methodNotAllowed,
+		autoHead:         r.autoHead,
+		autoOptions:      r.autoOptions,
+		timeout:          r.timeout,
+
+		webSocketUpgrader: newWebSocketUpgrader(r.webSocketOrigins),
+		uploadConfig:      &r.uploadConfig,
+		maxBodySize:       r.maxBodySize,
+		validationRules:   validationRules,
 	}
 }
 
+// newRegistry registers the routes of the config area and the routes modules in a new registry
+func (r *Router) newRegistry() *RouterRegistry {
+	registry := NewRegistry()
+	if r.paramTypeProvider != nil {
+		registry.paramTypes = r.paramTypeProvider()
+	}
+
+	if r.configArea != nil {
+		var namedFilters map[string]Filter
+		if r.namedFilterProvider != nil {
+			namedFilters = r.namedFilterProvider()
+		}
+
+		for _, route := range r.configArea.Routes {
+			handler := registry.MustRoute(route.Path, route.Controller)
+			for _, name := range route.Filters {
+				filter, ok := namedFilters[name]
+				if !ok {
+					panic(fmt.Errorf("the filter %q is not bound, used for path %q", name, route.Path))
+				}
+				handler.WithFilters(filter)
+			}
+			if route.Name != "" {
+				registry.Alias(route.Name, route.Controller)
+			}
+		}
+	}
+
+	for _, m := range r.routesProvider() {
+		m.Routes(registry)
+	}
+
+	return registry
+}
+
 // ListenAndServe starts flamingo server
 func (r *Router) ListenAndServe(addr string) error {
 	r.eventRouter.Dispatch(context.Background(), &flamingo.ServerStartEvent{Port: addr})
@@ -2,16 +2,20 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
 )
 
@@ -147,6 +151,85 @@ func TestRouter(t *testing.T) {
 	})
 }
 
+func TestRouterMethodHandling(t *testing.T) {
+	setup := func(methodNotAllowed, autoHead, autoOptions bool) http.Handler {
+		router := &Router{
+			eventRouter:      new(flamingo.DefaultEventRouter),
+			filterProvider:   func() []Filter { return nil },
+			routesProvider:   func() []RoutesModule { return nil },
+			logger:           flamingo.NullLogger{},
+			methodNotAllowed: methodNotAllowed,
+			autoHead:         autoHead,
+			autoOptions:      autoOptions,
+		}
+		h := router.Handler()
+
+		registry := NewRegistry()
+		h.(*handler).routerRegistry = registry
+		registry.HandleGet("get", func(context.Context, *Request) Result {
+			return &Response{Status: http.StatusOK, Body: strings.NewReader("body")}
+		})
+		registry.HandlePut("get", func(context.Context, *Request) Result { return &Response{Status: http.StatusOK} })
+		registry.HandleAny("any", func(context.Context, *Request) Result { return &Response{Status: http.StatusAccepted} })
+		registry.HandleAny(FlamingoNotfound, func(context.Context, *Request) Result { return &Response{Status: http.StatusNotFound} })
+		_, err := registry.Route("/get", "get")
+		require.NoError(t, err)
+		_, err = registry.Route("/any", "any")
+		require.NoError(t, err)
+
+		return h
+	}
+
+	serve := func(h http.Handler, method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		h := setup(true, true, true)
+
+		res := serve(h, http.MethodPost, "/get")
+		assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+		assert.Equal(t, "GET, HEAD, OPTIONS, PUT", res.Header().Get("Allow"))
+
+		res = serve(h, http.MethodOptions, "/get")
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "GET, HEAD, OPTIONS, PUT", res.Header().Get("Allow"))
+
+		res = serve(h, http.MethodHead, "/get")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Empty(t, res.Body.String())
+
+		res = serve(h, http.MethodGet, "/get")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "body", res.Body.String())
+
+		res = serve(h, http.MethodPost, "/any")
+		assert.Equal(t, http.StatusAccepted, res.Code)
+
+		res = serve(h, http.MethodOptions, "/any")
+		assert.Equal(t, http.StatusAccepted, res.Code)
+
+		res = serve(h, http.MethodPost, "/unknown")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		h := setup(false, false, false)
+
+		res := serve(h, http.MethodPost, "/get")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.Empty(t, res.Header().Get("Allow"))
+
+		res = serve(h, http.MethodOptions, "/get")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+
+		res = serve(h, http.MethodHead, "/get")
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
 func TestRouterTestify(t *testing.T) {
 	registry := NewRegistry()
 	_, err := registry.Route("/test", "test")
@@ -196,6 +279,26 @@ func TestRouterRelativeAndAbsolute(t *testing.T) {
 			Path        string `inject:"config:flamingo.router.path,optional"`
 			External    string `inject:"config:flamingo.router.external,optional"`
 			SessionName string `inject:"config:flamingo.session.name,optional"`
+			// method handling
+			MethodNotAllowed bool `inject:"config:flamingo.router.methodNotAllowed,optional"`
+			AutoHead         bool `inject:"config:flamingo.router.autoHead,optional"`
+			AutoOptions      bool `inject:"config:flamingo.router.autoOptions,optional"`
+			// request timeout in milliseconds
+			Timeout float64 `inject:"config:flamingo.router.timeout,optional"`
+			// origins allowed to open websocket connections
+			WebSocketAllowedOrigins config.Slice `inject:"config:flamingo.router.websocket.allowedOrigins,optional"`
+			// upload limits in bytes
+			UploadTempDir      string  `inject:"config:flamingo.web.upload.tempDir,optional"`
+			UploadMaxFileSize  float64 `inject:"config:flamingo.web.upload.maxFileSize,optional"`
+			UploadMaxTotalSize float64 `inject:"config:flamingo.web.upload.maxTotalSize,optional"`
+			// body limit of Request.Bind in bytes
+			BindingMaxBodySize float64 `inject:"config:flamingo.web.binding.maxBodySize,optional"`
+			// NamedFilters are bound via BindNamedFilter, to be referenced by the routes.yml
+			NamedFilters namedFilterProvider `inject:",optional"`
+			// ParamTypes are bound via BindParamType
+			ParamTypes paramTypeProvider `inject:",optional"`
+			// ValidationRules are bound via BindValidationRule
+			ValidationRules validationRuleProvider `inject:",optional"`
 		}{
 			Scheme:      scheme,
 			Host:        host,
@@ -522,3 +625,251 @@ func TestRouterRelativeAndAbsolute(t *testing.T) {
 		assert.Equal(t, "http://external.domain/external-path/test", absoluteURL.String())
 	})
 }
+
+type (
+	testRoutesModule func(registry *RouterRegistry)
+
+	testFilter struct {
+		name  string
+		calls *[]string
+	}
+)
+
+func (m testRoutesModule) Routes(registry *RouterRegistry) {
+	m(registry)
+}
+
+func (f *testFilter) Filter(ctx context.Context, req *Request, w http.ResponseWriter, fc *FilterChain) Result {
+	*f.calls = append(*f.calls, f.name)
+	return fc.Next(ctx, req, w)
+}
+
+func TestRouteFilters(t *testing.T) {
+	var calls []string
+	filter := func(name string) Filter { return &testFilter{name: name, calls: &calls} }
+
+	area := config.NewArea("test", nil)
+	area.Routes = []config.Route{{Path: "/configured", Controller: "configured", Filters: []string{"named"}}}
+
+	router := &Router{
+		eventRouter:         new(flamingo.DefaultEventRouter),
+		filterProvider:      func() []Filter { return []Filter{filter("global")} },
+		namedFilterProvider: func() map[string]Filter { return map[string]Filter{"named": filter("named")} },
+		routesProvider: func() []RoutesModule {
+			return []RoutesModule{testRoutesModule(func(registry *RouterRegistry) {
+				action := func(context.Context, *Request) Result {
+					calls = append(calls, "action")
+					return &Response{Status: http.StatusOK}
+				}
+				registry.HandleGet("plain", action)
+				registry.HandleGet("route", action)
+				registry.HandleGet("group", action)
+				registry.HandleGet("nested", action)
+				registry.HandleGet("configured", action)
+				registry.HandleAny(FlamingoNotfound, func(context.Context, *Request) Result { return &Response{Status: http.StatusNotFound} })
+
+				registry.MustRoute("/plain", "plain")
+				registry.MustRoute("/route", "route").WithFilters(filter("route1"), filter("route2"))
+
+				group := registry.Group("/api/", filter("group"))
+				group.MustRoute("/group", "group")
+				group.Group("/v1", filter("v1")).MustRoute("/nested", "nested").WithFilters(filter("route"))
+			})}
+		},
+		logger:     flamingo.NullLogger{},
+		configArea: area,
+	}
+	h := router.Handler()
+
+	for _, tt := range []struct {
+		path     string
+		expected []string
+	}{
+		{path: "/plain", expected: []string{"global", "action"}},
+		{path: "/route", expected: []string{"global", "route1", "route2", "action"}},
+		{path: "/api/group", expected: []string{"global", "group", "action"}},
+		{path: "/api/v1/nested", expected: []string{"global", "group", "v1", "route", "action"}},
+		{path: "/configured", expected: []string{"global", "named", "action"}},
+		{path: "/unknown", expected: []string{"global"}},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			calls = nil
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.expected, calls)
+		})
+	}
+
+	t.Run("unknown named filter", func(t *testing.T) {
+		area := config.NewArea("test", nil)
+		area.Routes = []config.Route{{Path: "/configured", Controller: "configured", Filters: []string{"unknown"}}}
+		router := &Router{
+			filterProvider: func() []Filter { return nil },
+			routesProvider: func() []RoutesModule { return nil },
+			logger:         flamingo.NullLogger{},
+			configArea:     area,
+		}
+		assert.Panics(t, func() { router.Handler() })
+	})
+}
+
+type handlerNameFilter struct {
+	name, preflightName *string
+}
+
+func (f *handlerNameFilter) Filter(ctx context.Context, req *Request, w http.ResponseWriter, fc *FilterChain) Result {
+	*f.name, *f.preflightName = "", ""
+	if handler := HandlerFromContext(ctx); handler != nil {
+		*f.name = handler.GetHandlerName()
+	}
+	if handler := PreflightHandlerFromContext(ctx); handler != nil {
+		*f.preflightName = handler.GetHandlerName()
+	}
+	return fc.Next(ctx, req, w)
+}
+
+func TestHandlerFromContext(t *testing.T) {
+	var name, preflightName string
+
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return []Filter{&handlerNameFilter{name: &name, preflightName: &preflightName}} },
+		routesProvider: func() []RoutesModule {
+			return []RoutesModule{testRoutesModule(func(registry *RouterRegistry) {
+				action := func(context.Context, *Request) Result { return &Response{Status: http.StatusOK} }
+				registry.HandleGet("item.show", action)
+				registry.HandlePut("item.update", action)
+				registry.HandleAny(FlamingoNotfound, func(context.Context, *Request) Result { return &Response{Status: http.StatusNotFound} })
+
+				registry.MustRoute("/item", "item.show")
+				registry.MustRoute("/item", "item.update")
+			})}
+		},
+		logger:      flamingo.NullLogger{},
+		configArea:  config.NewArea("test", nil),
+		autoOptions: true,
+	}
+	h := router.Handler()
+
+	for _, tt := range []struct {
+		name              string
+		method            string
+		path              string
+		preflight         string
+		expected          string
+		expectedPreflight string
+	}{
+		{name: "get", method: http.MethodGet, path: "/item", expected: "item.show"},
+		{name: "put", method: http.MethodPut, path: "/item", expected: "item.update"},
+		{name: "options", method: http.MethodOptions, path: "/item", expected: ""},
+		{name: "preflight", method: http.MethodOptions, path: "/item", preflight: http.MethodPut, expected: "", expectedPreflight: "item.update"},
+		{name: "preflight unknown method", method: http.MethodOptions, path: "/item", preflight: http.MethodDelete, expected: ""},
+		{name: "unknown path", method: http.MethodGet, path: "/unknown", expected: ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.preflight != "" {
+				request.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.expected, name)
+			assert.Equal(t, tt.expectedPreflight, preflightName)
+			if tt.method == http.MethodOptions {
+				assert.Equal(t, http.StatusNoContent, recorder.Code, "the preflight route does not change the action")
+			}
+		})
+	}
+}
+
+func TestRouterTimeout(t *testing.T) {
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return nil },
+		routesProvider: func() []RoutesModule { return nil },
+		logger:         flamingo.NullLogger{},
+		timeout:        10 * time.Millisecond,
+	}
+	h := router.Handler()
+
+	registry := NewRegistry()
+	h.(*handler).routerRegistry = registry
+
+	var routerError error
+	registry.HandleAny(FlamingoError, func(ctx context.Context, _ *Request) Result {
+		routerError = ctx.Value(RouterError).(error)
+		return &Response{Status: http.StatusServiceUnavailable}
+	})
+	wait := func(ctx context.Context, _ *Request) Result {
+		select {
+		case <-ctx.Done():
+			return &Response{Status: http.StatusInternalServerError}
+		case <-time.After(50 * time.Millisecond):
+			return &Response{Status: http.StatusOK}
+		}
+	}
+	registry.HandleGet("slow", wait)
+	registry.HandleGet("override", wait)
+	registry.MustRoute("/slow", "slow")
+	registry.MustRoute("/override", "override").WithTimeout(time.Second)
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.True(t, errors.Is(routerError, ErrRequestTimeout))
+
+	routerError = nil
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/override", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, routerError)
+}
+
+type (
+	forkFilter struct {
+		forked *Request
+		writer http.ResponseWriter
+	}
+
+	responseEventRouter struct {
+		events []*OnResponseEvent
+	}
+)
+
+func (f *forkFilter) Filter(ctx context.Context, req *Request, w http.ResponseWriter, fc *FilterChain) Result {
+	f.forked = CreateRequest(req.Request().Clone(ctx), nil)
+	f.writer = httptest.NewRecorder()
+	fc.Fork().Next(ctx, f.forked, f.writer)
+	return fc.Next(ctx, req, w)
+}
+
+func (e *responseEventRouter) Dispatch(_ context.Context, event flamingo.Event) {
+	if event, ok := event.(*OnResponseEvent); ok {
+		e.events = append(e.events, event)
+	}
+}
+
+func TestForkedFilterChain(t *testing.T) {
+	fork := new(forkFilter)
+	events := new(responseEventRouter)
+
+	router := &Router{
+		eventRouter:    events,
+		filterProvider: func() []Filter { return []Filter{fork} },
+		routesProvider: func() []RoutesModule {
+			return []RoutesModule{testRoutesModule(func(registry *RouterRegistry) {
+				registry.HandleGet("page", func(context.Context, *Request) Result { return &Response{Status: http.StatusOK} })
+				registry.MustRoute("/page", "page")
+			})}
+		},
+		logger: flamingo.NullLogger{},
+	}
+
+	recorder := httptest.NewRecorder()
+	router.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	require.Len(t, events.events, 2)
+	assert.Same(t, fork.forked, events.events[0].Request)
+	assert.Same(t, fork.writer, events.events[0].ResponseWriter)
+	assert.True(t, fork.forked != events.events[1].Request)
+}
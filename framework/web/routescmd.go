@@ -1,8 +1,10 @@
 package web
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"os"
 	"sort"
 	"strings"
 
@@ -23,6 +25,8 @@ func RoutesCmd(router *Router, area *config.Area) *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(routesMatchCmd(router, area))
+
 	return cmd
 }
 
@@ -42,6 +46,35 @@ func HandlerCmd(router *Router, area *config.Area) *cobra.Command {
 	return cmd
 }
 
+// OpenAPICmd generates the OpenAPI 3 document of all routes
+func OpenAPICmd(generator *OpenAPIGenerator) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "openapi",
+		Short: "Generate the OpenAPI 3 document of all routes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(generator.Document())
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the document to a file instead of stdout")
+
+	return cmd
+}
+
 func dumpRoutes(router *Router, area *config.Area) {
 	if router == nil {
 		return
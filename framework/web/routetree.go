@@ -0,0 +1,193 @@
+package web
+
+import (
+	"sort"
+	"strings"
+)
+
+type (
+	// routeTree is a compiled radix index over all registered route paths.
+	// Fixed parts are stored as literal edges (including their leading `/`), dynamic parts as matcher nodes.
+	// The routes are kept along with the tree, so the indexes of matches refer to the routes the tree was built from.
+	routeTree struct {
+		root   *routeNode
+		routes []*Handler
+	}
+
+	routeNode struct {
+		prefix  string
+		part    part
+		key     string
+		static  []*routeNode
+		dynamic []*routeNode
+		routes  []int
+	}
+
+	// routeMatch is a single route matching a path, index is the registration order.
+	// err is set if the route only matches when ignoring the type of a param.
+	routeMatch struct {
+		index  int
+		values map[string]string
+		err    *ParamError
+	}
+
+	routeValue struct {
+		key, value string
+	}
+)
+
+func newRouteTree(routes []*Handler) *routeTree {
+	tree := &routeTree{root: new(routeNode), routes: routes}
+	for i, route := range routes {
+		tree.insert(i, route.path)
+	}
+	return tree
+}
+
+func (t *routeTree) insert(index int, path *Path) {
+	node := t.root
+	literal := ""
+
+	for _, p := range path.parts {
+		if fixed, ok := p.(*partFixed); ok {
+			literal += "/" + fixed.part
+			continue
+		}
+
+		node = node.insertLiteral(literal + "/")
+		literal = ""
+		node = node.insertDynamic(p)
+	}
+
+	node = node.insertLiteral(literal)
+	node.routes = append(node.routes, index)
+}
+
+func (n *routeNode) insertLiteral(literal string) *routeNode {
+	for literal != "" {
+		var child *routeNode
+		for _, c := range n.static {
+			if c.prefix[0] == literal[0] {
+				child = c
+				break
+			}
+		}
+
+		if child == nil {
+			child = &routeNode{prefix: literal}
+			n.static = append(n.static, child)
+			return child
+		}
+
+		common := commonPrefixLength(child.prefix, literal)
+		if common < len(child.prefix) {
+			// split the existing edge
+			split := &routeNode{
+				prefix:  child.prefix[common:],
+				part:    child.part,
+				key:     child.key,
+				static:  child.static,
+				dynamic: child.dynamic,
+				routes:  child.routes,
+			}
+			*child = routeNode{
+				prefix: child.prefix[:common],
+				static: []*routeNode{split},
+			}
+		}
+
+		n = child
+		literal = literal[common:]
+	}
+
+	return n
+}
+
+func (n *routeNode) insertDynamic(p part) *routeNode {
+	key := partKey(p)
+	for _, c := range n.dynamic {
+		if c.key == key {
+			return c
+		}
+	}
+
+	child := &routeNode{part: p, key: key}
+	n.dynamic = append(n.dynamic, child)
+	return child
+}
+
+// partKey identifies dynamic parts which match exactly the same way and can share a node
+func partKey(p part) string {
+	switch p := p.(type) {
+	case *partParam:
+		return ":" + p.name + "|" + p.suffix + "|" + p.typeName
+	case *partRegex:
+		return "$" + p.name + "|" + p.regex.String()
+	case *partWildcard:
+		return "*" + p.name
+	}
+	return ""
+}
+
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// match returns all routes matching the path, ordered by their registration.
+// With invalid set, routes which do not match because of an invalid typed param are returned as well, with their error.
+func (t *routeTree) match(path string, invalid bool) []routeMatch {
+	var matches []routeMatch
+	t.root.match(path, nil, nil, invalid, &matches)
+
+	if len(matches) > 1 {
+		sort.Slice(matches, func(i, j int) bool { return matches[i].index < matches[j].index })
+	}
+
+	return matches
+}
+
+func (n *routeNode) match(path string, values []routeValue, err *ParamError, invalid bool, matches *[]routeMatch) {
+	if len(n.routes) > 0 && (path == "" || path == "/") {
+		for _, index := range n.routes {
+			m := routeMatch{index: index, values: make(map[string]string, len(values)), err: err}
+			for _, v := range values {
+				m.values[v.key] = v.value
+			}
+			*matches = append(*matches, m)
+		}
+	}
+
+	if path != "" {
+		for _, c := range n.static {
+			if c.prefix[0] == path[0] {
+				if strings.HasPrefix(path, c.prefix) {
+					c.match(path[len(c.prefix):], values, err, invalid, matches)
+				}
+				break
+			}
+		}
+	}
+
+	for _, c := range n.dynamic {
+		matched, key, value, length := c.part.match(path)
+		childErr := err
+		if param, ok := c.part.(*partParam); ok && !matched && invalid {
+			// descend ignoring the type, the first invalid typed param is reported if a route matches otherwise
+			if matched, key, value, length = param.matchUntyped(path); matched && childErr == nil {
+				childErr = param.paramError(value)
+			}
+		}
+		if !matched {
+			continue
+		}
+		if key != "" {
+			c.match(path[length:], append(values, routeValue{key: key, value: value}), childErr, invalid, matches)
+		} else {
+			c.match(path[length:], values, childErr, invalid, matches)
+		}
+	}
+}
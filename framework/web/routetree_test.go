@@ -0,0 +1,167 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// linearMatch is the reference implementation: every route is matched one by one
+func linearMatch(registry *RouterRegistry, path string) []routeMatch {
+	var matches []routeMatch
+	for i, route := range registry.routes {
+		if match := route.path.Match(path); match != nil {
+			matches = append(matches, routeMatch{index: i, values: match.Values})
+		}
+	}
+	return matches
+}
+
+func TestRouteTree(t *testing.T) {
+	registry := NewRegistry()
+	for _, path := range []string{
+		`/`,
+		`/page`,
+		`/page/`,
+		`/page/:page`,
+		`/page/:page.html`,
+		`/page/:page/edit`,
+		`/page/:other`,
+		`/pages`,
+		`/pages/*rest`,
+		`/path/to/:something/$id<[0-9]+>/*foo`,
+		`/path/to/$<[0-9]+>/$id<[0-9]+>`,
+		`/path/to/$foo<.*>`,
+		`/path/to/something`,
+		`/path/tox`,
+		`/sitemap/$id<product-(\d+).xml>`,
+		`/sitemap/:name`,
+		`/typed/:id<int>`,
+		`/typed/:slug`,
+		`/*catchall`,
+	} {
+		_, err := registry.Route(path, "handler")
+		require.NoError(t, err)
+	}
+
+	for _, path := range []string{
+		`/`,
+		``,
+		`/page`,
+		`/page/`,
+		`/page//`,
+		`/page/foo`,
+		`/page/foo/`,
+		`/page/foo.html`,
+		`/page/foo/edit`,
+		`/page/foo/edit/`,
+		`/page/foo/bar`,
+		`/pag`,
+		`/pages`,
+		`/pages/a/b/c`,
+		`/path/to/something`,
+		`/path/to/something123/445566/foo/bar`,
+		`/path/to/10/20`,
+		`/path/to/10/`,
+		`/path/to//20`,
+		`/path/to/`,
+		`/path/tox`,
+		`/path/toxy`,
+		`/sitemap/product-1.xml`,
+		`/sitemap/product-test.xml`,
+		`/typed/12`,
+		`/typed/foo`,
+		`/unknown/path`,
+	} {
+		t.Run(path, func(t *testing.T) {
+			expected := linearMatch(registry, path)
+			actual := registry.routeTree().match(path, false)
+			if len(expected) == 0 {
+				assert.Empty(t, actual)
+				return
+			}
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	t.Run("tree is rebuilt after new routes are added", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.HandleAny("a", testController)
+		registry.HandleAny("b", testController)
+		_, err := registry.Route("/a", "a")
+		require.NoError(t, err)
+
+		request, _ := http.NewRequest(http.MethodGet, "/b", nil)
+		_, _, handler := registry.matchRequest(request)
+		assert.Nil(t, handler)
+
+		_, err = registry.Route("/b", "b")
+		require.NoError(t, err)
+
+		_, _, handler = registry.matchRequest(request)
+		require.NotNil(t, handler)
+		assert.Equal(t, "b", handler.GetHandlerName())
+	})
+}
+
+func benchmarkRegistry(b *testing.B, areas int) *RouterRegistry {
+	registry := NewRegistry()
+	for i := 0; i < areas; i++ {
+		for _, path := range []string{
+			"/area%d",
+			"/area%d/product/:id",
+			"/area%d/product/:id/reviews",
+			"/area%d/category/*path",
+			"/area%d/cms/$page<[a-z-]+\\.html>",
+			"/area%d/checkout/cart",
+			"/area%d/checkout/payment",
+			"/area%d/checkout/success",
+			"/area%d/account/orders/:order",
+			"/area%d/api/v1/:resource/:id",
+		} {
+			name := fmt.Sprintf(path, i)
+			registry.HandleGet(name, testController)
+			_, err := registry.Route(fmt.Sprintf(path, i), name)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return registry
+}
+
+func BenchmarkRouteMatching(b *testing.B) {
+	for _, areas := range []int{1, 10, 50} {
+		registry := benchmarkRegistry(b, areas)
+		path := fmt.Sprintf("/area%d/account/orders/12345", areas-1)
+
+		b.Run(fmt.Sprintf("linear/%d-routes", len(registry.routes)), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				linearMatch(registry, path)
+			}
+		})
+
+		b.Run(fmt.Sprintf("tree/%d-routes", len(registry.routes)), func(b *testing.B) {
+			registry.routeTree()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				registry.routeTree().match(path, false)
+			}
+		})
+
+		b.Run(fmt.Sprintf("matchRequest/%d-routes", len(registry.routes)), func(b *testing.B) {
+			request, _ := http.NewRequest(http.MethodGet, path, nil)
+			registry.routeTree()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				registry.matchRequest(request)
+			}
+		})
+	}
+}
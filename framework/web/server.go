@@ -0,0 +1,292 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	// Server builds and runs the http.Server of the serve commands, configured via flamingo.web.server
+	Server struct {
+		logger      flamingo.Logger
+		eventRouter flamingo.EventRouter
+
+		readTimeout       time.Duration
+		readHeaderTimeout time.Duration
+		writeTimeout      time.Duration
+		idleTimeout       time.Duration
+		maxHeaderBytes    int
+		certFile          string
+		keyFile           string
+		h2c               bool
+		drain             time.Duration
+		shutdownTimeout   time.Duration
+		socket            string
+		fd                int
+
+		mu       sync.Mutex
+		server   *http.Server
+		address  string
+		draining int32
+
+		certificateMu sync.RWMutex
+		certificate   *tls.Certificate
+	}
+)
+
+// Inject Server dependencies
+func (s *Server) Inject(logger flamingo.Logger, eventRouter flamingo.EventRouter, cfg *struct {
+	// timeouts in milliseconds
+	ReadTimeout       float64 `inject:"config:flamingo.web.server.readTimeout,optional"`
+	ReadHeaderTimeout float64 `inject:"config:flamingo.web.server.readHeaderTimeout,optional"`
+	WriteTimeout      float64 `inject:"config:flamingo.web.server.writeTimeout,optional"`
+	IdleTimeout       float64 `inject:"config:flamingo.web.server.idleTimeout,optional"`
+	MaxHeaderBytes    float64 `inject:"config:flamingo.web.server.maxHeaderBytes,optional"`
+	CertFile          string  `inject:"config:flamingo.web.server.tls.certFile,optional"`
+	KeyFile           string  `inject:"config:flamingo.web.server.tls.keyFile,optional"`
+	H2C               bool    `inject:"config:flamingo.web.server.h2c,optional"`
+	Drain             float64 `inject:"config:flamingo.web.server.drain,optional"`
+	ShutdownTimeout   float64 `inject:"config:flamingo.web.server.shutdownTimeout,optional"`
+	Socket            string  `inject:"config:flamingo.web.server.socket,optional"`
+	FD                float64 `inject:"config:flamingo.web.server.fd,optional"`
+}) *Server {
+	s.logger = logger.WithField(flamingo.LogKeyModule, "web").WithField(flamingo.LogKeyCategory, "server")
+	s.eventRouter = eventRouter
+	if cfg != nil {
+		s.readTimeout = time.Duration(cfg.ReadTimeout) * time.Millisecond
+		s.readHeaderTimeout = time.Duration(cfg.ReadHeaderTimeout) * time.Millisecond
+		s.writeTimeout = time.Duration(cfg.WriteTimeout) * time.Millisecond
+		s.idleTimeout = time.Duration(cfg.IdleTimeout) * time.Millisecond
+		s.maxHeaderBytes = int(cfg.MaxHeaderBytes)
+		s.certFile = cfg.CertFile
+		s.keyFile = cfg.KeyFile
+		s.h2c = cfg.H2C
+		s.drain = time.Duration(cfg.Drain) * time.Millisecond
+		s.shutdownTimeout = time.Duration(cfg.ShutdownTimeout) * time.Millisecond
+		s.socket = cfg.Socket
+		s.fd = int(cfg.FD)
+	}
+	if s.shutdownTimeout <= 0 {
+		s.shutdownTimeout = 5 * time.Second
+	}
+	return s
+}
+
+// Serve the handler until the server is shut down, which returns http.ErrServerClosed.
+// The server listens on the configured unix socket or inherited file descriptor, or on the TCP address otherwise.
+// The ServerStartEvent and ServerShutdownEvent are dispatched around serving.
+func (s *Server) Serve(addr string, handler http.Handler) error {
+	useTLS := s.certFile != "" || s.keyFile != ""
+	if useTLS {
+		if err := s.ReloadCertificate(); err != nil {
+			return err
+		}
+		stop := s.reloadOnSignal()
+		defer stop()
+	}
+
+	listener, address, err := s.listen(addr)
+	if err != nil {
+		return err
+	}
+
+	server := s.build(addr, handler, useTLS)
+	s.mu.Lock()
+	s.server = server
+	s.address = address
+	s.mu.Unlock()
+
+	s.logger.Info("Starting HTTP Server at ", address, " .....")
+	s.eventRouter.Dispatch(context.Background(), &flamingo.ServerStartEvent{Port: address})
+	defer s.eventRouter.Dispatch(context.Background(), &flamingo.ServerShutdownEvent{})
+
+	if useTLS {
+		return server.ServeTLS(listener, "", "")
+	}
+	return server.Serve(listener)
+}
+
+// Addr returns the address the server listens on, prefixed with unix: or fd: for sockets and file descriptors
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.address
+}
+
+// build the http.Server
+func (s *Server) build(addr string, handler http.Handler, useTLS bool) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       s.readTimeout,
+		ReadHeaderTimeout: s.readHeaderTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
+		MaxHeaderBytes:    s.maxHeaderBytes,
+	}
+
+	if useTLS {
+		// HTTP/2 is negotiated via ALPN by the http.Server
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.getCertificate,
+		}
+	} else if s.h2c {
+		server.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: s.idleTimeout})
+	}
+
+	return server
+}
+
+// listen on the inherited file descriptor, the unix socket or the TCP address
+func (s *Server) listen(addr string) (net.Listener, string, error) {
+	if s.fd > 0 {
+		file := os.NewFile(uintptr(s.fd), "listener")
+		if file == nil {
+			return nil, "", fmt.Errorf("server: invalid file descriptor %d", s.fd)
+		}
+		defer file.Close()
+
+		// FileListener duplicates the file descriptor, so the file can be closed
+		listener, err := net.FileListener(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("server: listen on file descriptor %d: %w", s.fd, err)
+		}
+		return listener, "fd:" + strconv.Itoa(s.fd), nil
+	}
+
+	if s.socket != "" {
+		if err := removeStaleSocket(s.socket); err != nil {
+			return nil, "", err
+		}
+		listener, err := net.Listen("unix", s.socket)
+		if err != nil {
+			return nil, "", fmt.Errorf("server: listen on unix socket %s: %w", s.socket, err)
+		}
+		return listener, "unix:" + s.socket, nil
+	}
+
+	if addr == "" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("server: listen on %s: %w", addr, err)
+	}
+	return listener, listener.Addr().String(), nil
+}
+
+// removeStaleSocket removes the socket file of a previous process, as long as no one accepts connections on it
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("server: unix socket %s is in use", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("server: remove stale unix socket %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReloadCertificate loads the TLS certificate and key files. If they are invalid, the previous certificate stays in use.
+// The server reloads them on SIGHUP.
+func (s *Server) ReloadCertificate() error {
+	certificate, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("server: load tls certificate: %w", err)
+	}
+
+	s.certificateMu.Lock()
+	s.certificate = &certificate
+	s.certificateMu.Unlock()
+
+	return nil
+}
+
+func (s *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.certificateMu.RLock()
+	defer s.certificateMu.RUnlock()
+	return s.certificate, nil
+}
+
+// reloadOnSignal reloads the certificate on SIGHUP until stop is called
+func (s *Server) reloadOnSignal() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := s.ReloadCertificate(); err != nil {
+					s.logger.Error(err)
+					continue
+				}
+				s.logger.Info("reloaded tls certificate ", s.certFile)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// Shutdown drains the server: for the configured drain period keep-alives are disabled, but new requests are still served,
+// e.g. until a load balancer stops sending traffic. Afterwards active requests are awaited until the shutdown timeout.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.server
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	// a repeated shutdown, e.g. on the ServerShutdownEvent and the ShutdownEvent, does not drain again
+	first := atomic.CompareAndSwapInt32(&s.draining, 0, 1)
+	server.SetKeepAlivesEnabled(false)
+
+	if first && s.drain > 0 {
+		s.logger.Info("draining server for ", s.drain)
+		select {
+		case <-time.After(s.drain):
+		case <-ctx.Done():
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.shutdownTimeout)
+	defer cancel()
+
+	return server.Shutdown(ctx)
+}
+
+// Draining reports if the server is shutting down, e.g. to fail readiness checks during the drain period
+func (s *Server) Draining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
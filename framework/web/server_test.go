@@ -0,0 +1,324 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	serverTestConfig = struct {
+		ReadTimeout       float64 `inject:"config:flamingo.web.server.readTimeout,optional"`
+		ReadHeaderTimeout float64 `inject:"config:flamingo.web.server.readHeaderTimeout,optional"`
+		WriteTimeout      float64 `inject:"config:flamingo.web.server.writeTimeout,optional"`
+		IdleTimeout       float64 `inject:"config:flamingo.web.server.idleTimeout,optional"`
+		MaxHeaderBytes    float64 `inject:"config:flamingo.web.server.maxHeaderBytes,optional"`
+		CertFile          string  `inject:"config:flamingo.web.server.tls.certFile,optional"`
+		KeyFile           string  `inject:"config:flamingo.web.server.tls.keyFile,optional"`
+		H2C               bool    `inject:"config:flamingo.web.server.h2c,optional"`
+		Drain             float64 `inject:"config:flamingo.web.server.drain,optional"`
+		ShutdownTimeout   float64 `inject:"config:flamingo.web.server.shutdownTimeout,optional"`
+		Socket            string  `inject:"config:flamingo.web.server.socket,optional"`
+		FD                float64 `inject:"config:flamingo.web.server.fd,optional"`
+	}
+
+	serverTestEventRouter struct {
+		mu     sync.Mutex
+		events []flamingo.Event
+	}
+)
+
+func (e *serverTestEventRouter) Dispatch(_ context.Context, event flamingo.Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *serverTestEventRouter) dispatched() []flamingo.Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]flamingo.Event(nil), e.events...)
+}
+
+var serverTestHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "hello "+r.Proto)
+})
+
+// startServer serves the test handler and waits until the server listens
+func startServer(t *testing.T, server *Server, addr string) <-chan error {
+	t.Helper()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(addr, serverTestHandler)
+	}()
+
+	require.True(t, waitFor(func() bool { return server.Addr() != "" }), "server does not listen")
+	return served
+}
+
+// waitFor polls the condition for up to a second
+func waitFor(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+func stopServer(t *testing.T, server *Server, served <-chan error) {
+	t.Helper()
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	select {
+	case err := <-served:
+		assert.Equal(t, http.ErrServerClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("server is not shut down")
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	t.Helper()
+
+	response, err := client.Get(url)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response, string(body)
+}
+
+func TestServer_build(t *testing.T) {
+	server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{
+		ReadTimeout:       1000,
+		ReadHeaderTimeout: 2000,
+		WriteTimeout:      3000,
+		IdleTimeout:       4000,
+		MaxHeaderBytes:    4096,
+	})
+
+	httpServer := server.build(":3322", serverTestHandler, false)
+	assert.Equal(t, ":3322", httpServer.Addr)
+	assert.Equal(t, time.Second, httpServer.ReadTimeout)
+	assert.Equal(t, 2*time.Second, httpServer.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, httpServer.WriteTimeout)
+	assert.Equal(t, 4*time.Second, httpServer.IdleTimeout)
+	assert.Equal(t, 4096, httpServer.MaxHeaderBytes)
+	assert.Nil(t, httpServer.TLSConfig)
+	assert.Equal(t, 5*time.Second, server.shutdownTimeout, "the shutdown timeout defaults to 5 seconds")
+}
+
+func TestServer_Serve(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) {
+		eventRouter := new(serverTestEventRouter)
+		server := new(Server).Inject(flamingo.NullLogger{}, eventRouter, nil)
+
+		served := startServer(t, server, "127.0.0.1:0")
+		response, body := get(t, http.DefaultClient, "http://"+server.Addr())
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "hello HTTP/1.1", body)
+
+		stopServer(t, server, served)
+		assert.Equal(t, []flamingo.Event{&flamingo.ServerStartEvent{Port: server.Addr()}, &flamingo.ServerShutdownEvent{}}, eventRouter.dispatched())
+	})
+
+	t.Run("unix socket", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "flamingo-server")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		socket := filepath.Join(dir, "flamingo.sock")
+
+		// a stale socket of a previous process is replaced
+		stale, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, stale.Close())
+
+		server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{Socket: socket})
+		served := startServer(t, server, ":3322")
+		assert.Equal(t, "unix:"+socket, server.Addr())
+
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return new(net.Dialer).DialContext(ctx, "unix", socket)
+			},
+		}}
+		_, body := get(t, client, "http://flamingo/")
+		assert.Equal(t, "hello HTTP/1.1", body)
+
+		inUse := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{Socket: socket})
+		assert.EqualError(t, inUse.Serve("", serverTestHandler), "server: unix socket "+socket+" is in use")
+
+		stopServer(t, server, served)
+	})
+
+	t.Run("inherited file descriptor", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		file, err := listener.(*net.TCPListener).File()
+		require.NoError(t, err)
+		defer file.Close()
+
+		server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{FD: float64(file.Fd())})
+		served := startServer(t, server, ":3322")
+
+		_, body := get(t, http.DefaultClient, "http://"+listener.Addr().String())
+		assert.Equal(t, "hello HTTP/1.1", body)
+
+		stopServer(t, server, served)
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{H2C: true})
+		served := startServer(t, server, "127.0.0.1:0")
+
+		conn, err := net.Dial("tcp", server.Addr())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// HTTP/2 with prior knowledge: the server answers the connection preface with a SETTINGS frame
+		_, err = io.WriteString(conn, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+		require.NoError(t, err)
+		frameHeader := make([]byte, 9)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		_, err = io.ReadFull(conn, frameHeader)
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x4), frameHeader[3], "SETTINGS frame")
+
+		stopServer(t, server, served)
+	})
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flamingo-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeServerTestCertificate(t, certFile, keyFile, 1)
+
+	server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{CertFile: certFile, KeyFile: keyFile})
+	served := startServer(t, server, "127.0.0.1:0")
+
+	serial := func() int64 {
+		conn, err := tls.Dial("tcp", server.Addr(), &tls.Config{InsecureSkipVerify: true})
+		require.NoError(t, err)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(1), serial())
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	_, body := get(t, client, "https://"+server.Addr())
+	assert.Equal(t, "hello HTTP/1.1", body)
+
+	t.Run("reload on SIGHUP", func(t *testing.T) {
+		writeServerTestCertificate(t, certFile, keyFile, 2)
+
+		process, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		require.NoError(t, process.Signal(syscall.SIGHUP))
+
+		assert.True(t, waitFor(func() bool { return serial() == 2 }), "certificate is not reloaded")
+	})
+
+	t.Run("invalid files keep the certificate", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+		assert.Error(t, server.ReloadCertificate())
+		assert.Equal(t, int64(2), serial())
+	})
+
+	stopServer(t, server, served)
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	t.Run("not started", func(t *testing.T) {
+		server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), nil)
+		assert.NoError(t, server.Shutdown(context.Background()))
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		server := new(Server).Inject(flamingo.NullLogger{}, new(serverTestEventRouter), &serverTestConfig{Drain: 200})
+		served := startServer(t, server, "127.0.0.1:0")
+		assert.False(t, server.Draining())
+
+		shutdown := make(chan error, 1)
+		go func() {
+			shutdown <- server.Shutdown(context.Background())
+		}()
+		require.True(t, waitFor(server.Draining), "server is not draining")
+
+		// requests are still served during the drain period, but connections are closed afterwards
+		conn, err := net.Dial("tcp", server.Addr())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: flamingo\r\n\r\n")
+		require.NoError(t, err)
+		response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.True(t, response.Close)
+
+		select {
+		case <-shutdown:
+			t.Fatal("shutdown before the drain period")
+		default:
+		}
+
+		require.NoError(t, <-shutdown)
+		assert.Equal(t, http.ErrServerClosed, <-served)
+
+		start := time.Now()
+		assert.NoError(t, server.Shutdown(context.Background()))
+		assert.True(t, time.Since(start) < 200*time.Millisecond, "a repeated shutdown drains again")
+	})
+}
+
+// writeServerTestCertificate writes a self-signed certificate with the serial number
+func writeServerTestCertificate(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "flamingo"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
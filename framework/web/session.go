@@ -16,6 +16,7 @@ type Session struct {
 	hashedid        string
 	dirty           map[interface{}]struct{}
 	dirtyAll        bool
+	released        bool
 	sessionSaveMode sessionPersistLevel
 }
 
@@ -49,6 +50,14 @@ func SessionFromContext(ctx context.Context) *Session {
 	return session
 }
 
+// release marks the session as already persisted, e.g. for long-lived responses, so it is not saved again
+func (s *Session) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.released = true
+}
+
 func (s *Session) markDirty(key interface{}) {
 	// do not mark dirty sessions when session save mode is set to always
 	if s.sessionSaveMode == sessionSaveAlways {
@@ -132,6 +141,14 @@ func (s *Session) Keys() []interface{} {
 	return keys
 }
 
+// IsEmpty checks if the session holds no values, e.g. for anonymous visitors
+func (s *Session) IsEmpty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.s.Values) == 0
+}
+
 // ClearAll removes all values from the session
 // Deprecated: do not use ClearAll
 func (s *Session) ClearAll() *Session {
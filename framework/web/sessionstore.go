@@ -113,6 +113,10 @@ func (s *SessionStore) Save(ctx context.Context, session *Session) (http.Header,
 	session.mu.Lock()
 	defer session.mu.Unlock()
 
+	if session.released {
+		return nil, nil
+	}
+
 	gs := session.s
 
 	// copy dirty values to new instance and move Values to original session
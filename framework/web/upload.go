@@ -0,0 +1,207 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"sync"
+
+	"go.opencensus.io/trace"
+)
+
+type (
+	// Upload is a file of a multipart request, streamed into a temporary file
+	Upload struct {
+		// Field is the name of the form field
+		Field string
+		// Filename is the name of the file as sent by the client
+		Filename string
+		Header   textproto.MIMEHeader
+		Size     int64
+		// Path is the temporary file, which is removed after the request finished
+		Path string
+	}
+
+	// uploadConfig limits the uploads of a request, sizes are in bytes and 0 disables a limit
+	uploadConfig struct {
+		tempDir      string
+		maxFileSize  int64
+		maxTotalSize int64
+	}
+
+	uploadState struct {
+		once    sync.Once
+		mu      sync.Mutex
+		uploads []*Upload
+		err     error
+	}
+)
+
+var (
+	// ErrUploadTooLarge is returned if an upload exceeds the configured file or total size
+	ErrUploadTooLarge = errors.New("upload too large")
+
+	// ErrUploadNotFound is returned for unknown upload fields
+	ErrUploadNotFound = errors.New("upload not found")
+)
+
+// Uploads reads the multipart body and stores all files in the configured temporary directory.
+// The other form fields are available via Form afterwards. The body is read once, subsequent calls return the same uploads.
+func (r *Request) Uploads(ctx context.Context) ([]*Upload, error) {
+	r.upload.once.Do(func() {
+		r.upload.uploads, r.upload.err = r.readUploads(ctx)
+	})
+
+	return r.upload.uploads, r.upload.err
+}
+
+// Upload returns the first uploaded file of the field
+func (r *Request) Upload(ctx context.Context, field string) (*Upload, error) {
+	uploads, err := r.Uploads(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range uploads {
+		if upload.Field == field {
+			return upload, nil
+		}
+	}
+
+	return nil, ErrUploadNotFound
+}
+
+// Open opens the temporary file of the upload
+func (u *Upload) Open() (*os.File, error) {
+	return os.Open(u.Path)
+}
+
+func (r *Request) readUploads(ctx context.Context) ([]*Upload, error) {
+	_, span := trace.StartSpan(ctx, "web/request/uploads")
+	defer span.End()
+
+	reader, err := r.request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	config := r.uploadConfig
+	if config == nil {
+		config = new(uploadConfig)
+	}
+
+	values := make(url.Values)
+	var total int64
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return r.uploadFailed(err)
+		}
+
+		// the remaining total size is the limit, unless the file size limit is lower, -1 means unlimited
+		limit, tooLarge := int64(-1), fmt.Errorf("%w: the request exceeds the total size of %d bytes", ErrUploadTooLarge, config.maxTotalSize)
+		if config.maxTotalSize > 0 {
+			limit = config.maxTotalSize - total
+		}
+
+		if part.FileName() == "" {
+			value := new(bytes.Buffer)
+			n, err := copyLimited(value, part, limit)
+			if err != nil {
+				return r.uploadFailed(err)
+			}
+			if limit >= 0 && n > limit {
+				return r.uploadFailed(tooLarge)
+			}
+			total += n
+			values.Add(part.FormName(), value.String())
+			continue
+		}
+
+		if config.maxFileSize > 0 && (limit < 0 || config.maxFileSize < limit) {
+			limit = config.maxFileSize
+			tooLarge = fmt.Errorf("%w: file %q of field %q exceeds %d bytes", ErrUploadTooLarge, part.FileName(), part.FormName(), config.maxFileSize)
+		}
+
+		upload, err := r.storeUpload(part, config.tempDir, limit)
+		if err != nil {
+			return r.uploadFailed(err)
+		}
+		if limit >= 0 && upload.Size > limit {
+			return r.uploadFailed(tooLarge)
+		}
+		total += upload.Size
+	}
+
+	r.request.MultipartForm = &multipart.Form{Value: values}
+	r.request.PostForm = values
+	r.request.Form = make(url.Values, len(values))
+	for name, value := range r.request.URL.Query() {
+		r.request.Form[name] = value
+	}
+	for name, value := range values {
+		r.request.Form[name] = append(value, r.request.Form[name]...)
+	}
+
+	return r.upload.uploads, nil
+}
+
+// storeUpload streams the part into a temporary file, the upload is registered for cleanup before it is written.
+// At most limit+1 bytes are written, so exceeding the limit can be detected.
+func (r *Request) storeUpload(part *multipart.Part, tempDir string, limit int64) (*Upload, error) {
+	file, err := ioutil.TempFile(tempDir, "flamingo-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	upload := &Upload{
+		Field:    part.FormName(),
+		Filename: part.FileName(),
+		Header:   part.Header,
+		Path:     file.Name(),
+	}
+
+	r.upload.mu.Lock()
+	r.upload.uploads = append(r.upload.uploads, upload)
+	r.upload.mu.Unlock()
+
+	upload.Size, err = copyLimited(file, part, limit)
+	return upload, err
+}
+
+// uploadFailed removes the already stored files, as they are incomplete
+func (r *Request) uploadFailed(err error) ([]*Upload, error) {
+	r.removeUploads()
+	return nil, err
+}
+
+// removeUploads deletes the temporary files of all uploads
+func (r *Request) removeUploads() {
+	r.upload.mu.Lock()
+	defer r.upload.mu.Unlock()
+
+	for _, upload := range r.upload.uploads {
+		_ = os.Remove(upload.Path)
+	}
+	r.upload.uploads = nil
+}
+
+// copyLimited copies at most limit+1 bytes, a negative limit copies everything
+func copyLimited(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+	if limit >= 0 {
+		src = io.LimitReader(src, limit+1)
+	}
+	return io.Copy(dst, src)
+}
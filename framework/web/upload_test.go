@@ -0,0 +1,136 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func uploadRequest(t *testing.T, files map[string]string, fields map[string]string) *http.Request {
+	t.Helper()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".txt")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/upload?source=test", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestRequestUploads(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "flamingo-upload-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	tempFiles := func(t *testing.T) int {
+		t.Helper()
+		files, err := ioutil.ReadDir(tempDir)
+		require.NoError(t, err)
+		return len(files)
+	}
+
+	t.Run("store", func(t *testing.T) {
+		req := CreateRequest(uploadRequest(t, map[string]string{"report": "content"}, map[string]string{"title": "monthly"}), nil)
+		req.uploadConfig = &uploadConfig{tempDir: tempDir, maxFileSize: 10}
+
+		uploads, err := req.Uploads(context.Background())
+		require.NoError(t, err)
+		require.Len(t, uploads, 1)
+		assert.Equal(t, "report", uploads[0].Field)
+		assert.Equal(t, "report.txt", uploads[0].Filename)
+		assert.Equal(t, int64(7), uploads[0].Size)
+
+		upload, err := req.Upload(context.Background(), "report")
+		require.NoError(t, err)
+		file, err := upload.Open()
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(file)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		assert.Equal(t, "content", string(content))
+
+		_, err = req.Upload(context.Background(), "unknown")
+		assert.True(t, errors.Is(err, ErrUploadNotFound))
+
+		title, err := req.Form1("title")
+		require.NoError(t, err)
+		assert.Equal(t, "monthly", title)
+		source, err := req.Form1("source")
+		require.NoError(t, err)
+		assert.Equal(t, "test", source)
+
+		assert.Equal(t, 1, tempFiles(t))
+		req.removeUploads()
+		assert.Equal(t, 0, tempFiles(t))
+	})
+
+	t.Run("file size limit", func(t *testing.T) {
+		req := CreateRequest(uploadRequest(t, map[string]string{"report": strings.Repeat("a", 11)}, nil), nil)
+		req.uploadConfig = &uploadConfig{tempDir: tempDir, maxFileSize: 10}
+
+		_, err := req.Uploads(context.Background())
+		assert.True(t, errors.Is(err, ErrUploadTooLarge))
+		assert.Equal(t, 0, tempFiles(t))
+	})
+
+	t.Run("total size limit", func(t *testing.T) {
+		req := CreateRequest(uploadRequest(t, map[string]string{"a": "12345", "b": "12345"}, map[string]string{"title": "x"}), nil)
+		req.uploadConfig = &uploadConfig{tempDir: tempDir, maxFileSize: 10, maxTotalSize: 10}
+
+		_, err := req.Uploads(context.Background())
+		assert.True(t, errors.Is(err, ErrUploadTooLarge))
+		assert.Equal(t, 0, tempFiles(t))
+
+		response := (&Responder{logger: flamingo.NullLogger{}}).Error(err)
+		assert.Equal(t, uint(http.StatusRequestEntityTooLarge), response.Response.Status)
+	})
+
+	t.Run("removed after the request", func(t *testing.T) {
+		router := &Router{
+			eventRouter:    new(flamingo.DefaultEventRouter),
+			filterProvider: func() []Filter { return nil },
+			routesProvider: func() []RoutesModule { return nil },
+			logger:         flamingo.NullLogger{},
+			uploadConfig:   uploadConfig{tempDir: tempDir},
+		}
+		h := router.Handler()
+		registry := NewRegistry()
+		h.(*handler).routerRegistry = registry
+
+		registry.HandlePost("upload", func(ctx context.Context, req *Request) Result {
+			uploads, err := req.Uploads(ctx)
+			require.NoError(t, err)
+			assert.Len(t, uploads, 2)
+			assert.Equal(t, 2, tempFiles(t))
+			return &Response{Status: http.StatusNoContent}
+		})
+		registry.MustRoute("/upload", "upload")
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, uploadRequest(t, map[string]string{"a": "1", "b": "2"}, nil))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, 0, tempFiles(t))
+	})
+}
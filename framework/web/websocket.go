@@ -0,0 +1,122 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/gorilla/websocket"
+)
+
+type (
+	// WebSocketHandler serves an upgraded websocket connection, the connection is closed after the handler returns.
+	// The request carries the session and all values of the filter chain which ran before the upgrade.
+	WebSocketHandler func(ctx context.Context, req *Request, conn *websocket.Conn)
+
+	// WebSocketOpenEvent is dispatched after a websocket connection has been upgraded
+	WebSocketOpenEvent struct {
+		Request *Request
+		Handler string
+	}
+
+	// WebSocketCloseEvent is dispatched after a websocket connection has been closed
+	WebSocketCloseEvent struct {
+		Request *Request
+		Handler string
+	}
+
+	// webSocketResponse upgrades the connection when it is applied, after the filter chain ran
+	webSocketResponse struct {
+		name        string
+		handler     WebSocketHandler
+		request     *Request
+		upgrader    *websocket.Upgrader
+		eventRouter flamingo.EventRouter
+	}
+)
+
+// HandleWebSocket registers a websocket handler for GET requests.
+// Requests which are no websocket handshake are answered with 426 Upgrade Required.
+func (registry *RouterRegistry) HandleWebSocket(name string, handler WebSocketHandler) {
+	registry.HandleGet(name, func(ctx context.Context, req *Request) Result {
+		if !websocket.IsWebSocketUpgrade(req.Request()) {
+			return &Response{
+				Status: http.StatusUpgradeRequired,
+				Header: http.Header{"Upgrade": []string{"websocket"}, "Connection": []string{"Upgrade"}},
+			}
+		}
+
+		return &webSocketResponse{
+			name:    name,
+			handler: handler,
+			request: req,
+		}
+	})
+}
+
+// newWebSocketUpgrader creates an upgrader which accepts the given origins.
+// Origins are either `*`, exact origins like `https://example.com` or patterns like `https://*.example.com`.
+// Without configured origins only same-origin requests are allowed.
+func newWebSocketUpgrader(allowedOrigins []string) *websocket.Upgrader {
+	upgrader := new(websocket.Upgrader)
+	if len(allowedOrigins) == 0 {
+		return upgrader
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `.*`)
+		patterns = append(patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, pattern := range patterns {
+			if pattern.MatchString(strings.ToLower(origin)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return upgrader
+}
+
+// Apply upgrades the connection and serves it with the websocket handler
+func (r *webSocketResponse) Apply(ctx context.Context, w http.ResponseWriter) error {
+	// the session has been saved before the upgrade, the connection is long living and must not block it
+	if session := SessionFromContext(ctx); session != nil {
+		session.release()
+	}
+
+	upgrader := r.upgrader
+	if upgrader == nil {
+		upgrader = new(websocket.Upgrader)
+	}
+
+	// headers set so far, e.g. the session cookie, are sent with the handshake response
+	conn, err := upgrader.Upgrade(w, r.request.Request(), w.Header())
+	if err != nil {
+		// the upgrader already answered the request with an error status
+		return nil
+	}
+	defer conn.Close()
+
+	if r.eventRouter != nil {
+		r.eventRouter.Dispatch(ctx, &WebSocketOpenEvent{Request: r.request, Handler: r.name})
+		defer r.eventRouter.Dispatch(ctx, &WebSocketCloseEvent{Request: r.request, Handler: r.name})
+	}
+
+	r.handler(ctx, r.request, conn)
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zemirco/memorystore"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	webSocketEventRouter chan flamingo.Event
+	webSocketFilter      struct{}
+)
+
+func (webSocketFilter) Filter(ctx context.Context, req *Request, w http.ResponseWriter, chain *FilterChain) Result {
+	req.Values.Store("filter", "applied")
+	return chain.Next(ctx, req, w)
+}
+
+func (e webSocketEventRouter) Dispatch(_ context.Context, event flamingo.Event) {
+	switch event.(type) {
+	case *WebSocketOpenEvent, *WebSocketCloseEvent:
+		e <- event
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	events := make(webSocketEventRouter, 2)
+	router := &Router{
+		eventRouter:    events,
+		filterProvider: func() []Filter { return []Filter{webSocketFilter{}} },
+		routesProvider: func() []RoutesModule { return nil },
+		logger:         flamingo.NullLogger{},
+		sessionStore: &SessionStore{
+			logger:       flamingo.NullLogger{},
+			sessionName:  "test",
+			sessionStore: memorystore.NewMemoryStore([]byte("flamingosecret")),
+		},
+		sessionName:      "test",
+		webSocketOrigins: []string{"https://*.example.com"},
+	}
+	h := router.Handler()
+	registry := NewRegistry()
+	h.(*handler).routerRegistry = registry
+
+	registry.HandleWebSocket("ws", func(ctx context.Context, req *Request, conn *websocket.Conn) {
+		req.Session().Store("user", "jane")
+		filter, _ := req.Values.Load("filter")
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(string(message)+" "+filter.(string))); err != nil {
+				return
+			}
+		}
+	})
+	registry.MustRoute("/ws", "ws")
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	t.Run("upgrade", func(t *testing.T) {
+		conn, response, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+		assert.NotEmpty(t, response.Header.Get("Set-Cookie"))
+		assert.IsType(t, new(WebSocketOpenEvent), <-events)
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+		_, message, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, "hello applied", string(message))
+
+		require.NoError(t, conn.Close())
+		select {
+		case event := <-events:
+			assert.Equal(t, "ws", event.(*WebSocketCloseEvent).Handler)
+		case <-time.After(time.Second):
+			t.Fatal("close event not dispatched")
+		}
+	})
+
+	t.Run("no handshake", func(t *testing.T) {
+		response, err := http.Get(server.URL + "/ws")
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		assert.Equal(t, http.StatusUpgradeRequired, response.StatusCode)
+		assert.Equal(t, "websocket", response.Header.Get("Upgrade"))
+	})
+
+	t.Run("origins", func(t *testing.T) {
+		for origin, allowed := range map[string]bool{
+			"https://shop.example.com": true,
+			"https://example.org":      false,
+			server.URL:                 true,
+		} {
+			conn, response, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{origin}})
+			if !allowed {
+				assert.Error(t, err, origin)
+				assert.Equal(t, http.StatusForbidden, response.StatusCode, origin)
+				continue
+			}
+			require.NoError(t, err, origin)
+			<-events
+			require.NoError(t, conn.Close())
+			<-events
+		}
+	})
+}